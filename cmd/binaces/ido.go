@@ -1,129 +1,160 @@
 package main
 
 import (
-	"chainget/pkg/helper"
+	"chainget/pkg/decode"
+	"chainget/pkg/endpoint"
+	"chainget/pkg/factory"
+	"chainget/pkg/head"
+	"chainget/pkg/multicall"
+	"chainget/pkg/notify"
+	"chainget/pkg/poll"
 	"context"
+	"encoding/json"
 	"fmt"
-	"github.com/ethereum/go-ethereum"
-	"github.com/ethereum/go-ethereum/accounts/abi"
-	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"log"
+	"maps"
 	"math/big"
+	"os"
 	"strings"
 	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 type Ido struct{}
 
 var (
-	ctx             = context.Background()
-	rpcUrl          = "wss://bsc-rpc.publicnode.com"
-	contractAddress common.Address
-	contractAbi     abi.ABI
+	ctx          = context.Background()
+	rpcUrl       = "wss://bsc-rpc.publicnode.com" //http(s) 地址改为轮询
+	pollInterval = poll.DefaultInterval
+	factoryFile  = "factories.yaml"    //工厂配置，不存在时只监听币安 IDO 工厂
+	childrenFile = "ido_children.json" //已发现的子合约，重启后继续监听
+	notifyFile   = "notify.yaml"       //通知配置，不存在时只输出到终端
+	notifier     *notify.Notifier
+	blockTimes   = make(map[uint64]time.Time) //区块时间缓存，同一区块的多条日志只查一次
+	tracker      *head.Tracker                //最近区块头，新日志的区块时间直接从这里取
 )
 
-func topic() [][]common.Hash {
-	var err error
-	contractAbi, err = abi.JSON(strings.NewReader(helper.ReadAbiJson("binance_ido_abi.json")))
+// binanceIdo 币安 IDO 工厂：NewIDOContract 创建池子合约，池子的 PoolParametersSet 等事件在同一个 ABI 里
+var binanceIdo = factory.Config{
+	Name:     "binance-ido",
+	Address:  "0xe0C7897d48847b6916094bF5cD8216449Ea8fB86",
+	ABI:      "binance_ido_abi.json",
+	Event:    "NewIDOContract",
+	ChildArg: "idoAddress",
+	ChildABI: "binance_ido_abi.json",
+	//池子的募集额和发售额分别以 lpToken、offeringToken 计价，精度从链上查询
+	Amounts: map[string]string{"offeringAmountPool": "offeringToken()", "raisingAmountPool": "lpToken()"},
+}
+
+func factories() []*factory.Factory {
+	if _, err := os.Stat(factoryFile); err == nil {
+		factories, err := factory.Load(factoryFile)
+		if err != nil {
+			log.Fatalf("❌ 读取 %s 失败: %v", factoryFile, err)
+		}
+		return factories
+	}
+	f, err := binanceIdo.Factory()
 	if err != nil {
 		log.Fatalf("❌ Failed to parse ABI: %v", err)
 	}
-	//事件签名 创建 IDO 事件 设置池子事件
-	NewIDOContractEvent := contractAbi.Events["NewIDOContract"]
-	if NewIDOContractEvent.ID == (common.Hash{}) {
-		log.Fatalf("❌ Event 'NewIDOContractEvent' not found in ABI")
+	return []*factory.Factory{f}
+}
+
+// dial 逗号分隔的多个 http(s) 地址组成节点池，故障时自动切换，日志和区块用轮询获取
+func dial() (poll.Backend, error) {
+	urls := strings.Split(rpcUrl, ",")
+	if len(urls) == 1 {
+		return poll.Dial(rpcUrl, pollInterval)
 	}
-	PoolParametersSetEvent := contractAbi.Events["PoolParametersSet"]
-	if PoolParametersSetEvent.ID == (common.Hash{}) {
-		log.Fatalf("❌ Event 'PoolParametersSet' not found in ABI")
+	pool, err := endpoint.New(urls)
+	if err != nil {
+		return nil, err
 	}
-	return [][]common.Hash{{
-		NewIDOContractEvent.ID,
-		PoolParametersSetEvent.ID,
-	}}
+	go pool.Run(ctx)
+	return poll.New(pool.Client(), pollInterval), nil
 }
 
 func watch() {
-	contractAddress = common.HexToAddress("0xe0C7897d48847b6916094bF5cD8216449Ea8fB86")
-	client, err := ethclient.Dial(rpcUrl)
+	client, err := dial()
 	if err != nil {
 		log.Fatalf("connect rpc error: %v", err)
 	}
 	defer client.Close()
 
-	number, _ := client.BlockNumber(context.Background())
-	go func() {
-		for {
-			number, _ = client.BlockNumber(context.Background())
-			log.Println("block number: ", number)
-			time.Sleep(1000 * time.Second)
+	store, err := factory.OpenStore(childrenFile)
+	if err != nil {
+		log.Fatalf("❌ 读取 %s 失败: %v", childrenFile, err)
+	}
+	if _, err := os.Stat(notifyFile); err == nil {
+		if notifier, err = notify.Load(notifyFile); err != nil {
+			log.Fatalf("❌ 读取 %s 失败: %v", notifyFile, err)
 		}
-	}()
-
-	var (
-		logsChannel = make(chan types.Log, 100)
-		query       = ethereum.FilterQuery{
-			FromBlock: big.NewInt(int64(number)),
-			Addresses: []common.Address{contractAddress},
-			Topics:    topic(),
+		log.Printf("✔️ 已配置 %d 个通知渠道", notifier.Channels())
+	}
+	watcher := factory.NewWatcher(client, store, factories())
+	//BSC 出块不足 1 秒，区块头时间只有秒级，不检测错过的 slot
+	tracker = head.NewTracker(client, 0)
+	go tracker.Run(ctx)
+	watcher.BlockTime = func(number uint64) time.Time { return blockTime(client, number) }
+	watcher.OnRecord = handleLog
+	if rc, ok := client.(interface{ Client() *rpc.Client }); ok {
+		tokens := decode.NewTokenCache(multicall.New(rc.Client()))
+		watcher.NewDecoder = func(f *factory.Factory, child bool) *decode.Decoder {
+			d := decode.NewDecoder(f.ABI)
+			if child {
+				d = decode.NewDecoder(f.ChildABI)
+			}
+			maps.Copy(d.Amounts, f.Amounts)
+			d.TokenDecimals, d.TokenAddress = tokens.Decimals, tokens.Address
+			return d
 		}
-	)
-	// 订阅事件
-	sub, err := client.SubscribeFilterLogs(ctx, query, logsChannel)
-	if err != nil {
-		log.Fatalf("❌ Failed to subscribe to logs: %v", err)
 	}
-	defer sub.Unsubscribe()
 
 	fmt.Println("开始监听事件...")
-
-	// 监听循环
-	for {
-		select {
-		case errs := <-sub.Err():
-			log.Fatalf("❌ Subscription error: %v", errs)
-		case vLog := <-logsChannel:
-			handleLog(vLog)
-		}
+	if err := watcher.Run(ctx); err != nil {
+		log.Fatalf("❌ Subscription error: %v", err)
 	}
 }
 
-// handleLog 处理不同的事件日志
-func handleLog(vLog types.Log) {
-	var (
-		eventID   = vLog.Topics[0]
-		eventName string
-	)
-	// 查找事件名称
-	for name, event := range contractAbi.Events {
-		if event.ID == eventID {
-			eventName = name
-			break
-		}
+// blockTime 查询区块时间，失败时返回零值
+func blockTime(client poll.Backend, number uint64) time.Time {
+	if h, ok := tracker.Header(number); ok {
+		return time.Unix(int64(h.Time), 0)
 	}
-	if eventName == "" {
-		log.Printf("⚠️ Unknown event ID: %s", eventID.Hex())
-		return
+	if t, ok := blockTimes[number]; ok {
+		return t
 	}
+	header, err := client.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+	if err != nil {
+		log.Printf("⚠️ 获取区块 %d 时间失败: %v", number, err)
+		return time.Time{}
+	}
+	t := time.Unix(int64(header.Time), 0)
+	if len(blockTimes) > 1000 {
+		clear(blockTimes)
+	}
+	blockTimes[number] = t
+	return t
+}
 
-	fmt.Printf("时间: %s\n", time.Now().Format("2006-01-02 15:04:05"))
-	fmt.Printf("块号: %d\n", vLog.BlockNumber)
-	fmt.Printf("交易哈希: %s\n", vLog.TxHash.Hex())
-	fmt.Printf("事件: %s\n", eventName)
-
-	switch eventName {
-	case "NewIDOContract":
-		idoAddress := common.HexToAddress(vLog.Topics[1].Hex())
-		fmt.Printf("idoAddress: %s\n", idoAddress.Hex())
-	case "PoolParametersSet":
-		if len(vLog.Topics) > 1 {
-			param1 := common.HexToAddress(vLog.Topics[1].Hex())
-			fmt.Printf("param1: %s\n", param1.Hex())
-		}
-	default:
-		fmt.Println("未处理的事件")
+// handleLog 打印工厂或子合约事件的全部参数，再输出一行 JSON 记录
+func handleLog(record *decode.Record) {
+	fmt.Printf("合约: %s\n", record.Address.Hex())
+	fmt.Printf("时间: %s\n", record.BlockTime)
+	fmt.Printf("块号: %d\n", record.BlockNumber)
+	fmt.Printf("交易哈希: %s\n", record.TxHash.Hex())
+	fmt.Printf("事件: %s\n", record.Event)
+	for _, field := range record.Fields {
+		fmt.Printf("%s (%s): %s\n", field.Name, field.Type, field.Display)
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		log.Printf("❌ 序列化事件失败: %v", err)
+		return
 	}
+	fmt.Println(string(data))
 	fmt.Println("------------------------")
+	notifier.Go(record.Event, fmt.Sprintf("%s:%d", record.TxHash.Hex(), record.LogIndex), record)
 }
@@ -1,6 +1,12 @@
 package main
 
+import "flag"
+
 func main() {
+	flag.StringVar(&rpcUrl, "rpc", rpcUrl, "RPC 地址，http(s) 时轮询 eth_getFilterChanges/eth_getLogs，多个 http(s) 地址用逗号分隔组成节点池")
+	flag.DurationVar(&pollInterval, "interval", pollInterval, "http(s) RPC 的轮询间隔")
+	flag.Parse()
+
 	//监听币安合约IDO事件
 	watch()
 
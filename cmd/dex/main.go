@@ -1 +1,160 @@
-package dex
+package main
+
+import (
+	"chainget/pkg/dex"
+	"chainget/pkg/endpoint"
+	"chainget/pkg/multicall"
+	"chainget/pkg/poll"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func main() {
+	config := flag.String("config", "dex.yaml", "交易对配置文件")
+	asJSON := flag.Bool("json", false, "兑换按 JSON 每行一条输出")
+	syncs := flag.Bool("sync", false, "储备量变化时打印中间价")
+	quote := flag.String("quote", "", `按加载到的状态离线报价后退出，如 "1.5 WETH USDC"，代币可用符号或地址`)
+	hops := flag.Int("hops", 3, "报价路径最多经过几个交易对")
+	flag.Parse()
+
+	file, err := dex.Load(*config)
+	if err != nil {
+		log.Fatalf("❌ 读取 %s 失败: %v", *config, err)
+	}
+	client, rpcClient, err := dial(file)
+	if err != nil {
+		log.Fatalf("❌ 连接节点失败: %v", err)
+	}
+	defer client.Close()
+	caller := multicall.New(rpcClient)
+	if file.Multicall != "" {
+		caller.Address = common.HexToAddress(file.Multicall)
+	}
+
+	var pairs []*dex.Pair
+	var pools []*dex.Pool
+	for _, c := range file.Pairs {
+		if c.V3() {
+			pools = append(pools, c.Pool())
+		} else {
+			pairs = append(pairs, c.Pair())
+		}
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	m := dex.NewMonitor(client, caller, pairs, pools)
+	if err := m.Load(ctx); err != nil {
+		log.Fatalf("❌ 加载交易对失败: %v", err)
+	}
+	for _, p := range pairs {
+		r0, r1, block := p.Reserves()
+		log.Printf("✔️ %s %s 储备 %s %s / %s %s，价格 %s（区块 %d，手续费 %d bp）", p, p.Address.Hex(),
+			dex.FormatAmount(r0, p.Token0.Decimals), p.Token0.Symbol, dex.FormatAmount(r1, p.Token1.Decimals), p.Token1.Symbol,
+			dex.FormatPrice(p.Price()), block, p.Fee)
+	}
+	for _, p := range pools {
+		_, liquidity, tick, block := p.State()
+		log.Printf("✔️ %s %s 价格 %s，tick %d，流动性 %s（区块 %d，手续费 %.2f%%）", p, p.Address.Hex(),
+			dex.FormatPrice(p.Price()), tick, liquidity, block, float64(p.Fee)/1e4)
+	}
+
+	if *quote != "" {
+		if err := printQuotes(m.Quoter(), *quote, *hops); err != nil {
+			log.Fatalf("❌ 报价失败: %v", err)
+		}
+		return
+	}
+
+	m.OnSwap = func(s *dex.Swap) {
+		if !*asJSON {
+			fmt.Println(s)
+			return
+		}
+		data, err := json.Marshal(s)
+		if err != nil {
+			log.Printf("❌ 序列化兑换失败: %v", err)
+			return
+		}
+		fmt.Println(string(data))
+	}
+	if *syncs {
+		m.OnSync = func(p *dex.Pair) {
+			_, _, block := p.Reserves()
+			log.Printf("[%s] 区块 %d 价格 %s %s", p, block, dex.FormatPrice(p.Price()), p.QuoteToken().Symbol)
+		}
+		m.OnPool = func(p *dex.Pool) {
+			_, liquidity, tick, block := p.State()
+			log.Printf("[%s] 区块 %d 价格 %s %s，tick %d，流动性 %s", p, block, dex.FormatPrice(p.Price()), p.QuoteToken().Symbol, tick, liquidity)
+		}
+	}
+	log.Printf("✔️ 开始监听 %d 个交易对、%d 个 V3 池子", len(pairs), len(pools))
+	if err := m.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		log.Fatalf("❌ %v", err)
+	}
+}
+
+// printQuotes 输出全部路径的报价，最优的在前
+func printQuotes(q *dex.Quoter, spec string, hops int) error {
+	fields := strings.Fields(spec)
+	if len(fields) != 3 {
+		return fmt.Errorf("invalid quote %q, want \"amount tokenIn tokenOut\"", spec)
+	}
+	tokenIn, ok := q.Token(fields[1])
+	if !ok {
+		return fmt.Errorf("unknown token %s", fields[1])
+	}
+	tokenOut, ok := q.Token(fields[2])
+	if !ok {
+		return fmt.Errorf("unknown token %s", fields[2])
+	}
+	amount, err := dex.ParseAmount(fields[0], tokenIn.Decimals)
+	if err != nil {
+		return err
+	}
+	q.MaxHops = hops
+	quotes := q.Routes(tokenIn.Address, tokenOut.Address, amount)
+	if len(quotes) == 0 {
+		return dex.ErrNoRoute
+	}
+	for i, route := range quotes {
+		fmt.Printf("%d. %s\n", i+1, route)
+	}
+	return nil
+}
+
+// dial 单个地址直接连接（http(s) 轮询），多个 http(s) 地址组成节点池；另外返回 Multicall 用的 rpc.Client
+func dial(f *dex.File) (poll.Backend, *rpc.Client, error) {
+	switch len(f.RPC) {
+	case 0:
+		return nil, nil, errors.New("no rpc")
+	case 1:
+		client, err := poll.Dial(f.RPC[0], f.PollInterval)
+		if err != nil {
+			return nil, nil, err
+		}
+		switch c := client.(type) {
+		case *poll.Client:
+			return c, c.Client.Client(), nil
+		case *ethclient.Client:
+			return c, c.Client(), nil
+		}
+		return nil, nil, fmt.Errorf("unexpected client %T", client)
+	}
+	pool, err := endpoint.New(f.RPC)
+	if err != nil {
+		return nil, nil, err
+	}
+	go pool.Run(context.Background())
+	return poll.New(pool.Client(), f.PollInterval), pool.RPC(), nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"chainget/pkg/watch"
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+)
+
+func main() {
+	config := flag.String("config", "watches.yaml", "监听定义文件")
+	flag.Parse()
+
+	file, err := watch.Load(*config)
+	if err != nil {
+		log.Fatalf("❌ 读取 %s 失败: %v", *config, err)
+	}
+	engine, err := watch.New(file)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	engine.Run(ctx)
+}
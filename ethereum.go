@@ -1,6 +1,7 @@
 package chainget
 
 import (
+	"chainget/pkg/head"
 	"context"
 	"encoding/hex"
 	"fmt"
@@ -25,13 +26,11 @@ func main() {
 	}
 	defer client.Close()
 
-	go func() {
-		for {
-			number, _ := client.BlockNumber(context.Background())
-			fmt.Printf("当前区块高度：%d\n", number)
-			time.Sleep(1000 * time.Second)
-		}
-	}()
+	tracker := head.NewTracker(client, 12*time.Second)
+	go tracker.Run(ctx)
+	if latest, err := tracker.Wait(ctx); err == nil {
+		fmt.Printf("当前区块高度：%d\n", latest.Number)
+	}
 
 	contractAddress := common.HexToAddress("0xdac17f958d2ee523a2206206994597c13d831ec7")
 	topic := crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
@@ -27,11 +27,15 @@ func InitConfig() {
 }
 
 func GetPrivateKey() string {
+	return DecryptPrivateKey(Viper.GetString("key.privateKey"))
+}
+
+// DecryptPrivateKey 用 PASSWORD 环境变量解密配置中的加密私钥
+func DecryptPrivateKey(privateKey string) string {
 	pwd := []byte(strings.TrimRight(os.Getenv("PASSWORD"), " \t\n\r"))
 	if len(pwd) != 16 {
 		log.Fatal("❌ 获取密码失败！！获取私钥失败")
 	}
-	privateKey := Viper.GetString("key.privateKey")
 	if privateKey == "" {
 		log.Fatal("❌ 获取加密私钥失败")
 	}
@@ -0,0 +1,106 @@
+package bundle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/lmittmann/w3"
+)
+
+var (
+	// transferLogAddress eth_simulateV1 开启 traceTransfers 后 ETH 转账日志的伪地址
+	transferLogAddress = common.HexToAddress("0xEeeeeEeeeEeEeeEeEeEeeEEEeeeeEeeeeeeeEEeE")
+	transferTopic      = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+)
+
+type simulatedLog struct {
+	Address common.Address `json:"address"`
+	Topics  []common.Hash  `json:"topics"`
+	Data    hexutil.Bytes  `json:"data"`
+}
+
+type simulatedCall struct {
+	GasUsed hexutil.Uint64 `json:"gasUsed"`
+	Logs    []simulatedLog `json:"logs"`
+}
+
+type simulatedBlock struct {
+	BaseFee *hexutil.Big    `json:"baseFeePerGas"`
+	Calls   []simulatedCall `json:"calls"`
+}
+
+// BalanceChange 用节点的 eth_simulateV1（traceTransfers）在最新状态上执行 b，
+// 统计 account 的 ETH 净变化（含内部转账和 gas），同时返回目标区块的 base fee
+func BalanceChange(ctx context.Context, client *w3.Client, b *Bundle, account common.Address) (change, baseFee *big.Int, err error) {
+	var (
+		calls = make([]map[string]any, len(b.Txs))
+		froms = make([]common.Address, len(b.Txs))
+	)
+	for i, tx := range b.Txs {
+		if froms[i], err = types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx); err != nil {
+			return nil, nil, fmt.Errorf("tx %s: invalid sender: %w", tx.Hash().Hex(), err)
+		}
+		calls[i] = map[string]any{
+			"from":                 froms[i],
+			"to":                   tx.To(),
+			"gas":                  hexutil.Uint64(tx.Gas()),
+			"value":                (*hexutil.Big)(tx.Value()),
+			"input":                hexutil.Bytes(tx.Data()),
+			"nonce":                hexutil.Uint64(tx.Nonce()),
+			"maxFeePerGas":         (*hexutil.Big)(tx.GasFeeCap()),
+			"maxPriorityFeePerGas": (*hexutil.Big)(tx.GasTipCap()),
+		}
+	}
+	overrides := map[string]any{}
+	if b.BlockNumber != nil {
+		overrides["number"] = (*hexutil.Big)(b.BlockNumber)
+	}
+	opts := map[string]any{
+		"blockStateCalls": []map[string]any{{"blockOverrides": overrides, "calls": calls}},
+		"traceTransfers":  true,
+		"validation":      true,
+	}
+
+	var blocks []simulatedBlock
+	if err := client.CallCtx(ctx, newCall[[]simulatedBlock]("eth_simulateV1", opts, "latest").Returns(&blocks)); err != nil {
+		return nil, nil, err
+	}
+	if len(blocks) == 0 {
+		return nil, nil, errors.New("bundle: empty simulation result")
+	}
+	block := blocks[len(blocks)-1]
+	if len(block.Calls) != len(b.Txs) {
+		return nil, nil, fmt.Errorf("bundle: simulation returned %d results for %d txs", len(block.Calls), len(b.Txs))
+	}
+	baseFee = new(big.Int)
+	if block.BaseFee != nil {
+		baseFee = block.BaseFee.ToInt()
+	}
+
+	change = new(big.Int)
+	for i, call := range block.Calls {
+		for _, l := range call.Logs {
+			if l.Address != transferLogAddress || len(l.Topics) != 3 || l.Topics[0] != transferTopic {
+				continue
+			}
+			value := new(big.Int).SetBytes(l.Data)
+			if common.BytesToAddress(l.Topics[1].Bytes()) == account {
+				change.Sub(change, value)
+			}
+			if common.BytesToAddress(l.Topics[2].Bytes()) == account {
+				change.Add(change, value)
+			}
+		}
+		if froms[i] == account {
+			gas := new(big.Int).Mul(effectiveGasPrice(b.Txs[i], baseFee), new(big.Int).SetUint64(uint64(call.GasUsed)))
+			change.Sub(change, gas)
+		}
+	}
+	return change, baseFee, nil
+}
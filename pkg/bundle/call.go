@@ -0,0 +1,45 @@
+package bundle
+
+import (
+	"encoding/json"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/lmittmann/w3/w3types"
+)
+
+// call 通用的 w3 RPC 调用，用于 w3 和 flashbots 库未内置的方法
+type call[T any] struct {
+	method  string
+	args    []any
+	returns *T
+}
+
+func newCall[T any](method string, args ...any) *call[T] {
+	return &call[T]{method: method, args: args}
+}
+
+func (c *call[T]) Returns(returns *T) w3types.RPCCaller {
+	c.returns = returns
+	return c
+}
+
+func (c *call[T]) CreateRequest() (rpc.BatchElem, error) {
+	elem := rpc.BatchElem{Method: c.method, Args: c.args}
+	if c.returns != nil {
+		elem.Result = c.returns
+	} else {
+		elem.Result = new(json.RawMessage)
+	}
+	return elem, nil
+}
+
+func (c *call[T]) HandleResponse(elem rpc.BatchElem) error {
+	return elem.Error
+}
+
+// RawTx 获取已签名的原始交易，pending 交易也可获取
+func RawTx(hash common.Hash) w3types.RPCCallerFactory[hexutil.Bytes] {
+	return newCall[hexutil.Bytes]("eth_getRawTransactionByHash", hash)
+}
@@ -0,0 +1,66 @@
+package bundle
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/lmittmann/w3"
+	"github.com/lmittmann/w3/module/eth"
+)
+
+// Fees 一次报价的 EIP-1559 费用
+type Fees struct {
+	BaseFee *big.Int
+	TipCap  *big.Int
+	FeeCap  *big.Int
+}
+
+// FeeOracle 根据最新区块和节点建议的小费给出报价
+type FeeOracle struct {
+	client            *w3.Client
+	MinTip            *big.Int // 最低小费，节点建议值更低时使用该值
+	BaseFeeMultiplier int64    // feeCap = baseFee * BaseFeeMultiplier + tip，覆盖接下来几个块的 base fee 上涨
+}
+
+func NewFeeOracle(client *w3.Client) *FeeOracle {
+	return &FeeOracle{client: client, MinTip: w3.I("1 gwei"), BaseFeeMultiplier: 2}
+}
+
+// Suggest 获取当前报价
+func (o *FeeOracle) Suggest(ctx context.Context) (*Fees, error) {
+	var (
+		header *types.Header
+		tip    *big.Int
+	)
+	if err := o.client.CallCtx(ctx,
+		eth.HeaderByNumber(nil).Returns(&header),
+		eth.GasTipCap().Returns(&tip),
+	); err != nil {
+		return nil, err
+	}
+	if o.MinTip != nil && tip.Cmp(o.MinTip) < 0 {
+		tip = new(big.Int).Set(o.MinTip)
+	}
+	baseFee := new(big.Int)
+	if header.BaseFee != nil {
+		baseFee.Set(header.BaseFee)
+	}
+	feeCap := new(big.Int).Mul(baseFee, big.NewInt(o.BaseFeeMultiplier))
+	return &Fees{BaseFee: baseFee, TipCap: tip, FeeCap: feeCap.Add(feeCap, tip)}, nil
+}
+
+// Tx 按报价构造 EIP-1559 交易
+func (f *Fees) Tx(chainID *big.Int, nonce uint64, to common.Address, value *big.Int, gas uint64, data []byte) *types.DynamicFeeTx {
+	return &types.DynamicFeeTx{
+		ChainID:   chainID,
+		Nonce:     nonce,
+		To:        &to,
+		Value:     value,
+		Gas:       gas,
+		GasTipCap: f.TipCap,
+		GasFeeCap: f.FeeCap,
+		Data:      data,
+	}
+}
@@ -0,0 +1,131 @@
+package bundle
+
+import (
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/lmittmann/flashbots"
+	"github.com/lmittmann/w3"
+)
+
+// Policy 提交前对模拟结果做的花费与目标检查，只约束 Account 签名的交易，victim 等他人交易不计入。
+// 为 nil 或空的字段不检查
+type Policy struct {
+	Account          common.Address
+	MaxValue         *big.Int         // 我方交易 value 总和上限
+	MaxGasSpend      *big.Int         // 我方交易 gasUsed * 实际 gas 单价总和上限
+	MinBalanceChange *big.Int         // 我方账户净余额变化下限，可为负数表示最多亏损多少
+	AllowedTargets   []common.Address // 我方交易允许调用的合约
+	AllowedSelectors [][4]byte        // 我方交易允许调用的方法
+}
+
+// PolicyError 未通过的全部规则
+type PolicyError struct {
+	Violations []string
+}
+
+func (e *PolicyError) Error() string {
+	return "bundle rejected by policy: " + strings.Join(e.Violations, "; ")
+}
+
+// Outcome 一次模拟中我方账户的花费
+type Outcome struct {
+	Value         *big.Int // value 总和
+	GasSpend      *big.Int // gas 花费总和
+	BalanceChange *big.Int // 净余额变化
+}
+
+// Evaluate 用模拟结果检查 bundle，返回我方花费；有规则未通过时返回 *PolicyError。
+// baseFee 为目标区块的 base fee，用于计算实际 gas 单价；balanceChange 为 nil 时按 -(value + gas) 估算，
+// 即假设我方账户没有 ETH 流入，需要精确值时传入 BalanceChange 的结果
+func (p *Policy) Evaluate(b *Bundle, res *flashbots.CallBundleResponse, baseFee, balanceChange *big.Int) (*Outcome, error) {
+	gasUsed := make(map[common.Hash]uint64, len(res.Results))
+	for _, r := range res.Results {
+		gasUsed[r.TxHash] = r.GasUsed
+	}
+	return p.evaluate(b, func(tx *types.Transaction) (uint64, bool) {
+		used, ok := gasUsed[tx.Hash()]
+		return used, ok
+	}, baseFee, balanceChange)
+}
+
+// Precheck 不模拟时按最坏情况检查：gas 全部用完、按 feeCap 计价
+func (p *Policy) Precheck(b *Bundle) (*Outcome, error) {
+	return p.evaluate(b, func(tx *types.Transaction) (uint64, bool) { return tx.Gas(), true }, nil, nil)
+}
+
+func (p *Policy) evaluate(b *Bundle, gasUsed func(*types.Transaction) (uint64, bool), baseFee, balanceChange *big.Int) (*Outcome, error) {
+	var (
+		out        = &Outcome{Value: new(big.Int), GasSpend: new(big.Int)}
+		violations []string
+	)
+	for _, tx := range b.Txs {
+		from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+		if err != nil {
+			return nil, fmt.Errorf("tx %s: invalid sender: %w", tx.Hash().Hex(), err)
+		}
+		if from != p.Account {
+			continue
+		}
+		used, ok := gasUsed(tx)
+		if !ok {
+			return nil, fmt.Errorf("tx %s: missing from simulation result", tx.Hash().Hex())
+		}
+		out.Value.Add(out.Value, tx.Value())
+		out.GasSpend.Add(out.GasSpend, new(big.Int).Mul(effectiveGasPrice(tx, baseFee), new(big.Int).SetUint64(used)))
+
+		if len(p.AllowedTargets) > 0 && (tx.To() == nil || !slices.Contains(p.AllowedTargets, *tx.To())) {
+			violations = append(violations, fmt.Sprintf("tx %s calls target %s not in allow list", tx.Hash().Hex(), targetString(tx.To())))
+		}
+		if len(p.AllowedSelectors) > 0 {
+			var selector [4]byte
+			if len(tx.Data()) < 4 {
+				violations = append(violations, fmt.Sprintf("tx %s has no selector", tx.Hash().Hex()))
+			} else if copy(selector[:], tx.Data()[:4]); !slices.Contains(p.AllowedSelectors, selector) {
+				violations = append(violations, fmt.Sprintf("tx %s calls selector 0x%x not in allow list", tx.Hash().Hex(), selector))
+			}
+		}
+	}
+
+	out.BalanceChange = balanceChange
+	if out.BalanceChange == nil {
+		out.BalanceChange = new(big.Int).Add(out.Value, out.GasSpend)
+		out.BalanceChange.Neg(out.BalanceChange)
+	}
+	if p.MaxValue != nil && out.Value.Cmp(p.MaxValue) > 0 {
+		violations = append(violations, fmt.Sprintf("total value %s ETH exceeds max %s ETH", w3.FromWei(out.Value, 18), w3.FromWei(p.MaxValue, 18)))
+	}
+	if p.MaxGasSpend != nil && out.GasSpend.Cmp(p.MaxGasSpend) > 0 {
+		violations = append(violations, fmt.Sprintf("gas spend %s ETH exceeds max %s ETH", w3.FromWei(out.GasSpend, 18), w3.FromWei(p.MaxGasSpend, 18)))
+	}
+	if p.MinBalanceChange != nil && out.BalanceChange.Cmp(p.MinBalanceChange) < 0 {
+		violations = append(violations, fmt.Sprintf("net balance change %s ETH below min %s ETH", w3.FromWei(out.BalanceChange, 18), w3.FromWei(p.MinBalanceChange, 18)))
+	}
+	if len(violations) > 0 {
+		return out, &PolicyError{Violations: violations}
+	}
+	return out, nil
+}
+
+// effectiveGasPrice min(feeCap, baseFee + tip)，baseFee 未知时按 feeCap 计
+func effectiveGasPrice(tx *types.Transaction, baseFee *big.Int) *big.Int {
+	if baseFee == nil {
+		return new(big.Int).Set(tx.GasFeeCap())
+	}
+	price := new(big.Int).Add(baseFee, tx.GasTipCap())
+	if price.Cmp(tx.GasFeeCap()) > 0 {
+		return new(big.Int).Set(tx.GasFeeCap())
+	}
+	return price
+}
+
+func targetString(to *common.Address) string {
+	if to == nil {
+		return "<create>"
+	}
+	return to.Hex()
+}
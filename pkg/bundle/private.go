@@ -0,0 +1,27 @@
+package bundle
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/lmittmann/flashbots"
+)
+
+// SendPrivate 调用 eth_sendPrivateTransaction 私密发送单笔交易，maxBlock 之后 relay 不再尝试打包
+func (s *Sender) SendPrivate(ctx context.Context, tx *types.Transaction, maxBlock *big.Int) (common.Hash, error) {
+	var hash common.Hash
+	err := s.relay.CallCtx(ctx, flashbots.SendPrivateTx(&flashbots.SendPrivateTxRequest{
+		Tx:             tx,
+		MaxBlockNumber: maxBlock,
+	}).Returns(&hash))
+	return hash, err
+}
+
+// CancelPrivate 调用 eth_cancelPrivateTransaction 撤回还未上链的私密交易
+func (s *Sender) CancelPrivate(ctx context.Context, hash common.Hash) (bool, error) {
+	var cancelled bool
+	err := s.relay.CallCtx(ctx, flashbots.CancelPrivateTx(hash).Returns(&cancelled))
+	return cancelled, err
+}
@@ -0,0 +1,113 @@
+package bundle
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/google/uuid"
+	"github.com/lmittmann/flashbots"
+	"github.com/lmittmann/w3"
+)
+
+// Bundle 待提交的 bundle
+type Bundle struct {
+	Txs               types.Transactions
+	BlockNumber       *big.Int
+	RevertingTxHashes []common.Hash //允许回滚的交易
+	ReplacementUUID   uuid.UUID     //不为空时 relay 用它替换或撤回之前提交的同 UUID bundle
+}
+
+// Hashes bundle 内所有交易 hash
+func (b *Bundle) Hashes() []common.Hash {
+	hashes := make([]common.Hash, len(b.Txs))
+	for i, tx := range b.Txs {
+		hashes[i] = tx.Hash()
+	}
+	return hashes
+}
+
+// Check 检查模拟结果，返回第一笔不允许回滚却失败的交易错误
+func (b *Bundle) Check(res *flashbots.CallBundleResponse) error {
+	for _, r := range res.Results {
+		if r.Error == nil || slices.Contains(b.RevertingTxHashes, r.TxHash) {
+			continue
+		}
+		if r.Revert != "" {
+			return fmt.Errorf("tx %s reverted: %s", r.TxHash.Hex(), r.Revert)
+		}
+		return fmt.Errorf("tx %s failed: %w", r.TxHash.Hex(), r.Error)
+	}
+	return nil
+}
+
+// Sender 向 Flashbots relay 模拟和提交 bundle，请求使用 key 签名
+type Sender struct {
+	relay *w3.Client
+}
+
+func Dial(relayURL string, key *ecdsa.PrivateKey) (*Sender, error) {
+	client, err := flashbots.Dial(relayURL, key)
+	if err != nil {
+		return nil, err
+	}
+	return &Sender{relay: client}, nil
+}
+
+func (s *Sender) Close() error {
+	return s.relay.Close()
+}
+
+// Simulate 调用 eth_callBundle 模拟 bundle
+func (s *Sender) Simulate(ctx context.Context, b *Bundle) (*flashbots.CallBundleResponse, error) {
+	var res *flashbots.CallBundleResponse
+	if err := s.relay.CallCtx(ctx, flashbots.CallBundle(&flashbots.CallBundleRequest{
+		Transactions: b.Txs,
+		BlockNumber:  b.BlockNumber,
+	}).Returns(&res)); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Send 调用 eth_sendBundle 提交 bundle 到 b.BlockNumber
+func (s *Sender) Send(ctx context.Context, b *Bundle) (common.Hash, error) {
+	var hash common.Hash
+	err := s.relay.CallCtx(ctx, flashbots.SendBundle(&flashbots.SendBundleRequest{
+		Transactions:      b.Txs,
+		BlockNumber:       b.BlockNumber,
+		RevertingTxHashes: b.RevertingTxHashes,
+		ReplacementUuid:   b.ReplacementUUID,
+	}).Returns(&hash))
+	return hash, err
+}
+
+// Cancel 调用 eth_cancelBundle 撤回 UUID 对应的 bundle
+func (s *Sender) Cancel(ctx context.Context, id uuid.UUID) error {
+	return s.relay.CallCtx(ctx, newCall[json.RawMessage]("eth_cancelBundle", map[string]uuid.UUID{"replacementUuid": id}).Returns(nil))
+}
+
+// SendRange 把同一个 bundle 提交到 [from, to] 的每个区块，返回每个区块的 bundle hash。
+// 同一 UUID 会互相替换，需要可撤回的多区块提交请使用 SubmissionTracker
+func (s *Sender) SendRange(ctx context.Context, b *Bundle, from, to uint64) ([]common.Hash, error) {
+	if b.ReplacementUUID != uuid.Nil {
+		return nil, errors.New("bundle: SendRange does not support ReplacementUUID")
+	}
+	hashes := make([]common.Hash, 0, to-from+1)
+	for number := from; number <= to; number++ {
+		target := *b
+		target.BlockNumber = new(big.Int).SetUint64(number)
+		hash, err := s.Send(ctx, &target)
+		if err != nil {
+			return hashes, fmt.Errorf("send bundle for block %d: %w", number, err)
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, nil
+}
@@ -0,0 +1,32 @@
+package bundle
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Signer 账户签名器，bundle 和私有交易共用
+type Signer struct {
+	Key     *ecdsa.PrivateKey
+	Address common.Address
+	ChainID *big.Int
+	signer  types.Signer
+}
+
+func NewSigner(key *ecdsa.PrivateKey, chainID *big.Int) *Signer {
+	return &Signer{
+		Key:     key,
+		Address: crypto.PubkeyToAddress(key.PublicKey),
+		ChainID: chainID,
+		signer:  types.LatestSignerForChainID(chainID),
+	}
+}
+
+// Sign 签名交易
+func (s *Signer) Sign(tx types.TxData) (*types.Transaction, error) {
+	return types.SignNewTx(s.Key, s.signer, tx)
+}
@@ -0,0 +1,111 @@
+package bundle
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+)
+
+// Submission 一个逻辑 bundle 的某个版本，每个目标区块各用一个 UUID，避免互相替换
+type Submission struct {
+	Key     string
+	Version int
+	Bundle  *Bundle
+	UUIDs   map[uint64]uuid.UUID   // 目标区块 → replacement UUID
+	Hashes  map[uint64]common.Hash // 目标区块 → bundle hash
+	SentAt  time.Time
+}
+
+// SubmissionTracker 按 key 记录当前有效的 bundle 版本，提交新版本时自动撤回旧版本
+type SubmissionTracker struct {
+	sender *Sender
+
+	mu      sync.Mutex
+	current map[string]*Submission
+}
+
+func NewSubmissionTracker(sender *Sender) *SubmissionTracker {
+	return &SubmissionTracker{sender: sender, current: make(map[string]*Submission)}
+}
+
+// Submit 提交 key 的新版本到 [from, to] 的每个区块，存在旧版本时先 eth_cancelBundle 撤回，
+// 防止价格变化或 nonce 已被占用后旧 bundle 仍被打包
+func (t *SubmissionTracker) Submit(ctx context.Context, key string, b *Bundle, from, to uint64) (*Submission, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	version := 1
+	if old, ok := t.current[key]; ok {
+		if err := t.cancel(ctx, old); err != nil {
+			return nil, fmt.Errorf("cancel stale bundle %s v%d: %w", key, old.Version, err)
+		}
+		delete(t.current, key)
+		version = old.Version + 1
+	}
+
+	sub := &Submission{
+		Key:     key,
+		Version: version,
+		Bundle:  b,
+		UUIDs:   make(map[uint64]uuid.UUID),
+		Hashes:  make(map[uint64]common.Hash),
+		SentAt:  time.Now(),
+	}
+	t.current[key] = sub
+	for number := from; number <= to; number++ {
+		target := *b
+		target.BlockNumber = new(big.Int).SetUint64(number)
+		target.ReplacementUUID = uuid.New()
+		hash, err := t.sender.Send(ctx, &target)
+		if err != nil {
+			return sub, fmt.Errorf("send bundle %s v%d for block %d: %w", key, version, number, err)
+		}
+		sub.UUIDs[number] = target.ReplacementUUID
+		sub.Hashes[number] = hash
+	}
+	return sub, nil
+}
+
+// Cancel 撤回 key 当前版本
+func (t *SubmissionTracker) Cancel(ctx context.Context, key string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	sub, ok := t.current[key]
+	if !ok {
+		return nil
+	}
+	if err := t.cancel(ctx, sub); err != nil {
+		return err
+	}
+	delete(t.current, key)
+	return nil
+}
+
+// Current 返回 key 当前有效的版本
+func (t *SubmissionTracker) Current(key string) (*Submission, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	sub, ok := t.current[key]
+	return sub, ok
+}
+
+// Done 上链或过期后移除记录，不再撤回
+func (t *SubmissionTracker) Done(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.current, key)
+}
+
+func (t *SubmissionTracker) cancel(ctx context.Context, sub *Submission) error {
+	for number, id := range sub.UUIDs {
+		if err := t.sender.Cancel(ctx, id); err != nil {
+			return fmt.Errorf("block %d: %w", number, err)
+		}
+	}
+	return nil
+}
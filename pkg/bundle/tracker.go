@@ -0,0 +1,81 @@
+package bundle
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/lmittmann/w3"
+	"github.com/lmittmann/w3/module/eth"
+	"github.com/lmittmann/w3/w3types"
+)
+
+// ErrNotIncluded 超过最大区块仍未上链
+var ErrNotIncluded = errors.New("bundle: not included before max block")
+
+// Tracker 轮询收据跟踪交易是否上链
+type Tracker struct {
+	client   *w3.Client
+	Interval time.Duration
+}
+
+func NewTracker(client *w3.Client) *Tracker {
+	return &Tracker{client: client, Interval: 3 * time.Second}
+}
+
+// Wait 等待 hashes 全部上链并返回收据，链高超过 maxBlock 仍未全部上链时返回 ErrNotIncluded
+func (t *Tracker) Wait(ctx context.Context, hashes []common.Hash, maxBlock uint64) ([]*types.Receipt, error) {
+	ticker := time.NewTicker(t.Interval)
+	defer ticker.Stop()
+	for {
+		receipts, head, err := t.poll(ctx, hashes)
+		if err != nil {
+			return nil, err
+		}
+		if receipts != nil {
+			return receipts, nil
+		}
+		if head > maxBlock {
+			return nil, ErrNotIncluded
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll 查询一次，全部上链时返回收据，否则返回 nil 收据和当前链高
+func (t *Tracker) poll(ctx context.Context, hashes []common.Hash) ([]*types.Receipt, uint64, error) {
+	var (
+		head     *big.Int
+		receipts = make([]*types.Receipt, len(hashes))
+		calls    = []w3types.RPCCaller{eth.BlockNumber().Returns(&head)}
+	)
+	for i, hash := range hashes {
+		calls = append(calls, newCall[*types.Receipt]("eth_getTransactionReceipt", hash).Returns(&receipts[i]))
+	}
+	if err := t.client.CallCtx(ctx, calls...); err != nil {
+		//节点交易索引未追上时查不到收据，等同未上链
+		var callErrs w3.CallErrors
+		if !errors.As(err, &callErrs) || callErrs[0] != nil {
+			return nil, 0, err
+		}
+		for _, err := range callErrs[1:] {
+			if err != nil && !strings.Contains(err.Error(), "indexing is in progress") {
+				return nil, 0, err
+			}
+		}
+	}
+	for _, receipt := range receipts {
+		if receipt == nil {
+			return nil, head.Uint64(), nil
+		}
+	}
+	return receipts, head.Uint64(), nil
+}
@@ -0,0 +1,232 @@
+package decode
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/lmittmann/w3"
+)
+
+// ErrUnknownEvent 日志的 topic0 不在 ABI 中
+var ErrUnknownEvent = errors.New("decode: unknown event")
+
+// TimeLayout 时间字段的显示格式
+const TimeLayout = "2006-01-02 15:04:05 MST"
+
+// Field 解码后的事件参数
+type Field struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Indexed bool   `json:"indexed"`
+	Value   any    `json:"value"`   // abi 解出的原始值，indexed 的动态类型（string、bytes、数组）只有 keccak256 hash
+	Display string `json:"display"` // 可读形式：配置为时间的参数转日期，配置了精度的金额按精度缩放
+}
+
+// Record 一条结构化的事件记录
+type Record struct {
+	Event       string         `json:"event"`
+	Address     common.Address `json:"address"`
+	BlockNumber uint64         `json:"blockNumber"`
+	BlockTime   string         `json:"blockTime,omitempty"`
+	TxHash      common.Hash    `json:"txHash"`
+	LogIndex    uint           `json:"logIndex"`
+	Removed     bool           `json:"removed,omitempty"`
+	Fields      []Field        `json:"fields"`
+}
+
+// Get 按参数名取原始值
+func (r *Record) Get(name string) (any, bool) {
+	for _, f := range r.Fields {
+		if f.Name == name {
+			return f.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Display 按参数名取可读形式，模板中可以写 {{.Display "value"}}
+func (r *Record) Display(name string) string {
+	for _, f := range r.Fields {
+		if f.Name == name {
+			return f.Display
+		}
+	}
+	return ""
+}
+
+// Decoder 用合约 ABI 解码日志的 indexed 和非 indexed 参数。
+// 参数名不能说明单位，金额精度和时间字段都要显式配置，未配置的整数按原值显示
+type Decoder struct {
+	ABI abi.ABI
+
+	// Decimals 参数名 → 精度，命中的整数按精度缩放显示
+	Decimals map[string]uint8
+	// Amounts 参数名 → 代币来源：为空表示发出事件的合约本身（如 ERC20 Transfer 的 value）；
+	// 以 () 结尾表示调用发出事件的合约上返回代币地址的 view 函数（如 IDO 池子的 offeringToken()）；
+	// 其余为事件中代币地址参数的名字。按 TokenDecimals 查到的代币精度缩放，Decimals 中配置的优先
+	Amounts map[string]string
+	// TokenDecimals 查询代币精度，查不到时返回 false，见 TokenCache
+	TokenDecimals func(token common.Address) (uint8, bool)
+	// TokenAddress 调用 contract 上的 getter 取代币地址，查不到时返回 false，见 TokenCache
+	TokenAddress func(contract common.Address, getter string) (common.Address, bool)
+	// TimeFields 按时间显示的参数名
+	TimeFields []string
+	// Location 时间显示的时区
+	Location *time.Location
+}
+
+func NewDecoder(contractAbi abi.ABI) *Decoder {
+	return &Decoder{
+		ABI:      contractAbi,
+		Decimals: make(map[string]uint8),
+		Amounts:  make(map[string]string),
+		Location: time.Local,
+	}
+}
+
+// Event 按 topic0 查找事件
+func (d *Decoder) Event(topic common.Hash) (*abi.Event, bool) {
+	event, err := d.ABI.EventByID(topic)
+	return event, err == nil
+}
+
+// Decode 解码日志，blockTime 为日志所在区块的时间，未知时传零值
+func (d *Decoder) Decode(l types.Log, blockTime time.Time) (*Record, error) {
+	if len(l.Topics) == 0 {
+		return nil, ErrUnknownEvent
+	}
+	event, ok := d.Event(l.Topics[0])
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEvent, l.Topics[0].Hex())
+	}
+
+	values := make(map[string]any)
+	if len(l.Data) > 0 {
+		if err := event.Inputs.NonIndexed().UnpackIntoMap(values, l.Data); err != nil {
+			return nil, fmt.Errorf("decode %s data: %w", event.Name, err)
+		}
+	}
+	var indexed abi.Arguments
+	for _, arg := range event.Inputs {
+		if arg.Indexed {
+			indexed = append(indexed, arg)
+		}
+	}
+	if len(l.Topics)-1 != len(indexed) {
+		return nil, fmt.Errorf("decode %s: expected %d indexed topics, got %d", event.Name, len(indexed), len(l.Topics)-1)
+	}
+	if err := abi.ParseTopicsIntoMap(values, indexed, l.Topics[1:]); err != nil {
+		return nil, fmt.Errorf("decode %s topics: %w", event.Name, err)
+	}
+
+	r := &Record{
+		Event:       event.Name,
+		Address:     l.Address,
+		BlockNumber: l.BlockNumber,
+		TxHash:      l.TxHash,
+		LogIndex:    l.Index,
+		Removed:     l.Removed,
+	}
+	if !blockTime.IsZero() {
+		r.BlockTime = blockTime.In(d.Location).Format(TimeLayout)
+	}
+	for i, arg := range event.Inputs {
+		name := arg.Name
+		if name == "" {
+			name = fmt.Sprintf("arg%d", i)
+		}
+		value := values[arg.Name]
+		r.Fields = append(r.Fields, Field{
+			Name:    name,
+			Type:    arg.Type.String(),
+			Indexed: arg.Indexed,
+			Value:   value,
+			Display: d.display(arg, value, l.Address, values),
+		})
+	}
+	return r, nil
+}
+
+// display 生成可读形式，emitter 和 values 用来找金额对应的代币
+func (d *Decoder) display(arg abi.Argument, value any, emitter common.Address, values map[string]any) string {
+	switch v := value.(type) {
+	case common.Address:
+		return v.Hex()
+	case common.Hash:
+		return v.Hex()
+	case []byte:
+		return hexutil.Encode(v)
+	case bool, string:
+		return fmt.Sprint(v)
+	}
+	n, ok := toBig(value)
+	if !ok {
+		return fmt.Sprint(value)
+	}
+	if slices.Contains(d.TimeFields, arg.Name) && n.IsInt64() {
+		return time.Unix(n.Int64(), 0).In(d.Location).Format(TimeLayout)
+	}
+	if decimals, ok := d.decimals(arg.Name, emitter, values); ok {
+		return w3.FromWei(n, decimals)
+	}
+	return n.String()
+}
+
+// decimals 先看显式配置的精度，再按 Amounts 找到代币查询精度
+func (d *Decoder) decimals(name string, emitter common.Address, values map[string]any) (uint8, bool) {
+	if decimals, ok := d.Decimals[name]; ok {
+		return decimals, true
+	}
+	tokenArg, ok := d.Amounts[name]
+	if !ok || d.TokenDecimals == nil {
+		return 0, false
+	}
+	token := emitter
+	switch {
+	case strings.HasSuffix(tokenArg, "()"):
+		if d.TokenAddress == nil {
+			return 0, false
+		}
+		if token, ok = d.TokenAddress(emitter, tokenArg); !ok {
+			return 0, false
+		}
+	case tokenArg != "":
+		if token, ok = values[tokenArg].(common.Address); !ok {
+			return 0, false
+		}
+	}
+	return d.TokenDecimals(token)
+}
+
+// toBig 把 abi 解出的各宽度整数统一为 *big.Int
+func toBig(value any) (*big.Int, bool) {
+	switch v := value.(type) {
+	case *big.Int:
+		return v, true
+	case uint8:
+		return new(big.Int).SetUint64(uint64(v)), true
+	case uint16:
+		return new(big.Int).SetUint64(uint64(v)), true
+	case uint32:
+		return new(big.Int).SetUint64(uint64(v)), true
+	case uint64:
+		return new(big.Int).SetUint64(v), true
+	case int8:
+		return big.NewInt(int64(v)), true
+	case int16:
+		return big.NewInt(int64(v)), true
+	case int32:
+		return big.NewInt(int64(v)), true
+	case int64:
+		return big.NewInt(v), true
+	}
+	return nil, false
+}
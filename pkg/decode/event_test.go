@@ -0,0 +1,70 @@
+package decode
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const idoABI = `[{"type":"event","name":"PoolParametersSet","anonymous":false,"inputs":[
+	{"name":"offeringAmountPool","type":"uint256","indexed":false},
+	{"name":"raisingAmountPool","type":"uint256","indexed":false},
+	{"name":"pid","type":"uint8","indexed":false}]}]`
+
+func TestDecodeAmounts(t *testing.T) {
+	contractAbi, err := abi.JSON(strings.NewReader(idoABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		pool     = common.HexToAddress("0x1111111111111111111111111111111111111111")
+		offering = common.HexToAddress("0x2222222222222222222222222222222222222222")
+		lp       = common.HexToAddress("0x3333333333333333333333333333333333333333")
+		event    = contractAbi.Events["PoolParametersSet"]
+	)
+	data, err := event.Inputs.Pack(big.NewInt(1_500_000_000), new(big.Int).Mul(big.NewInt(25), big.NewInt(1e18)), uint8(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := types.Log{Address: pool, Topics: []common.Hash{event.ID}, Data: data}
+
+	d := NewDecoder(contractAbi)
+	r, err := d.Decode(l, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 没有配置时按原值显示，不按参数名猜测精度
+	if got := r.Display("raisingAmountPool"); got != "25000000000000000000" {
+		t.Errorf("unconfigured raisingAmountPool = %s", got)
+	}
+
+	d.Amounts = map[string]string{"offeringAmountPool": "offeringToken()", "raisingAmountPool": "lpToken()"}
+	d.TokenAddress = func(contract common.Address, getter string) (common.Address, bool) {
+		if contract != pool {
+			return common.Address{}, false
+		}
+		return map[string]common.Address{"offeringToken()": offering, "lpToken()": lp}[getter], true
+	}
+	d.TokenDecimals = func(token common.Address) (uint8, bool) {
+		return map[common.Address]uint8{offering: 6, lp: 18}[token], true
+	}
+	if r, err = d.Decode(l, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"offeringAmountPool": "1500", "raisingAmountPool": "25", "pid": "1"} {
+		if got := r.Display(name); got != want {
+			t.Errorf("%s = %s, want %s", name, got, want)
+		}
+	}
+
+	// 显式精度优先
+	d.Decimals["raisingAmountPool"] = 9
+	if r, _ = d.Decode(l, time.Time{}); r.Display("raisingAmountPool") != "25000000000" {
+		t.Errorf("Decimals override: raisingAmountPool = %s", r.Display("raisingAmountPool"))
+	}
+}
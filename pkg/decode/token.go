@@ -0,0 +1,95 @@
+package decode
+
+import (
+	"chainget/pkg/multicall"
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/lmittmann/w3"
+)
+
+var funcDecimals = w3.MustNewFunc("decimals()", "uint8")
+
+// TokenCache 用 multicall 查询代币精度和合约上的代币地址并缓存，
+// Decimals、Address 可以直接作为 Decoder.TokenDecimals、Decoder.TokenAddress
+type TokenCache struct {
+	caller  *multicall.Caller
+	Timeout time.Duration
+
+	mu       sync.Mutex
+	decimals map[common.Address]uint8
+	failed   map[common.Address]bool //不是 ERC20 的地址，不再重复查询
+	tokens   map[tokenGetter]common.Address
+}
+
+type tokenGetter struct {
+	contract common.Address
+	getter   string
+}
+
+func NewTokenCache(caller *multicall.Caller) *TokenCache {
+	return &TokenCache{
+		caller:   caller,
+		Timeout:  10 * time.Second,
+		decimals: make(map[common.Address]uint8),
+		failed:   make(map[common.Address]bool),
+		tokens:   make(map[tokenGetter]common.Address),
+	}
+}
+
+// Address 调用 contract 上返回地址的无参 view 函数（如 "offeringToken()"）取代币地址，成功的结果缓存
+func (c *TokenCache) Address(contract common.Address, getter string) (common.Address, bool) {
+	key := tokenGetter{contract, getter}
+	c.mu.Lock()
+	token, ok := c.tokens[key]
+	c.mu.Unlock()
+	if ok {
+		return token, true
+	}
+	fn, err := w3.NewFunc(getter, "address")
+	if err != nil {
+		log.Printf("⚠️ 无效的代币地址函数 %q: %v", getter, err)
+		return common.Address{}, false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+	call := multicall.NewCall(contract, fn).Returns(&token)
+	if err := c.caller.Do(ctx, call); err != nil {
+		log.Printf("⚠️ 调用 %s %s 失败: %v", contract.Hex(), getter, err)
+		return common.Address{}, false
+	}
+	c.mu.Lock()
+	c.tokens[key] = token
+	c.mu.Unlock()
+	return token, true
+}
+
+// Decimals 返回代币精度；合约没有 decimals() 时返回 false，节点错误不缓存，下次重新查询
+func (c *TokenCache) Decimals(token common.Address) (uint8, bool) {
+	c.mu.Lock()
+	decimals, ok := c.decimals[token]
+	failed := c.failed[token]
+	c.mu.Unlock()
+	if ok || failed {
+		return decimals, ok
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+	call := multicall.NewCall(token, funcDecimals).Returns(&decimals).Optional()
+	if err := c.caller.Do(ctx, call); err != nil {
+		log.Printf("⚠️ 查询代币 %s 精度失败: %v", token.Hex(), err)
+		return 0, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if call.Err != nil {
+		c.failed[token] = true
+		return 0, false
+	}
+	c.decimals[token] = decimals
+	return decimals, true
+}
@@ -0,0 +1,100 @@
+package dex
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"gopkg.in/yaml.v3"
+)
+
+// File 交易对监听配置
+//
+//	rpc: ["wss://ethereum-rpc.publicnode.com"]
+//	pairs:
+//	  - dex: uniswap
+//	    address: "0xB4e16d0168e52d35CaCD2c6185b44281Ec28C9Dc"   # USDC/WETH
+//	    base: "0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2"      # 按 WETH 计价
+//	  - {dex: pancakeswap, address: "0x…", fee: 25}
+//	  - {dex: uniswap, version: 3, address: "0x88e6A0c2dDD26FEEb64F039a2c41296FcB3f5640"}  # V3 池子，手续费从链上读取
+type File struct {
+	RPC          []string      `yaml:"rpc"`
+	PollInterval time.Duration `yaml:"pollInterval"` // http(s) 节点的轮询间隔
+	Multicall    string        `yaml:"multicall"`    // Multicall3 地址，默认 0xcA11…CA11
+	Pairs        []PairConfig  `yaml:"pairs"`
+}
+
+// PairConfig 单个交易对
+type PairConfig struct {
+	Name    string `yaml:"name"`
+	DEX     string `yaml:"dex"` // uniswap | sushiswap | pancakeswap | 其他 V2 分叉
+	Address string `yaml:"address"`
+	Base    string `yaml:"base"`    // 基础代币地址，默认 token0
+	Fee     uint64 `yaml:"fee"`     // 万分之几，默认按 dex 取；V3 忽略
+	Version int    `yaml:"version"` // 2 或 3，默认 2
+}
+
+// Load 读取配置并校验地址
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f File
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	if len(f.Pairs) == 0 {
+		return nil, fmt.Errorf("%s: no pairs", path)
+	}
+	for i, p := range f.Pairs {
+		if !common.IsHexAddress(p.Address) {
+			return nil, fmt.Errorf("pair %d: invalid address %q", i, p.Address)
+		}
+		if p.Base != "" && !common.IsHexAddress(p.Base) {
+			return nil, fmt.Errorf("pair %s: invalid base %q", p.Address, p.Base)
+		}
+		if p.Version != 0 && p.Version != 2 && p.Version != 3 {
+			return nil, fmt.Errorf("pair %s: unsupported version %d", p.Address, p.Version)
+		}
+		if p.Version != 3 && p.Fee >= 10000 {
+			return nil, fmt.Errorf("pair %s: fee %d must be below 10000 (万分之几)", p.Address, p.Fee)
+		}
+	}
+	if f.Multicall != "" && !common.IsHexAddress(f.Multicall) {
+		return nil, fmt.Errorf("invalid multicall address %q", f.Multicall)
+	}
+	return &f, nil
+}
+
+// Pair 未加载代币信息的交易对
+func (c PairConfig) Pair() *Pair {
+	p := &Pair{Market: c.market(), Fee: c.Fee}
+	if p.Fee == 0 {
+		p.Fee = 30
+		if fee, ok := Fees[p.DEX]; ok {
+			p.Fee = fee
+		}
+	}
+	return p
+}
+
+// Pool 未加载代币和手续费的 V3 池子
+func (c PairConfig) Pool() *Pool {
+	return &Pool{Market: c.market()}
+}
+
+// V3 是否为 V3 池子
+func (c PairConfig) V3() bool {
+	return c.Version == 3
+}
+
+func (c PairConfig) market() Market {
+	m := Market{Name: c.Name, DEX: strings.ToLower(c.DEX), Address: common.HexToAddress(c.Address)}
+	if c.Base != "" {
+		m.base = common.HexToAddress(c.Base)
+	}
+	return m
+}
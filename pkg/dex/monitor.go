@@ -0,0 +1,355 @@
+package dex
+
+import (
+	"chainget/pkg/multicall"
+	"chainget/pkg/poll"
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Monitor 订阅 V2 交易对的 Sync、Swap 事件和 V3 池子的 Swap、Mint、Burn 事件，在内存中维护储备量和池子状态
+type Monitor struct {
+	client poll.Backend
+	caller *multicall.Caller
+	pairs  map[common.Address]*Pair
+	pools  map[common.Address]*Pool
+	cursor poll.Cursor // 已处理到的日志，重新订阅时从它所在的区块补齐
+
+	OnSwap func(*Swap)
+	OnSync func(*Pair)
+	OnPool func(*Pool) // V3 池子价格或区间内流动性变化
+}
+
+func NewMonitor(client poll.Backend, caller *multicall.Caller, pairs []*Pair, pools []*Pool) *Monitor {
+	m := &Monitor{
+		client: client, caller: caller,
+		pairs: make(map[common.Address]*Pair, len(pairs)),
+		pools: make(map[common.Address]*Pool, len(pools)),
+	}
+	for _, p := range pairs {
+		m.pairs[p.Address] = p
+	}
+	for _, p := range pools {
+		m.pools[p.Address] = p
+	}
+	return m
+}
+
+// Pair 按地址查找交易对
+func (m *Monitor) Pair(address common.Address) (*Pair, bool) {
+	p, ok := m.pairs[address]
+	return p, ok
+}
+
+// Pairs 全部交易对
+func (m *Monitor) Pairs() []*Pair {
+	pairs := make([]*Pair, 0, len(m.pairs))
+	for _, p := range m.pairs {
+		pairs = append(pairs, p)
+	}
+	return pairs
+}
+
+// Pool 按地址查找 V3 池子
+func (m *Monitor) Pool(address common.Address) (*Pool, bool) {
+	p, ok := m.pools[address]
+	return p, ok
+}
+
+// Pools 全部 V3 池子
+func (m *Monitor) Pools() []*Pool {
+	pools := make([]*Pool, 0, len(m.pools))
+	for _, p := range m.pools {
+		pools = append(pools, p)
+	}
+	return pools
+}
+
+// Load 用 Multicall3 在同一区块读取 token0、token1、储备量（V3 为 slot0、流动性、手续费和 tick 间距）和代币信息，Run 之前调用
+func (m *Monitor) Load(ctx context.Context) error {
+	head, err := m.client.BlockNumber(ctx)
+	if err != nil {
+		return err
+	}
+	defer m.at(head)()
+
+	pairs, pools := m.Pairs(), m.Pools()
+	type state struct {
+		token0, token1     common.Address
+		reserve0, reserve1 *big.Int
+		timestamp          uint32
+		sqrtPriceX96       *big.Int
+		liquidity          *big.Int
+		tick, fee, spacing *big.Int
+	}
+	states := make([]state, len(pairs)+len(pools))
+	var calls []*multicall.Call
+	for i, p := range pairs {
+		s := &states[i]
+		s.reserve0, s.reserve1 = new(big.Int), new(big.Int)
+		calls = append(calls,
+			multicall.NewCall(p.Address, funcToken0).Returns(&s.token0),
+			multicall.NewCall(p.Address, funcToken1).Returns(&s.token1),
+			multicall.NewCall(p.Address, funcGetReserves).Returns(s.reserve0, s.reserve1, &s.timestamp),
+		)
+	}
+	for i, p := range pools {
+		s := &states[len(pairs)+i]
+		s.sqrtPriceX96, s.liquidity, s.tick, s.fee, s.spacing = new(big.Int), new(big.Int), new(big.Int), new(big.Int), new(big.Int)
+		calls = append(calls,
+			multicall.NewCall(p.Address, funcToken0).Returns(&s.token0),
+			multicall.NewCall(p.Address, funcToken1).Returns(&s.token1),
+			multicall.NewCall(p.Address, funcSlot0).Returns(s.sqrtPriceX96, s.tick),
+			multicall.NewCall(p.Address, funcLiquidity).Returns(s.liquidity),
+			multicall.NewCall(p.Address, funcFee).Returns(s.fee),
+			multicall.NewCall(p.Address, funcTickSpacing).Returns(s.spacing),
+		)
+	}
+	if err := m.caller.Do(ctx, calls...); err != nil {
+		return fmt.Errorf("load pairs: %w", err)
+	}
+
+	seen := make(map[common.Address]bool)
+	var addresses []common.Address
+	for _, s := range states {
+		for _, t := range []common.Address{s.token0, s.token1} {
+			if !seen[t] {
+				seen[t] = true
+				addresses = append(addresses, t)
+			}
+		}
+	}
+	infos, err := m.caller.Tokens(ctx, addresses...)
+	if err != nil {
+		return fmt.Errorf("load tokens: %w", err)
+	}
+	tokens := make(map[common.Address]Token, len(infos))
+	for _, t := range infos {
+		if t.Err != nil {
+			log.Printf("⚠️ 代币 %s 信息不完整: %v", t.Address.Hex(), t.Err)
+		}
+		if t.Symbol == "" {
+			t.Symbol = t.Address.Hex()[:8]
+		}
+		tokens[t.Address] = Token{Address: t.Address, Symbol: t.Symbol, Decimals: t.Decimals}
+	}
+	for i, p := range pairs {
+		s := states[i]
+		if err := p.setTokens(tokens[s.token0], tokens[s.token1]); err != nil {
+			return err
+		}
+		p.SetReserves(s.reserve0, s.reserve1, head, 0)
+	}
+	for i, p := range pools {
+		s := states[len(pairs)+i]
+		if err := p.setTokens(tokens[s.token0], tokens[s.token1]); err != nil {
+			return err
+		}
+		p.Fee, p.TickSpacing = s.fee.Uint64(), int(s.spacing.Int64())
+		p.SetState(s.sqrtPriceX96, s.liquidity, int(s.tick.Int64()), head, 0)
+	}
+	m.cursor = poll.BlockCursor(head)
+	return nil
+}
+
+// Run 订阅事件直到 ctx 取消，中断后 1 秒重试并从上次处理到的日志补齐
+func (m *Monitor) Run(ctx context.Context) error {
+	for ctx.Err() == nil {
+		err := m.run(ctx)
+		if ctx.Err() != nil {
+			break
+		}
+		log.Printf("⚠️ 交易对订阅中断，1 秒后重试: %v", err)
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+		}
+	}
+	return ctx.Err()
+}
+
+func (m *Monitor) run(ctx context.Context) error {
+	q := ethereum.FilterQuery{
+		Addresses: make([]common.Address, 0, len(m.pairs)+len(m.pools)),
+		Topics:    [][]common.Hash{{EventSync.Topic0, EventSwap.Topic0, EventSwapV3.Topic0, EventMint.Topic0, EventBurn.Topic0}},
+	}
+	for address := range m.pairs {
+		q.Addresses = append(q.Addresses, address)
+	}
+	for address := range m.pools {
+		q.Addresses = append(q.Addresses, address)
+	}
+	logs := make(chan types.Log, 256)
+	sub, err := m.client.SubscribeFilterLogs(ctx, q, logs)
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+	if !m.cursor.IsZero() {
+		head, err := m.client.BlockNumber(ctx)
+		if err != nil {
+			return err
+		}
+		//从游标所在区块补齐，同一区块中断后的日志也不会漏掉
+		if head >= m.cursor.Block {
+			q.FromBlock, q.ToBlock = new(big.Int).SetUint64(m.cursor.Block), new(big.Int).SetUint64(head)
+			missed, err := m.client.FilterLogs(ctx, q)
+			if err != nil {
+				return err
+			}
+			for _, l := range missed {
+				m.next(ctx, l)
+			}
+			m.cursor = poll.BlockCursor(head)
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-sub.Err():
+			return err
+		case l := <-logs:
+			m.next(ctx, l)
+		}
+	}
+}
+
+// next 丢掉补齐和订阅重复推送的已处理日志，被重组移除的日志总是处理
+func (m *Monitor) next(ctx context.Context, l types.Log) {
+	if !l.Removed && m.cursor.Covers(l) {
+		return
+	}
+	m.handle(ctx, l)
+	m.cursor = m.cursor.Next(l)
+}
+
+// handle Sync 更新储备量，Swap 按更新后的储备量解码；被重组移除的日志让储备量重新从链上读取
+func (m *Monitor) handle(ctx context.Context, l types.Log) {
+	if len(l.Topics) == 0 {
+		return
+	}
+	if pool, ok := m.pools[l.Address]; ok {
+		m.handlePool(ctx, pool, l)
+		return
+	}
+	p, ok := m.pairs[l.Address]
+	if !ok {
+		return
+	}
+	if l.Removed {
+		m.reload(ctx, p)
+		return
+	}
+	switch l.Topics[0] {
+	case EventSync.Topic0:
+		r0, r1, err := DecodeSync(&l)
+		if err != nil {
+			log.Printf("❌ [%s] 解码 Sync 失败 tx: %s: %v", p, l.TxHash.Hex(), err)
+			return
+		}
+		if p.SetReserves(r0, r1, l.BlockNumber, l.Index) && m.OnSync != nil {
+			m.OnSync(p)
+		}
+	case EventSwap.Topic0:
+		s, err := p.DecodeSwap(&l)
+		if err != nil {
+			log.Printf("❌ [%s] 解码 Swap 失败 tx: %s: %v", p, l.TxHash.Hex(), err)
+			return
+		}
+		if m.OnSwap != nil {
+			m.OnSwap(s)
+		}
+	}
+}
+
+// handlePool V3 Swap 解码并更新价格、tick 和流动性，Mint、Burn 调整区间内流动性
+func (m *Monitor) handlePool(ctx context.Context, p *Pool, l types.Log) {
+	if l.Removed {
+		m.reloadPool(ctx, p)
+		return
+	}
+	switch l.Topics[0] {
+	case EventSwapV3.Topic0:
+		s, err := p.DecodeSwap(&l)
+		if err != nil {
+			log.Printf("❌ [%s] 解码 Swap 失败 tx: %s: %v", p, l.TxHash.Hex(), err)
+			return
+		}
+		if m.OnSwap != nil {
+			m.OnSwap(s)
+		}
+		if m.OnPool != nil && s.PriceBefore != nil {
+			m.OnPool(p)
+		}
+	case EventMint.Topic0, EventBurn.Topic0:
+		changed, err := p.ApplyLiquidity(&l)
+		if err != nil {
+			log.Printf("❌ [%s] 解码流动性事件失败 tx: %s: %v", p, l.TxHash.Hex(), err)
+			return
+		}
+		if changed && m.OnPool != nil {
+			m.OnPool(p)
+		}
+	}
+}
+
+// reload 重组后按最新区块重新读取储备量
+func (m *Monitor) reload(ctx context.Context, p *Pair) {
+	head, err := m.client.BlockNumber(ctx)
+	if err != nil {
+		log.Printf("⚠️ [%s] 重组后读取区块号失败: %v", p, err)
+		return
+	}
+	r0, r1 := new(big.Int), new(big.Int)
+	var timestamp uint32
+	restore := m.at(head)
+	err = m.caller.Do(ctx, multicall.NewCall(p.Address, funcGetReserves).Returns(r0, r1, &timestamp))
+	restore()
+	if err != nil {
+		log.Printf("⚠️ [%s] 重组后读取储备量失败: %v", p, err)
+		return
+	}
+	p.mu.Lock()
+	p.reserve0, p.reserve1, p.block, p.index = r0, r1, head, ^uint(0)
+	p.mu.Unlock()
+	log.Printf("⚠️ [%s] 区块重组，储备量按区块 %d 重新读取", p, head)
+}
+
+// reloadPool 重组后按最新区块重新读取 slot0 和流动性
+func (m *Monitor) reloadPool(ctx context.Context, p *Pool) {
+	head, err := m.client.BlockNumber(ctx)
+	if err != nil {
+		log.Printf("⚠️ [%s] 重组后读取区块号失败: %v", p, err)
+		return
+	}
+	sqrtPriceX96, tick, liquidity := new(big.Int), new(big.Int), new(big.Int)
+	restore := m.at(head)
+	err = m.caller.Do(ctx,
+		multicall.NewCall(p.Address, funcSlot0).Returns(sqrtPriceX96, tick),
+		multicall.NewCall(p.Address, funcLiquidity).Returns(liquidity),
+	)
+	restore()
+	if err != nil {
+		log.Printf("⚠️ [%s] 重组后读取池子状态失败: %v", p, err)
+		return
+	}
+	p.mu.Lock()
+	p.sqrtPriceX96, p.liquidity, p.tick, p.block, p.index = sqrtPriceX96, liquidity, int(tick.Int64()), head, ^uint(0)
+	p.mu.Unlock()
+	log.Printf("⚠️ [%s] 区块重组，池子状态按区块 %d 重新读取", p, head)
+}
+
+// at 让 caller 读取指定区块，返回恢复原设置的函数
+func (m *Monitor) at(block uint64) func() {
+	saved := m.caller.Block
+	m.caller.Block = new(big.Int).SetUint64(block)
+	return func() { m.caller.Block = saved }
+}
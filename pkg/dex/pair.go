@@ -0,0 +1,155 @@
+package dex
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Fees 常见 V2 分叉的手续费（万分之几），配置未指定 fee 时按 dex 名取，未知的按 30
+var Fees = map[string]uint64{
+	"uniswap":     30,
+	"sushiswap":   30,
+	"pancakeswap": 25,
+}
+
+// Token 代币地址、符号和精度
+type Token struct {
+	Address  common.Address `json:"address"`
+	Symbol   string         `json:"symbol"`
+	Decimals uint8          `json:"decimals"`
+}
+
+// Market V2 交易对和 V3 池子共有的信息
+type Market struct {
+	Name    string // 配置的名字，为空时为基础代币/报价代币的符号
+	DEX     string
+	Address common.Address
+	Token0  Token
+	Token1  Token
+	Base    int // 计价时的基础代币：0 为 token0，1 为 token1；价格为每个基础代币值多少另一个代币
+
+	base common.Address // 配置的基础代币，加载代币后换算成 Base
+}
+
+// Pair V2 交易对，储备量由 Sync 事件维护
+type Pair struct {
+	Market
+	Fee uint64 // 万分之几
+
+	mu       sync.RWMutex
+	reserve0 *big.Int
+	reserve1 *big.Int
+	block    uint64 // 最后一次更新储备量的区块和日志序号，旧日志不会覆盖新状态
+	index    uint
+}
+
+// Reserves 当前储备量和更新时的区块
+func (p *Pair) Reserves() (reserve0, reserve1 *big.Int, block uint64) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.reserve0 == nil {
+		return new(big.Int), new(big.Int), p.block
+	}
+	return new(big.Int).Set(p.reserve0), new(big.Int).Set(p.reserve1), p.block
+}
+
+// SetReserves 更新储备量，(block, index) 不比当前新时忽略并返回 false
+func (p *Pair) SetReserves(reserve0, reserve1 *big.Int, block uint64, index uint) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.reserve0 != nil && (block < p.block || block == p.block && index <= p.index) {
+		return false
+	}
+	p.reserve0, p.reserve1 = new(big.Int).Set(reserve0), new(big.Int).Set(reserve1)
+	p.block, p.index = block, index
+	return true
+}
+
+// BaseToken、QuoteToken 计价的基础代币和报价代币
+func (m *Market) BaseToken() Token {
+	if m.Base == 1 {
+		return m.Token1
+	}
+	return m.Token0
+}
+
+func (m *Market) QuoteToken() Token {
+	if m.Base == 1 {
+		return m.Token0
+	}
+	return m.Token1
+}
+
+// setTokens 设置加载到的代币，并把配置的基础代币换算成 Base
+func (m *Market) setTokens(token0, token1 Token) error {
+	m.Token0, m.Token1 = token0, token1
+	switch m.base {
+	case common.Address{}, token0.Address:
+		m.Base = 0
+	case token1.Address:
+		m.Base = 1
+	default:
+		return fmt.Errorf("%s: base %s is neither token0 nor token1", m.Address.Hex(), m.base.Hex())
+	}
+	return nil
+}
+
+// orient 把 token0、token1 顺序的一对值换成基础代币、报价代币的顺序
+func (m *Market) orient(x0, x1 *big.Int) (base, quote *big.Int) {
+	if m.Base == 1 {
+		return x1, x0
+	}
+	return x0, x1
+}
+
+// Price 中间价：每个基础代币值多少报价代币，已按精度换算；储备为空时为 nil
+func (p *Pair) Price() *big.Float {
+	r0, r1, _ := p.Reserves()
+	return p.price(r0, r1)
+}
+
+func (p *Pair) price(r0, r1 *big.Int) *big.Float {
+	base, quote := p.orient(r0, r1)
+	return Ratio(quote, p.QuoteToken().Decimals, base, p.BaseToken().Decimals)
+}
+
+func (m *Market) String() string {
+	name := m.Name
+	if name == "" {
+		name = m.BaseToken().Symbol + "/" + m.QuoteToken().Symbol
+	}
+	if m.DEX != "" {
+		return m.DEX + " " + name
+	}
+	return name
+}
+
+// Amount 按精度换算成小数
+func Amount(x *big.Int, decimals uint8) *big.Float {
+	f := new(big.Float).SetPrec(256).SetInt(x)
+	return f.Quo(f, new(big.Float).SetPrec(256).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)))
+}
+
+// Ratio (num / 10^numDecimals) / (den / 10^denDecimals)，den 为零时为 nil
+func Ratio(num *big.Int, numDecimals uint8, den *big.Int, denDecimals uint8) *big.Float {
+	if den.Sign() == 0 {
+		return nil
+	}
+	return new(big.Float).Quo(Amount(num, numDecimals), Amount(den, denDecimals))
+}
+
+// FormatPrice 有效数字足够看清小币价格
+func FormatPrice(f *big.Float) string {
+	if f == nil {
+		return "-"
+	}
+	return f.Text('g', 8)
+}
+
+// FormatAmount 代币数量，保留 6 位有效小数
+func FormatAmount(x *big.Int, decimals uint8) string {
+	return Amount(x, decimals).Text('f', min(int(decimals), 6))
+}
@@ -0,0 +1,320 @@
+package dex
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// 离线报价：只用 Monitor 在内存中维护的储备量和池子状态，不请求节点
+
+var (
+	ErrNoLiquidity = errors.New("no liquidity")
+	ErrOutOfRange  = errors.New("amount exceeds current tick range")
+	ErrNoRoute     = errors.New("no route")
+)
+
+// Source 可离线报价的 V2 交易对或 V3 池子
+type Source interface {
+	Info() *Market
+	// QuoteIn 付出 amountIn 个 tokenIn 能换到多少另一个代币，已扣手续费
+	QuoteIn(tokenIn common.Address, amountIn *big.Int) (*Hop, error)
+}
+
+// Hop 在一个交易对或池子中的一次兑换
+type Hop struct {
+	Market    *Market
+	TokenIn   Token
+	TokenOut  Token
+	AmountIn  *big.Int
+	AmountOut *big.Int
+	Fee       float64 // 手续费比例，0.003 即 0.3%
+	// SpotBefore、SpotAfter 兑换前后的中间价：每个 TokenIn 值多少 TokenOut，已按精度换算
+	SpotBefore *big.Float
+	SpotAfter  *big.Float
+}
+
+// Price 成交价：每个 TokenIn 换到多少 TokenOut
+func (h *Hop) Price() *big.Float {
+	return Ratio(h.AmountOut, h.TokenOut.Decimals, h.AmountIn, h.TokenIn.Decimals)
+}
+
+// Info 交易对的公共信息
+func (m *Market) Info() *Market {
+	return m
+}
+
+// other 另一个代币，token 不属于这个市场时返回 false
+func (m *Market) other(token common.Address) (in, out Token, ok bool) {
+	switch token {
+	case m.Token0.Address:
+		return m.Token0, m.Token1, true
+	case m.Token1.Address:
+		return m.Token1, m.Token0, true
+	}
+	return Token{}, Token{}, false
+}
+
+// QuoteIn 恒定乘积报价，与 UniswapV2Library.getAmountOut 一致：
+// amountOut = amountIn * (10000 - fee) * reserveOut / (reserveIn * 10000 + amountIn * (10000 - fee))
+func (p *Pair) QuoteIn(tokenIn common.Address, amountIn *big.Int) (*Hop, error) {
+	in, out, ok := p.other(tokenIn)
+	if !ok {
+		return nil, fmt.Errorf("%s: token %s not in pair", p, tokenIn.Hex())
+	}
+	if p.Fee >= 10000 {
+		return nil, fmt.Errorf("%s: invalid fee %d", p, p.Fee)
+	}
+	r0, r1, _ := p.Reserves()
+	reserveIn, reserveOut := r0, r1
+	if in.Address == p.Token1.Address {
+		reserveIn, reserveOut = r1, r0
+	}
+	if reserveIn.Sign() == 0 || reserveOut.Sign() == 0 {
+		return nil, fmt.Errorf("%s: %w", p, ErrNoLiquidity)
+	}
+	withFee := new(big.Int).Mul(amountIn, big.NewInt(int64(10000-p.Fee)))
+	numerator := new(big.Int).Mul(withFee, reserveOut)
+	denominator := new(big.Int).Mul(reserveIn, big.NewInt(10000))
+	denominator.Add(denominator, withFee)
+	amountOut := numerator.Quo(numerator, denominator)
+
+	h := &Hop{
+		Market: &p.Market, TokenIn: in, TokenOut: out, AmountIn: new(big.Int).Set(amountIn), AmountOut: amountOut,
+		Fee:        float64(p.Fee) / 1e4,
+		SpotBefore: Ratio(reserveOut, out.Decimals, reserveIn, in.Decimals),
+	}
+	h.SpotAfter = Ratio(new(big.Int).Sub(reserveOut, amountOut), out.Decimals, new(big.Int).Add(reserveIn, amountIn), in.Decimals)
+	return h, nil
+}
+
+// QuoteIn 按当前价格和区间内流动性做一次 SwapMath.computeSwapStep，价格最多走到当前 tick 间距区间的边界：
+// 只有区间内的流动性是已知的，区间内放不下 amountIn 时返回 ErrOutOfRange，大额兑换应以链上 Quoter 为准
+func (p *Pool) QuoteIn(tokenIn common.Address, amountIn *big.Int) (*Hop, error) {
+	in, out, ok := p.other(tokenIn)
+	if !ok {
+		return nil, fmt.Errorf("%s: token %s not in pool", p, tokenIn.Hex())
+	}
+	sqrtPrice, liquidity, tick, _ := p.State()
+	if sqrtPrice.Sign() == 0 || liquidity.Sign() == 0 {
+		return nil, fmt.Errorf("%s: %w", p, ErrNoLiquidity)
+	}
+	if p.TickSpacing <= 0 {
+		return nil, fmt.Errorf("%s: tick spacing not loaded", p)
+	}
+	zeroForOne := in.Address == p.Token0.Address
+	target, err := p.boundary(tick, zeroForOne)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p, err)
+	}
+	step, err := ComputeSwapStep(sqrtPrice, target, liquidity, amountIn, p.Fee)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p, err)
+	}
+	if used := new(big.Int).Add(step.AmountIn, step.FeeAmount); used.Cmp(amountIn) < 0 {
+		return nil, fmt.Errorf("%s: %w: 区间内最多付出 %s", p, ErrOutOfRange, FormatAmount(used, in.Decimals))
+	}
+
+	return &Hop{
+		Market: &p.Market, TokenIn: in, TokenOut: out, AmountIn: new(big.Int).Set(amountIn), AmountOut: step.AmountOut,
+		Fee:        float64(p.Fee) / 1e6,
+		SpotBefore: p.spot(sqrtPrice, zeroForOne),
+		SpotAfter:  p.spot(step.SqrtPriceNextX96, zeroForOne),
+	}, nil
+}
+
+// boundary 当前 tick 所在间距区间在兑换方向上的边界价格，区间内流动性不变
+func (p *Pool) boundary(tick int, zeroForOne bool) (*big.Int, error) {
+	lower := tick - ((tick%p.TickSpacing)+p.TickSpacing)%p.TickSpacing
+	if zeroForOne {
+		if lower <= MinTick {
+			return new(big.Int).Add(MinSqrtRatio, big.NewInt(1)), nil
+		}
+		return SqrtRatioAtTick(lower)
+	}
+	if upper := lower + p.TickSpacing; upper < MaxTick {
+		return SqrtRatioAtTick(upper)
+	}
+	return new(big.Int).Sub(MaxSqrtRatio, big.NewInt(1)), nil
+}
+
+// spot 每个付出的代币值多少换到的代币
+func (p *Pool) spot(sqrtPriceX96 *big.Int, zeroForOne bool) *big.Float {
+	price := SqrtPriceToPrice(sqrtPriceX96, p.Token0.Decimals, p.Token1.Decimals)
+	if zeroForOne || price.Sign() == 0 {
+		return price
+	}
+	return new(big.Float).SetPrec(256).Quo(big.NewFloat(1), price)
+}
+
+// Quote 沿一条路径逐跳兑换的结果
+type Quote struct {
+	Hops      []*Hop
+	TokenIn   Token
+	TokenOut  Token
+	AmountIn  *big.Int
+	AmountOut *big.Int
+}
+
+// QuotePath 沿 path 依次兑换，每一跳的输出作为下一跳的输入
+func QuotePath(path []Source, tokenIn common.Address, amountIn *big.Int) (*Quote, error) {
+	if len(path) == 0 {
+		return nil, ErrNoRoute
+	}
+	q := &Quote{AmountIn: new(big.Int).Set(amountIn)}
+	token, amount := tokenIn, amountIn
+	for _, s := range path {
+		h, err := s.QuoteIn(token, amount)
+		if err != nil {
+			return nil, err
+		}
+		q.Hops = append(q.Hops, h)
+		token, amount = h.TokenOut.Address, h.AmountOut
+	}
+	q.TokenIn, q.TokenOut, q.AmountOut = q.Hops[0].TokenIn, q.Hops[len(q.Hops)-1].TokenOut, amount
+	return q, nil
+}
+
+// Price 整条路径的成交价：每个 TokenIn 换到多少 TokenOut
+func (q *Quote) Price() *big.Float {
+	return Ratio(q.AmountOut, q.TokenOut.Decimals, q.AmountIn, q.TokenIn.Decimals)
+}
+
+// Spot 兑换前各跳中间价的乘积，即不计手续费和滑点时的价格
+func (q *Quote) Spot() *big.Float {
+	spot := new(big.Float).SetPrec(256).SetInt64(1)
+	for _, h := range q.Hops {
+		if h.SpotBefore == nil {
+			return nil
+		}
+		spot.Mul(spot, h.SpotBefore)
+	}
+	return spot
+}
+
+// Impact 成交价相对中间价少了多少比例，含手续费
+func (q *Quote) Impact() float64 {
+	spot, price := q.Spot(), q.Price()
+	if spot == nil || price == nil || spot.Sign() == 0 {
+		return 0
+	}
+	s, _ := spot.Float64()
+	p, _ := price.Float64()
+	return 1 - p/s
+}
+
+func (q *Quote) String() string {
+	route := make([]string, 0, len(q.Hops)+1)
+	route = append(route, q.TokenIn.Symbol)
+	for _, h := range q.Hops {
+		route = append(route, fmt.Sprintf("[%s %.2f%%] %s", h.Market, h.Fee*100, h.TokenOut.Symbol))
+	}
+	return fmt.Sprintf("%s %s → %s %s @ %s（中间价 %s，%.3f%%）%s",
+		FormatAmount(q.AmountIn, q.TokenIn.Decimals), q.TokenIn.Symbol,
+		FormatAmount(q.AmountOut, q.TokenOut.Decimals), q.TokenOut.Symbol,
+		FormatPrice(q.Price()), FormatPrice(q.Spot()), q.Impact()*100, strings.Join(route, " → "))
+}
+
+// Quoter 在一组交易对和池子之间搜索路径
+type Quoter struct {
+	sources []Source
+	// MaxHops 最多经过几个交易对，默认 3
+	MaxHops int
+}
+
+func NewQuoter(sources ...Source) *Quoter {
+	return &Quoter{sources: sources, MaxHops: 3}
+}
+
+// Quoter 基于监听中的全部交易对和池子，状态随事件实时更新
+func (m *Monitor) Quoter() *Quoter {
+	sources := make([]Source, 0, len(m.pairs)+len(m.pools))
+	for _, p := range m.Pairs() {
+		sources = append(sources, p)
+	}
+	for _, p := range m.Pools() {
+		sources = append(sources, p)
+	}
+	return NewQuoter(sources...)
+}
+
+// Token 按地址或符号（不区分大小写）查找已加载的代币
+func (q *Quoter) Token(s string) (Token, bool) {
+	for _, src := range q.sources {
+		m := src.Info()
+		for _, t := range []Token{m.Token0, m.Token1} {
+			if common.IsHexAddress(s) && t.Address == common.HexToAddress(s) || strings.EqualFold(t.Symbol, s) {
+				return t, true
+			}
+		}
+	}
+	return Token{}, false
+}
+
+// Paths tokenIn 到 tokenOut 不超过 MaxHops 跳、不重复经过代币的全部路径
+func (q *Quoter) Paths(tokenIn, tokenOut common.Address) [][]Source {
+	maxHops := q.MaxHops
+	if maxHops <= 0 {
+		maxHops = 3
+	}
+	var paths [][]Source
+	visited := map[common.Address]bool{tokenIn: true}
+	var path []Source
+	var walk func(token common.Address)
+	walk = func(token common.Address) {
+		for _, s := range q.sources {
+			_, out, ok := s.Info().other(token)
+			if !ok || visited[out.Address] {
+				continue
+			}
+			path = append(path, s)
+			if out.Address == tokenOut {
+				paths = append(paths, append([]Source(nil), path...))
+			} else if len(path) < maxHops {
+				visited[out.Address] = true
+				walk(out.Address)
+				visited[out.Address] = false
+			}
+			path = path[:len(path)-1]
+		}
+	}
+	walk(tokenIn)
+	return paths
+}
+
+// Routes 全部可用路径的报价，按换到的数量从多到少排序；没有流动性或超出 V3 当前区间的路径跳过
+func (q *Quoter) Routes(tokenIn, tokenOut common.Address, amountIn *big.Int) []*Quote {
+	var quotes []*Quote
+	for _, path := range q.Paths(tokenIn, tokenOut) {
+		if quote, err := QuotePath(path, tokenIn, amountIn); err == nil {
+			quotes = append(quotes, quote)
+		}
+	}
+	sort.SliceStable(quotes, func(i, j int) bool {
+		return quotes[i].AmountOut.Cmp(quotes[j].AmountOut) > 0
+	})
+	return quotes
+}
+
+// Best 换到最多 tokenOut 的路径
+func (q *Quoter) Best(tokenIn, tokenOut common.Address, amountIn *big.Int) (*Quote, error) {
+	quotes := q.Routes(tokenIn, tokenOut, amountIn)
+	if len(quotes) == 0 {
+		return nil, fmt.Errorf("%s → %s: %w", tokenIn.Hex(), tokenOut.Hex(), ErrNoRoute)
+	}
+	return quotes[0], nil
+}
+
+// ParseAmount 把小数形式的数量按精度换算成最小单位，多余的小数位截断
+func ParseAmount(s string, decimals uint8) (*big.Int, error) {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok || r.Sign() < 0 {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+	r.Mul(r, new(big.Rat).SetInt(pow10(decimals)))
+	return new(big.Int).Quo(r.Num(), r.Denom()), nil
+}
@@ -0,0 +1,84 @@
+package dex
+
+import (
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	tokenA = Token{Address: common.HexToAddress("0x01"), Symbol: "A", Decimals: 18}
+	tokenB = Token{Address: common.HexToAddress("0x02"), Symbol: "B", Decimals: 18}
+)
+
+// testPool tick 0、价格 1、间距 60 的池子，[0, 60) 区间内流动性 1e18
+func testPool(t *testing.T) *Pool {
+	t.Helper()
+	sqrtPrice, err := SqrtRatioAtTick(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &Pool{Market: Market{DEX: "uniswap", Address: common.HexToAddress("0x10"), Token0: tokenA, Token1: tokenB}, Fee: 3000, TickSpacing: 60}
+	p.SetState(sqrtPrice, big.NewInt(1e18), 0, 1, 0)
+	return p
+}
+
+func testPair(fee uint64) *Pair {
+	p := &Pair{Market: Market{DEX: "uniswap", Address: common.HexToAddress("0x20"), Token0: tokenA, Token1: tokenB}, Fee: fee}
+	p.SetReserves(big.NewInt(1e18), big.NewInt(1e18), 1, 0)
+	return p
+}
+
+func TestPoolQuoteInRange(t *testing.T) {
+	p := testPool(t)
+	// 价格走到 tick 60 约需付出 3e15 个 B
+	h, err := p.QuoteIn(tokenB.Address, big.NewInt(1e15))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.AmountOut.Sign() <= 0 || h.AmountOut.Cmp(big.NewInt(1e15)) >= 0 {
+		t.Errorf("amountOut = %s, want (0, 1e15)", h.AmountOut)
+	}
+
+	if _, err := p.QuoteIn(tokenB.Address, big.NewInt(1e16)); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("amount beyond tick 60: err = %v, want ErrOutOfRange", err)
+	}
+	// 价格正好在区间下边界，向下兑换要越过 tick 0，区间外流动性未知
+	if _, err := p.QuoteIn(tokenA.Address, big.NewInt(1)); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("zeroForOne at range boundary: err = %v, want ErrOutOfRange", err)
+	}
+}
+
+func TestBestSkipsOutOfRange(t *testing.T) {
+	q := NewQuoter(testPool(t), testPair(30))
+	small, err := q.Best(tokenB.Address, tokenA.Address, big.NewInt(1e15))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if small.Hops[0].Market.Address != common.HexToAddress("0x10") {
+		t.Errorf("small amount: best via %s, want the V3 pool", small.Hops[0].Market)
+	}
+	large := q.Routes(tokenB.Address, tokenA.Address, big.NewInt(1e16))
+	if len(large) != 1 || large[0].Hops[0].Market.Address != common.HexToAddress("0x20") {
+		t.Errorf("large amount: got %d routes, want only the V2 pair", len(large))
+	}
+}
+
+func TestPairFee(t *testing.T) {
+	if _, err := testPair(10000).QuoteIn(tokenA.Address, big.NewInt(1e15)); err == nil {
+		t.Error("fee 10000: want error")
+	}
+
+	path := filepath.Join(t.TempDir(), "dex.yaml")
+	config := "pairs:\n  - {dex: uniswap, address: \"0xB4e16d0168e52d35CaCD2c6185b44281Ec28C9Dc\", fee: 10000}\n"
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("Load fee 10000: want error")
+	}
+}
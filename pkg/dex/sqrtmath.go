@@ -0,0 +1,190 @@
+package dex
+
+import (
+	"errors"
+	"math/big"
+)
+
+// 以下对应 Uniswap V3 的 FullMath、SqrtPriceMath、SwapMath，取整方式与合约一致，
+// 合约中 uint256 溢出时的备用算法也照搬，保证结果逐位相同
+
+var ErrInsufficientLiquidity = errors.New("insufficient liquidity")
+
+var (
+	maxUint160 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 160), big.NewInt(1))
+	pipsDenom  = big.NewInt(1_000_000)
+)
+
+// MulDiv floor(a * b / denominator)
+func MulDiv(a, b, denominator *big.Int) *big.Int {
+	n := new(big.Int).Mul(a, b)
+	return n.Quo(n, denominator)
+}
+
+// MulDivRoundingUp ceil(a * b / denominator)
+func MulDivRoundingUp(a, b, denominator *big.Int) *big.Int {
+	return divRoundingUp(new(big.Int).Mul(a, b), denominator)
+}
+
+func divRoundingUp(x, y *big.Int) *big.Int {
+	q, r := new(big.Int).QuoRem(x, y, new(big.Int))
+	if r.Sign() != 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	return q
+}
+
+// nextSqrtPriceFromAmount0 加入（add）或取出 token0 后的价格，向上取整
+func nextSqrtPriceFromAmount0(sqrtPriceX96, liquidity, amount *big.Int, add bool) (*big.Int, error) {
+	if amount.Sign() == 0 {
+		return new(big.Int).Set(sqrtPriceX96), nil
+	}
+	numerator1 := new(big.Int).Lsh(liquidity, 96)
+	product := new(big.Int).Mul(amount, sqrtPriceX96)
+	if add {
+		denominator := new(big.Int).Add(numerator1, product)
+		if product.Cmp(maxUint256) <= 0 && denominator.Cmp(maxUint256) <= 0 {
+			return MulDivRoundingUp(numerator1, sqrtPriceX96, denominator), nil
+		}
+		// 合约中乘积溢出时的算法：L / (L / sqrtP + amount)
+		denominator = new(big.Int).Quo(numerator1, sqrtPriceX96)
+		return divRoundingUp(numerator1, denominator.Add(denominator, amount)), nil
+	}
+	if product.Cmp(maxUint256) > 0 || numerator1.Cmp(product) <= 0 {
+		return nil, ErrInsufficientLiquidity
+	}
+	return MulDivRoundingUp(numerator1, sqrtPriceX96, new(big.Int).Sub(numerator1, product)), nil
+}
+
+// nextSqrtPriceFromAmount1 加入（add）或取出 token1 后的价格，向下取整
+func nextSqrtPriceFromAmount1(sqrtPriceX96, liquidity, amount *big.Int, add bool) (*big.Int, error) {
+	if add {
+		return new(big.Int).Add(sqrtPriceX96, MulDiv(amount, Q96, liquidity)), nil
+	}
+	quotient := MulDivRoundingUp(amount, Q96, liquidity)
+	if sqrtPriceX96.Cmp(quotient) <= 0 {
+		return nil, ErrInsufficientLiquidity
+	}
+	return quotient.Sub(sqrtPriceX96, quotient), nil
+}
+
+// NextSqrtPriceFromInput 付出 amountIn（zeroForOne 时为 token0）后的价格，价格向不利于交易者的方向取整
+func NextSqrtPriceFromInput(sqrtPriceX96, liquidity, amountIn *big.Int, zeroForOne bool) (*big.Int, error) {
+	if sqrtPriceX96.Sign() <= 0 || liquidity.Sign() <= 0 {
+		return nil, ErrInsufficientLiquidity
+	}
+	if zeroForOne {
+		return nextSqrtPriceFromAmount0(sqrtPriceX96, liquidity, amountIn, true)
+	}
+	return nextSqrtPriceFromAmount1(sqrtPriceX96, liquidity, amountIn, true)
+}
+
+// NextSqrtPriceFromOutput 取出 amountOut（zeroForOne 时为 token1）后的价格
+func NextSqrtPriceFromOutput(sqrtPriceX96, liquidity, amountOut *big.Int, zeroForOne bool) (*big.Int, error) {
+	if sqrtPriceX96.Sign() <= 0 || liquidity.Sign() <= 0 {
+		return nil, ErrInsufficientLiquidity
+	}
+	if zeroForOne {
+		return nextSqrtPriceFromAmount1(sqrtPriceX96, liquidity, amountOut, false)
+	}
+	return nextSqrtPriceFromAmount0(sqrtPriceX96, liquidity, amountOut, false)
+}
+
+// Amount0Delta 价格在 sqrtA、sqrtB 之间移动所需的 token0：L * (sqrtB - sqrtA) / (sqrtA * sqrtB)
+func Amount0Delta(sqrtA, sqrtB, liquidity *big.Int, roundUp bool) *big.Int {
+	if sqrtA.Cmp(sqrtB) > 0 {
+		sqrtA, sqrtB = sqrtB, sqrtA
+	}
+	numerator1 := new(big.Int).Lsh(liquidity, 96)
+	numerator2 := new(big.Int).Sub(sqrtB, sqrtA)
+	if roundUp {
+		return divRoundingUp(MulDivRoundingUp(numerator1, numerator2, sqrtB), sqrtA)
+	}
+	n := MulDiv(numerator1, numerator2, sqrtB)
+	return n.Quo(n, sqrtA)
+}
+
+// Amount1Delta 价格在 sqrtA、sqrtB 之间移动所需的 token1：L * (sqrtB - sqrtA)
+func Amount1Delta(sqrtA, sqrtB, liquidity *big.Int, roundUp bool) *big.Int {
+	if sqrtA.Cmp(sqrtB) > 0 {
+		sqrtA, sqrtB = sqrtB, sqrtA
+	}
+	diff := new(big.Int).Sub(sqrtB, sqrtA)
+	if roundUp {
+		return MulDivRoundingUp(liquidity, diff, Q96)
+	}
+	return MulDiv(liquidity, diff, Q96)
+}
+
+// SwapStep 一个流动性区间内的兑换结果
+type SwapStep struct {
+	SqrtPriceNextX96 *big.Int
+	AmountIn         *big.Int // 不含手续费
+	AmountOut        *big.Int
+	FeeAmount        *big.Int
+}
+
+// ComputeSwapStep 在流动性不变的前提下从 sqrtCurrent 向 sqrtTarget 兑换，与 SwapMath.computeSwapStep 一致。
+// amountRemaining 为正时是精确输入（含手续费），为负时是精确输出；fee 单位为百万分之一
+func ComputeSwapStep(sqrtCurrent, sqrtTarget, liquidity, amountRemaining *big.Int, fee uint64) (*SwapStep, error) {
+	zeroForOne := sqrtCurrent.Cmp(sqrtTarget) >= 0
+	exactIn := amountRemaining.Sign() >= 0
+	feePips := new(big.Int).SetUint64(fee)
+	step := &SwapStep{}
+
+	var amountIn, amountOut *big.Int
+	var err error
+	if exactIn {
+		lessFee := MulDiv(amountRemaining, new(big.Int).Sub(pipsDenom, feePips), pipsDenom)
+		if zeroForOne {
+			amountIn = Amount0Delta(sqrtTarget, sqrtCurrent, liquidity, true)
+		} else {
+			amountIn = Amount1Delta(sqrtCurrent, sqrtTarget, liquidity, true)
+		}
+		if lessFee.Cmp(amountIn) >= 0 {
+			step.SqrtPriceNextX96 = new(big.Int).Set(sqrtTarget)
+		} else if step.SqrtPriceNextX96, err = NextSqrtPriceFromInput(sqrtCurrent, liquidity, lessFee, zeroForOne); err != nil {
+			return nil, err
+		}
+	} else {
+		remaining := new(big.Int).Neg(amountRemaining)
+		if zeroForOne {
+			amountOut = Amount1Delta(sqrtTarget, sqrtCurrent, liquidity, false)
+		} else {
+			amountOut = Amount0Delta(sqrtCurrent, sqrtTarget, liquidity, false)
+		}
+		if remaining.Cmp(amountOut) >= 0 {
+			step.SqrtPriceNextX96 = new(big.Int).Set(sqrtTarget)
+		} else if step.SqrtPriceNextX96, err = NextSqrtPriceFromOutput(sqrtCurrent, liquidity, remaining, zeroForOne); err != nil {
+			return nil, err
+		}
+	}
+
+	reached := step.SqrtPriceNextX96.Cmp(sqrtTarget) == 0
+	next := step.SqrtPriceNextX96
+	if zeroForOne {
+		if !(reached && exactIn) {
+			amountIn = Amount0Delta(next, sqrtCurrent, liquidity, true)
+		}
+		if !(reached && !exactIn) {
+			amountOut = Amount1Delta(next, sqrtCurrent, liquidity, false)
+		}
+	} else {
+		if !(reached && exactIn) {
+			amountIn = Amount1Delta(sqrtCurrent, next, liquidity, true)
+		}
+		if !(reached && !exactIn) {
+			amountOut = Amount0Delta(sqrtCurrent, next, liquidity, false)
+		}
+	}
+	if !exactIn && amountOut.Cmp(new(big.Int).Neg(amountRemaining)) > 0 {
+		amountOut = new(big.Int).Neg(amountRemaining)
+	}
+	step.AmountIn, step.AmountOut = amountIn, amountOut
+	if exactIn && !reached {
+		step.FeeAmount = new(big.Int).Sub(amountRemaining, amountIn)
+	} else {
+		step.FeeAmount = MulDivRoundingUp(amountIn, feePips, new(big.Int).Sub(pipsDenom, feePips))
+	}
+	return step, nil
+}
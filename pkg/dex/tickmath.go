@@ -0,0 +1,145 @@
+package dex
+
+import (
+	"errors"
+	"math/big"
+)
+
+// V3 的价格以 sqrt(token1/token0) * 2^96 存储（sqrtPriceX96），tick 满足 price = 1.0001^tick。
+// 以下与 Uniswap V3 TickMath 逐位一致
+
+const (
+	MinTick = -887272
+	MaxTick = 887272
+)
+
+var (
+	Q96  = new(big.Int).Lsh(big.NewInt(1), 96)
+	Q128 = new(big.Int).Lsh(big.NewInt(1), 128)
+	Q192 = new(big.Int).Lsh(big.NewInt(1), 192)
+
+	// MinSqrtRatio、MaxSqrtRatio 分别为 MinTick、MaxTick 的 sqrtPriceX96，有效价格在 [MinSqrtRatio, MaxSqrtRatio) 内
+	MinSqrtRatio = big.NewInt(4295128739)
+	MaxSqrtRatio = bigHex("fffd8963efd1fc6a506488495d951d5263988d26")
+
+	maxUint256 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+	ErrTickOutOfRange  = errors.New("tick out of range")
+	ErrPriceOutOfRange = errors.New("sqrtPriceX96 out of range")
+
+	// tickRatios[i] 为 2^128 / sqrt(1.0001)^(2^i)，TickMath.getSqrtRatioAtTick 中的常量
+	tickRatios = []*big.Int{
+		bigHex("fffcb933bd6fad37aa2d162d1a594001"),
+		bigHex("fff97272373d413259a46990580e213a"),
+		bigHex("fff2e50f5f656932ef12357cf3c7fdcc"),
+		bigHex("ffe5caca7e10e4e61c3624eaa0941cd0"),
+		bigHex("ffcb9843d60f6159c9db58835c926644"),
+		bigHex("ff973b41fa98c081472e6896dfb254c0"),
+		bigHex("ff2ea16466c96a3843ec78b326b52861"),
+		bigHex("fe5dee046a99a2a811c461f1969c3053"),
+		bigHex("fcbe86c7900a88aedcffc83b479aa3a4"),
+		bigHex("f987a7253ac413176f2b074cf7815e54"),
+		bigHex("f3392b0822b70005940c7a398e4b70f3"),
+		bigHex("e7159475a2c29b7443b29c7fa6e889d9"),
+		bigHex("d097f3bdfd2022b8845ad8f792aa5825"),
+		bigHex("a9f746462d870fdf8a65dc1f90e061e5"),
+		bigHex("70d869a156d2a1b890bb3df62baf32f7"),
+		bigHex("31be135f97d08fd981231505542fcfa6"),
+		bigHex("9aa508b5b7a84e1c677de54f3e99bc9"),
+		bigHex("5d6af8dedb81196699c329225ee604"),
+		bigHex("2216e584f5fa1ea926041bedfe98"),
+		bigHex("48a170391f7dc42444e8fa2"),
+	}
+)
+
+func bigHex(s string) *big.Int {
+	n, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("invalid hex constant " + s)
+	}
+	return n
+}
+
+// SqrtRatioAtTick tick 对应的 sqrtPriceX96，向上取整
+func SqrtRatioAtTick(tick int) (*big.Int, error) {
+	if tick < MinTick || tick > MaxTick {
+		return nil, ErrTickOutOfRange
+	}
+	abs := tick
+	if abs < 0 {
+		abs = -abs
+	}
+	ratio := new(big.Int).Set(Q128)
+	for i, r := range tickRatios {
+		if abs&(1<<i) == 0 {
+			continue
+		}
+		if i == 0 {
+			ratio.Set(r)
+			continue
+		}
+		ratio.Mul(ratio, r).Rsh(ratio, 128)
+	}
+	if tick > 0 {
+		ratio.Div(maxUint256, ratio)
+	}
+	// Q128.128 转 Q64.96，有余数时进一
+	rem := new(big.Int).And(ratio, big.NewInt(1<<32-1))
+	ratio.Rsh(ratio, 32)
+	if rem.Sign() != 0 {
+		ratio.Add(ratio, big.NewInt(1))
+	}
+	return ratio, nil
+}
+
+// TickAtSqrtRatio 满足 SqrtRatioAtTick(tick) <= sqrtPriceX96 的最大 tick，与 TickMath.getTickAtSqrtRatio 一致
+func TickAtSqrtRatio(sqrtPriceX96 *big.Int) (int, error) {
+	if sqrtPriceX96.Cmp(MinSqrtRatio) < 0 || sqrtPriceX96.Cmp(MaxSqrtRatio) >= 0 {
+		return 0, ErrPriceOutOfRange
+	}
+	lo, hi := MinTick, MaxTick
+	for lo < hi {
+		mid := lo + (hi-lo+1)/2
+		r, _ := SqrtRatioAtTick(mid)
+		if r.Cmp(sqrtPriceX96) <= 0 {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	return lo, nil
+}
+
+// PriceX96 sqrtPriceX96 对应的原始价格 token1/token0（最小单位之比），精确值
+func PriceX96(sqrtPriceX96 *big.Int) *big.Rat {
+	return new(big.Rat).SetFrac(new(big.Int).Mul(sqrtPriceX96, sqrtPriceX96), Q192)
+}
+
+// SqrtPriceToPrice 每个 token0 值多少 token1，按两边精度换算
+func SqrtPriceToPrice(sqrtPriceX96 *big.Int, decimals0, decimals1 uint8) *big.Float {
+	price := PriceX96(sqrtPriceX96)
+	price.Mul(price, new(big.Rat).SetFrac(pow10(decimals0), pow10(decimals1)))
+	return new(big.Float).SetPrec(256).SetRat(price)
+}
+
+// PriceToSqrtPrice 每个 token0 值多少 token1（已按精度换算的价格）转换为 sqrtPriceX96，向下取整
+func PriceToSqrtPrice(price *big.Rat, decimals0, decimals1 uint8) *big.Int {
+	raw := new(big.Rat).Mul(price, new(big.Rat).SetFrac(pow10(decimals1), pow10(decimals0)))
+	// sqrt(raw * 2^192) = sqrt(num * 2^192 / den)
+	n := new(big.Int).Mul(raw.Num(), Q192)
+	n.Quo(n, raw.Denom())
+	return n.Sqrt(n)
+}
+
+// TickToPrice tick 对应的每个 token0 值多少 token1，按精度换算
+func TickToPrice(tick int, decimals0, decimals1 uint8) (*big.Float, error) {
+	sqrt, err := SqrtRatioAtTick(tick)
+	if err != nil {
+		return nil, err
+	}
+	return SqrtPriceToPrice(sqrt, decimals0, decimals1), nil
+}
+
+func pow10(n uint8) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
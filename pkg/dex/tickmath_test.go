@@ -0,0 +1,178 @@
+package dex
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func bigInt(t *testing.T, s string) *big.Int {
+	t.Helper()
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		t.Fatalf("invalid integer %q", s)
+	}
+	return n
+}
+
+// 期望值取自 Uniswap V3 TickMath.spec 的快照
+func TestSqrtRatioAtTick(t *testing.T) {
+	tests := []struct {
+		tick int
+		want string
+	}{
+		{MinTick, "4295128739"},
+		{MaxTick, "1461446703485210103287273052203988822378723970342"},
+		{0, "79228162514264337593543950336"},
+		{50, "79426470787362580746886972461"},
+		{-50, "79030349367926598376800521322"},
+	}
+	for _, tt := range tests {
+		got, err := SqrtRatioAtTick(tt.tick)
+		if err != nil {
+			t.Fatalf("tick %d: %v", tt.tick, err)
+		}
+		if got.Cmp(bigInt(t, tt.want)) != 0 {
+			t.Errorf("tick %d: got %s, want %s", tt.tick, got, tt.want)
+		}
+	}
+	if got, _ := SqrtRatioAtTick(MinTick); got.Cmp(MinSqrtRatio) != 0 {
+		t.Errorf("MinTick: got %s, want MinSqrtRatio %s", got, MinSqrtRatio)
+	}
+	if got, _ := SqrtRatioAtTick(MaxTick); got.Cmp(MaxSqrtRatio) != 0 {
+		t.Errorf("MaxTick: got %s, want MaxSqrtRatio %s", got, MaxSqrtRatio)
+	}
+	for _, tick := range []int{MinTick - 1, MaxTick + 1} {
+		if _, err := SqrtRatioAtTick(tick); !errors.Is(err, ErrTickOutOfRange) {
+			t.Errorf("tick %d: got %v, want ErrTickOutOfRange", tick, err)
+		}
+	}
+}
+
+func TestTickAtSqrtRatio(t *testing.T) {
+	for _, tick := range []int{MinTick, MinTick + 1, -200000, -50, -1, 0, 1, 50, 200000, MaxTick - 1} {
+		ratio, err := SqrtRatioAtTick(tick)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 恰好等于 tick 的价格时得到 tick，略小时得到 tick-1
+		if got, err := TickAtSqrtRatio(ratio); err != nil || got != tick {
+			t.Errorf("TickAtSqrtRatio(SqrtRatioAtTick(%d)) = %d, %v", tick, got, err)
+		}
+		if tick == MinTick {
+			continue
+		}
+		below := new(big.Int).Sub(ratio, big.NewInt(1))
+		if got, err := TickAtSqrtRatio(below); err != nil || got != tick-1 {
+			t.Errorf("TickAtSqrtRatio(SqrtRatioAtTick(%d)-1) = %d, %v, want %d", tick, got, err, tick-1)
+		}
+	}
+	for _, ratio := range []*big.Int{new(big.Int).Sub(MinSqrtRatio, big.NewInt(1)), MaxSqrtRatio} {
+		if _, err := TickAtSqrtRatio(ratio); !errors.Is(err, ErrPriceOutOfRange) {
+			t.Errorf("ratio %s: got %v, want ErrPriceOutOfRange", ratio, err)
+		}
+	}
+}
+
+// 期望值取自 Uniswap V3 SwapMath.spec
+func TestComputeSwapStep(t *testing.T) {
+	price1 := Q96.String()                        // encodePriceSqrt(1, 1)
+	price101 := "79623317895830914510639640423"   // encodePriceSqrt(101, 100)
+	price1000 := "250541448375047931186413801569" // encodePriceSqrt(1000, 100)
+	tests := []struct {
+		name                             string
+		price, target, liquidity, amount string
+		fee                              uint64
+		amountIn, amountOut, feeAmount   string
+		next                             string // 为空时表示到达 target
+	}{
+		{
+			name:  "exact in capped at price target",
+			price: price1, target: price101, liquidity: "2000000000000000000", amount: "1000000000000000000", fee: 600,
+			amountIn: "9975124224178055", amountOut: "9925619580021728", feeAmount: "5988667735148",
+		},
+		{
+			name:  "exact out capped at price target",
+			price: price1, target: price101, liquidity: "2000000000000000000", amount: "-1000000000000000000", fee: 600,
+			amountIn: "9975124224178055", amountOut: "9925619580021728", feeAmount: "5988667735148",
+		},
+		{
+			name:  "exact in fully spent",
+			price: price1, target: price1000, liquidity: "2000000000000000000", amount: "1000000000000000000", fee: 600,
+			amountIn: "999400000000000000", amountOut: "666399946655997866", feeAmount: "600000000000000",
+			next: "118818475322642227089037862318",
+		},
+		{
+			name:  "exact out fully received",
+			price: price1, target: price1000, liquidity: "2000000000000000000", amount: "-1000000000000000000", fee: 600,
+			amountIn: "2000000000000000000", amountOut: "1000000000000000000", feeAmount: "1200720432259356",
+			next: "158456325028528675187087900672",
+		},
+		{
+			name:  "amount out capped at desired amount",
+			price: "417332158212080721273783715441582", target: "1452870262520218020823638996",
+			liquidity: "159344665391607089467575320103", amount: "-1", fee: 1,
+			amountIn: "1", amountOut: "1", feeAmount: "1",
+			next: "417332158212080721273783715441581",
+		},
+		{
+			name:  "entire input taken as fee",
+			price: "2413", target: "79887613182836312", liquidity: "1985041575832132834610021537970", amount: "10", fee: 1872,
+			amountIn: "0", amountOut: "0", feeAmount: "10",
+			next: "2413",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, err := ComputeSwapStep(bigInt(t, tt.price), bigInt(t, tt.target), bigInt(t, tt.liquidity), bigInt(t, tt.amount), tt.fee)
+			if err != nil {
+				t.Fatal(err)
+			}
+			next := tt.next
+			if next == "" {
+				next = tt.target
+			}
+			for _, c := range []struct {
+				field     string
+				got, want string
+			}{
+				{"amountIn", step.AmountIn.String(), tt.amountIn},
+				{"amountOut", step.AmountOut.String(), tt.amountOut},
+				{"feeAmount", step.FeeAmount.String(), tt.feeAmount},
+				{"sqrtPriceNext", step.SqrtPriceNextX96.String(), next},
+			} {
+				if c.got != c.want {
+					t.Errorf("%s: got %s, want %s", c.field, c.got, c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSqrtPriceToPrice(t *testing.T) {
+	// USDC(6)/WETH(18)：1 USDC = 0.0004 WETH，原始价格 0.0004 * 10^12 = 4e8，sqrtPriceX96 = 20000 * 2^96
+	sqrt := new(big.Int).Mul(big.NewInt(20000), Q96)
+	got := SqrtPriceToPrice(sqrt, 6, 18)
+	if want := big.NewFloat(0.0004); got.Text('g', 12) != want.Text('g', 12) {
+		t.Errorf("USDC/WETH: got %s, want 0.0004", got.Text('g', 20))
+	}
+	if back := PriceToSqrtPrice(big.NewRat(4, 10000), 6, 18); back.Cmp(sqrt) != 0 {
+		t.Errorf("PriceToSqrtPrice: got %s, want %s", back, sqrt)
+	}
+
+	// WETH(18)/USDC(6)：1 WETH = 2500 USDC
+	sqrt = PriceToSqrtPrice(big.NewRat(2500, 1), 18, 6)
+	f, _ := SqrtPriceToPrice(sqrt, 18, 6).Float64()
+	if f < 2500*(1-1e-12) || f > 2500*(1+1e-12) {
+		t.Errorf("WETH/USDC: got %v, want 2500", f)
+	}
+
+	// tick 0 为原始价格 1，换算精度后每个 USDC 值 1e-12 WETH
+	price, err := TickToPrice(0, 6, 18)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f, _ := price.Float64(); f != 1e-12 {
+		t.Errorf("TickToPrice(0, 6, 18): got %v, want 1e-12", f)
+	}
+}
@@ -0,0 +1,143 @@
+package dex
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/lmittmann/w3"
+)
+
+var (
+	EventSync = w3.MustNewEvent("Sync(uint112 reserve0, uint112 reserve1)")
+	EventSwap = w3.MustNewEvent("Swap(address indexed sender, uint256 amount0In, uint256 amount1In, uint256 amount0Out, uint256 amount1Out, address indexed to)")
+
+	funcToken0      = w3.MustNewFunc("token0()", "address")
+	funcToken1      = w3.MustNewFunc("token1()", "address")
+	funcGetReserves = w3.MustNewFunc("getReserves()", "uint112 reserve0, uint112 reserve1, uint32 blockTimestampLast")
+)
+
+// Direction 相对基础代币的方向
+type Direction string
+
+const (
+	Buy  Direction = "buy"  // 付出报价代币买入基础代币
+	Sell Direction = "sell" // 卖出基础代币换报价代币
+)
+
+// Swap 解码后的一笔兑换，V3 池子的兑换另外带有兑换后的价格、流动性和 tick
+type Swap struct {
+	Market    *Market
+	Sender    common.Address
+	To        common.Address
+	Direction Direction
+	TokenIn   Token
+	TokenOut  Token
+	AmountIn  *big.Int
+	AmountOut *big.Int
+	// Price 成交价：每个基础代币成交了多少报价代币
+	Price *big.Float
+	// PriceBefore、PriceAfter 兑换前后的中间价，兑换前的储备由兑换后的储备倒推
+	PriceBefore *big.Float
+	PriceAfter  *big.Float
+
+	SqrtPriceX96 *big.Int // 以下仅 V3
+	Liquidity    *big.Int
+	Tick         int
+
+	Block    uint64
+	TxHash   common.Hash
+	LogIndex uint
+}
+
+// Impact 中间价变化的比例，价格未知时为 0
+func (s *Swap) Impact() float64 {
+	if s.PriceBefore == nil || s.PriceAfter == nil || s.PriceBefore.Sign() == 0 {
+		return 0
+	}
+	before, _ := s.PriceBefore.Float64()
+	after, _ := s.PriceAfter.Float64()
+	return after/before - 1
+}
+
+func (s *Swap) String() string {
+	return fmt.Sprintf("[%s] %s %s %s → %s %s @ %s（中间价 %s → %s，%+.3f%%）block %d tx %s",
+		s.Market, s.Direction,
+		FormatAmount(s.AmountIn, s.TokenIn.Decimals), s.TokenIn.Symbol,
+		FormatAmount(s.AmountOut, s.TokenOut.Decimals), s.TokenOut.Symbol,
+		FormatPrice(s.Price), FormatPrice(s.PriceBefore), FormatPrice(s.PriceAfter), s.Impact()*100,
+		s.Block, s.TxHash.Hex())
+}
+
+func (s *Swap) MarshalJSON() ([]byte, error) {
+	m := map[string]any{
+		"dex":         s.Market.DEX,
+		"pair":        s.Market.Address,
+		"name":        s.Market.String(),
+		"sender":      s.Sender,
+		"to":          s.To,
+		"direction":   s.Direction,
+		"tokenIn":     s.TokenIn,
+		"tokenOut":    s.TokenOut,
+		"amountIn":    s.AmountIn.String(),
+		"amountOut":   s.AmountOut.String(),
+		"price":       FormatPrice(s.Price),
+		"priceBefore": FormatPrice(s.PriceBefore),
+		"priceAfter":  FormatPrice(s.PriceAfter),
+		"block":       s.Block,
+		"tx":          s.TxHash,
+		"logIndex":    s.LogIndex,
+	}
+	if s.SqrtPriceX96 != nil {
+		m["sqrtPriceX96"], m["liquidity"], m["tick"] = s.SqrtPriceX96.String(), s.Liquidity.String(), s.Tick
+	}
+	return json.Marshal(m)
+}
+
+// DecodeSync 解码 Sync 事件的储备量
+func DecodeSync(l *types.Log) (reserve0, reserve1 *big.Int, err error) {
+	reserve0, reserve1 = new(big.Int), new(big.Int)
+	if err := EventSync.DecodeArgs(l, reserve0, reserve1); err != nil {
+		return nil, nil, err
+	}
+	return reserve0, reserve1, nil
+}
+
+// DecodeSwap 解码 Swap 事件。同一交易内 Sync 在 Swap 之前发出，p 的储备量应已是兑换后的值。
+// 闪电兑换两边同时有进出时按净额计算方向
+func (p *Pair) DecodeSwap(l *types.Log) (*Swap, error) {
+	in0, in1, out0, out1 := new(big.Int), new(big.Int), new(big.Int), new(big.Int)
+	s := &Swap{Market: &p.Market, Block: l.BlockNumber, TxHash: l.TxHash, LogIndex: l.Index}
+	if err := EventSwap.DecodeArgs(l, &s.Sender, in0, in1, out0, out1, &s.To); err != nil {
+		return nil, err
+	}
+	r0, r1, _ := p.Reserves()
+	s.fill(new(big.Int).Sub(in0, out0), new(big.Int).Sub(in1, out1))
+	if afterBase, afterQuote := p.orient(r0, r1); afterBase.Sign() > 0 {
+		baseNet, quoteNet := p.orient(new(big.Int).Sub(in0, out0), new(big.Int).Sub(in1, out1))
+		base, quote := p.BaseToken(), p.QuoteToken()
+		s.PriceAfter = Ratio(afterQuote, quote.Decimals, afterBase, base.Decimals)
+		beforeBase, beforeQuote := new(big.Int).Sub(afterBase, baseNet), new(big.Int).Sub(afterQuote, quoteNet)
+		if beforeBase.Sign() > 0 && beforeQuote.Sign() >= 0 {
+			s.PriceBefore = Ratio(beforeQuote, quote.Decimals, beforeBase, base.Decimals)
+		}
+	}
+	return s, nil
+}
+
+// fill 由流入池子的净额（token0、token1 顺序，负数为流出）确定方向、成交数量和成交价
+func (s *Swap) fill(net0, net1 *big.Int) {
+	baseNet, quoteNet := s.Market.orient(net0, net1)
+	base, quote := s.Market.BaseToken(), s.Market.QuoteToken()
+	if baseNet.Sign() > 0 {
+		s.Direction, s.TokenIn, s.TokenOut = Sell, base, quote
+		s.AmountIn, s.AmountOut = baseNet, new(big.Int).Neg(quoteNet)
+		s.Price = Ratio(s.AmountOut, quote.Decimals, s.AmountIn, base.Decimals)
+	} else {
+		s.Direction, s.TokenIn, s.TokenOut = Buy, quote, base
+		s.AmountIn, s.AmountOut = quoteNet, new(big.Int).Neg(baseNet)
+		s.Price = Ratio(s.AmountIn, quote.Decimals, s.AmountOut, base.Decimals)
+	}
+}
@@ -0,0 +1,142 @@
+package dex
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/lmittmann/w3"
+)
+
+var (
+	EventSwapV3 = w3.MustNewEvent("Swap(address indexed sender, address indexed recipient, int256 amount0, int256 amount1, uint160 sqrtPriceX96, uint128 liquidity, int24 tick)")
+	EventMint   = w3.MustNewEvent("Mint(address sender, address indexed owner, int24 indexed tickLower, int24 indexed tickUpper, uint128 amount, uint256 amount0, uint256 amount1)")
+	EventBurn   = w3.MustNewEvent("Burn(address indexed owner, int24 indexed tickLower, int24 indexed tickUpper, uint128 amount, uint256 amount0, uint256 amount1)")
+
+	// slot0 只解码前两个返回值，PancakeSwap V3 等分叉后面的字段类型不同
+	funcSlot0       = w3.MustNewFunc("slot0()", "uint160 sqrtPriceX96, int24 tick")
+	funcLiquidity   = w3.MustNewFunc("liquidity()", "uint128")
+	funcFee         = w3.MustNewFunc("fee()", "uint24")
+	funcTickSpacing = w3.MustNewFunc("tickSpacing()", "int24")
+)
+
+// Pool V3 池子，当前价格、tick 和区间内流动性由 Swap、Mint、Burn 事件维护
+type Pool struct {
+	Market
+	Fee         uint64 // 百万分之几，加载时从链上读取，500 即 0.05%
+	TickSpacing int
+
+	mu           sync.RWMutex
+	sqrtPriceX96 *big.Int
+	liquidity    *big.Int
+	tick         int
+	block        uint64 // 最后一次更新状态的区块和日志序号，旧日志不会覆盖新状态
+	index        uint
+}
+
+// State 当前 sqrtPriceX96、区间内流动性、tick 和更新时的区块
+func (p *Pool) State() (sqrtPriceX96, liquidity *big.Int, tick int, block uint64) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.sqrtPriceX96 == nil {
+		return new(big.Int), new(big.Int), 0, p.block
+	}
+	return new(big.Int).Set(p.sqrtPriceX96), new(big.Int).Set(p.liquidity), p.tick, p.block
+}
+
+// SetState 更新状态，(block, index) 不比当前新时忽略并返回 false
+func (p *Pool) SetState(sqrtPriceX96, liquidity *big.Int, tick int, block uint64, index uint) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.newer(block, index) {
+		return false
+	}
+	p.sqrtPriceX96, p.liquidity, p.tick = new(big.Int).Set(sqrtPriceX96), new(big.Int).Set(liquidity), tick
+	p.block, p.index = block, index
+	return true
+}
+
+func (p *Pool) newer(block uint64, index uint) bool {
+	return p.sqrtPriceX96 == nil || block > p.block || block == p.block && index > p.index
+}
+
+// Price 中间价：每个基础代币值多少报价代币，已按精度换算；状态为空时为 nil
+func (p *Pool) Price() *big.Float {
+	sqrt, _, _, _ := p.State()
+	return p.price(sqrt)
+}
+
+func (p *Pool) price(sqrtPriceX96 *big.Int) *big.Float {
+	if sqrtPriceX96.Sign() == 0 {
+		return nil
+	}
+	price := SqrtPriceToPrice(sqrtPriceX96, p.Token0.Decimals, p.Token1.Decimals)
+	if p.Base == 1 {
+		return new(big.Float).SetPrec(256).Quo(big.NewFloat(1), price)
+	}
+	return price
+}
+
+// DecodeSwap 解码 V3 Swap 事件并更新池子状态。amount0、amount1 为池子的净流入（负数为流出），
+// PriceBefore 为更新前的状态，日志比当前状态旧时为 nil
+func (p *Pool) DecodeSwap(l *types.Log) (*Swap, error) {
+	amount0, amount1, tick := new(big.Int), new(big.Int), new(big.Int)
+	s := &Swap{
+		Market: &p.Market, SqrtPriceX96: new(big.Int), Liquidity: new(big.Int),
+		Block: l.BlockNumber, TxHash: l.TxHash, LogIndex: l.Index,
+	}
+	if err := EventSwapV3.DecodeArgs(l, &s.Sender, &s.To, amount0, amount1, s.SqrtPriceX96, s.Liquidity, tick); err != nil {
+		return nil, err
+	}
+	s.Tick = int(tick.Int64())
+	s.fill(amount0, amount1)
+	s.PriceAfter = p.price(s.SqrtPriceX96)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.newer(l.BlockNumber, l.Index) {
+		if p.sqrtPriceX96 != nil {
+			s.PriceBefore = p.price(p.sqrtPriceX96)
+		}
+		p.sqrtPriceX96, p.liquidity, p.tick = new(big.Int).Set(s.SqrtPriceX96), new(big.Int).Set(s.Liquidity), s.Tick
+		p.block, p.index = l.BlockNumber, l.Index
+	}
+	return s, nil
+}
+
+// ApplyLiquidity 按 Mint（增加）、Burn（减少）事件调整流动性，只有包含当前 tick 的区间才影响区间内流动性。
+// 状态有变化时返回 true
+func (p *Pool) ApplyLiquidity(l *types.Log) (bool, error) {
+	if len(l.Topics) == 0 {
+		return false, fmt.Errorf("no topics")
+	}
+	var sender, owner common.Address
+	lower, upper, amount, amount0, amount1 := new(big.Int), new(big.Int), new(big.Int), new(big.Int), new(big.Int)
+	switch l.Topics[0] {
+	case EventMint.Topic0:
+		if err := EventMint.DecodeArgs(l, &sender, &owner, lower, upper, amount, amount0, amount1); err != nil {
+			return false, err
+		}
+	case EventBurn.Topic0:
+		if err := EventBurn.DecodeArgs(l, &owner, lower, upper, amount, amount0, amount1); err != nil {
+			return false, err
+		}
+		amount.Neg(amount)
+	default:
+		return false, fmt.Errorf("unexpected event %s", l.Topics[0].Hex())
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.sqrtPriceX96 == nil || !p.newer(l.BlockNumber, l.Index) {
+		return false, nil
+	}
+	p.block, p.index = l.BlockNumber, l.Index
+	if amount.Sign() == 0 || int64(p.tick) < lower.Int64() || int64(p.tick) >= upper.Int64() {
+		return false, nil
+	}
+	p.liquidity = new(big.Int).Add(p.liquidity, amount)
+	return true, nil
+}
@@ -0,0 +1,397 @@
+package endpoint
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ErrNoEndpoint 所有节点都请求失败
+var ErrNoEndpoint = errors.New("endpoint: all endpoints failed")
+
+// window 计算错误率的最近请求数
+const window = 50
+
+// Pool 同一条链的多个 HTTP RPC 节点，按健康状况排序：读请求发给最好的节点，
+// 连接失败、5xx、429 时透明地换下一个。Pool 本身是 http.RoundTripper，
+// 基于它的 rpc.Client、ethclient、w3 客户端都自动获得故障转移
+type Pool struct {
+	endpoints []*endpoint
+
+	ChainID       uint64        // 不为 0 时链 ID 不一致的节点视为不健康
+	MaxLag        uint64        // 落后最高区块超过 MaxLag 视为不健康
+	MaxErrorRate  float64       // 最近 window 次请求错误率超过它视为不健康
+	CheckInterval time.Duration // 健康检查间隔
+	Timeout       time.Duration // 单个节点的请求超时
+
+	transport http.RoundTripper
+}
+
+// endpoint 单个节点的状态
+type endpoint struct {
+	url string
+
+	mu        sync.Mutex
+	healthy   bool
+	chainID   uint64
+	head      uint64
+	lag       uint64
+	latency   time.Duration // 指数加权平均
+	requests  uint64
+	errors    uint64
+	outcomes  [window]bool // 环形记录最近请求是否失败
+	next      int
+	filled    int
+	lastError string
+	checkedAt time.Time
+}
+
+// Status 节点状态快照
+type Status struct {
+	URL       string    `json:"url"`
+	Healthy   bool      `json:"healthy"`
+	ChainID   uint64    `json:"chainId"`
+	Head      uint64    `json:"head"`
+	Lag       uint64    `json:"lag"`
+	LatencyMs int64     `json:"latencyMs"`
+	Requests  uint64    `json:"requests"`
+	Errors    uint64    `json:"errors"`
+	ErrorRate float64   `json:"errorRate"`
+	LastError string    `json:"lastError,omitempty"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+func New(urls []string) (*Pool, error) {
+	if len(urls) == 0 {
+		return nil, errors.New("endpoint: no urls")
+	}
+	p := &Pool{
+		MaxLag:        3,
+		MaxErrorRate:  0.5,
+		CheckInterval: 15 * time.Second,
+		Timeout:       10 * time.Second,
+		transport:     http.DefaultTransport,
+	}
+	for _, u := range urls {
+		if !strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://") {
+			return nil, fmt.Errorf("endpoint: %s is not http(s)", Redact(u))
+		}
+		// 检查前都视为健康，按配置顺序使用
+		p.endpoints = append(p.endpoints, &endpoint{url: u, healthy: true})
+	}
+	return p, nil
+}
+
+// Redact 去掉 URL 路径和参数，很多服务商的 key 在路径里
+func Redact(u string) string {
+	if i := strings.Index(u, "://"); i >= 0 {
+		if j := strings.IndexAny(u[i+3:], "/?"); j >= 0 {
+			return u[:i+3+j]
+		}
+	}
+	return u
+}
+
+// RPC 基于节点池的 rpc.Client
+func (p *Pool) RPC() *rpc.Client {
+	c, err := rpc.DialOptions(context.Background(), "http://endpoint-pool", rpc.WithHTTPClient(&http.Client{Transport: p}))
+	if err != nil {
+		// http 地址不会建立连接，只有 URL 非法才会失败
+		panic(err)
+	}
+	return c
+}
+
+// Client 基于节点池的 ethclient
+func (p *Pool) Client() *ethclient.Client {
+	return ethclient.NewClient(p.RPC())
+}
+
+// Run 周期性健康检查，直到 ctx 取消
+func (p *Pool) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.CheckInterval)
+	defer ticker.Stop()
+	for {
+		p.Check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check 并发检查所有节点的链 ID、区块高度和延迟，再按最高区块计算落后数
+func (p *Pool) Check(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, e := range p.endpoints {
+		wg.Add(1)
+		go func(e *endpoint) {
+			defer wg.Done()
+			p.check(ctx, e)
+		}(e)
+	}
+	wg.Wait()
+
+	var best uint64
+	for _, e := range p.endpoints {
+		e.mu.Lock()
+		best = max(best, e.head)
+		e.mu.Unlock()
+	}
+	for _, e := range p.endpoints {
+		e.mu.Lock()
+		was := e.healthy
+		e.lag = best - min(best, e.head)
+		e.healthy = e.lastError == "" && e.lag <= p.MaxLag && e.errorRate() <= p.MaxErrorRate &&
+			(p.ChainID == 0 || e.chainID == p.ChainID)
+		if was != e.healthy {
+			if e.healthy {
+				log.Printf("✔️ 节点恢复 %s head %d", Redact(e.url), e.head)
+			} else {
+				log.Printf("⚠️ 节点不健康 %s head %d lag %d 错误率 %.2f %s", Redact(e.url), e.head, e.lag, e.errorRate(), e.lastError)
+			}
+		}
+		e.mu.Unlock()
+	}
+}
+
+// check 一次批量请求 eth_chainId 和 eth_blockNumber
+func (p *Pool) check(ctx context.Context, e *endpoint) {
+	body := []byte(`[{"jsonrpc":"2.0","id":1,"method":"eth_chainId","params":[]},{"jsonrpc":"2.0","id":2,"method":"eth_blockNumber","params":[]}]`)
+	ctx, cancel := context.WithTimeout(ctx, p.Timeout)
+	defer cancel()
+	start := time.Now()
+	resp, err := p.send(ctx, e, body, nil)
+	var results []struct {
+		ID     int             `json:"id"`
+		Result hexutil.Uint64  `json:"result"`
+		Error  json.RawMessage `json:"error"`
+	}
+	if err == nil {
+		err = json.NewDecoder(resp.Body).Decode(&results)
+		resp.Body.Close()
+	}
+	if err == nil && len(results) != 2 {
+		err = fmt.Errorf("unexpected batch response")
+	}
+	for _, r := range results {
+		if err == nil && r.Error != nil {
+			err = fmt.Errorf("rpc error: %s", r.Error)
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.checkedAt = time.Now()
+	if err != nil {
+		e.lastError = err.Error()
+		return
+	}
+	e.lastError = ""
+	e.observe(time.Since(start))
+	for _, r := range results {
+		if r.ID == 1 {
+			e.chainID = uint64(r.Result)
+		} else {
+			e.head = uint64(r.Result)
+		}
+	}
+}
+
+// RoundTrip 按排名依次尝试，连接失败、5xx、429 时换下一个节点；JSON-RPC 层的错误（如 revert）直接返回
+func (p *Pool) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+	}
+	var errs []error
+	for _, e := range p.ranked() {
+		start := time.Now()
+		resp, err := p.send(req.Context(), e, body, req.Header)
+		if err == nil {
+			e.record(nil, time.Since(start))
+			return resp, nil
+		}
+		e.record(err, 0)
+		errs = append(errs, fmt.Errorf("%s: %w", Redact(e.url), err))
+		if req.Context().Err() != nil {
+			break
+		}
+	}
+	return nil, fmt.Errorf("%w: %w", ErrNoEndpoint, errors.Join(errs...))
+}
+
+// send 把请求发到节点，需要换节点的响应转成错误
+func (p *Pool) send(ctx context.Context, e *endpoint, body []byte, header http.Header) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.Timeout)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := p.transport.RoundTrip(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+		resp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("http %d", resp.StatusCode)
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelBody 读完响应后再释放超时 context
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// ranked 健康节点按延迟排序在前，不健康的按落后数和错误率排在后面兜底
+func (p *Pool) ranked() []*endpoint {
+	type item struct {
+		e       *endpoint
+		healthy bool
+		lag     uint64
+		latency time.Duration
+		rate    float64
+	}
+	items := make([]item, len(p.endpoints))
+	for i, e := range p.endpoints {
+		e.mu.Lock()
+		items[i] = item{e, e.healthy, e.lag, e.latency, e.errorRate()}
+		e.mu.Unlock()
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if a.healthy != b.healthy {
+			return a.healthy
+		}
+		if !a.healthy && a.lag != b.lag {
+			return a.lag < b.lag
+		}
+		if a.rate != b.rate && (a.rate > 0.1 || b.rate > 0.1) {
+			return a.rate < b.rate
+		}
+		return a.latency < b.latency
+	})
+	ranked := make([]*endpoint, len(items))
+	for i, it := range items {
+		ranked[i] = it.e
+	}
+	return ranked
+}
+
+// Status 按当前排名返回节点状态，第一个就是读请求会用的节点
+func (p *Pool) Status() []Status {
+	ranked := p.ranked()
+	statuses := make([]Status, len(ranked))
+	for i, e := range ranked {
+		e.mu.Lock()
+		statuses[i] = Status{
+			URL:       Redact(e.url),
+			Healthy:   e.healthy,
+			ChainID:   e.chainID,
+			Head:      e.head,
+			Lag:       e.lag,
+			LatencyMs: e.latency.Milliseconds(),
+			Requests:  e.requests,
+			Errors:    e.errors,
+			ErrorRate: e.errorRate(),
+			LastError: e.lastError,
+			CheckedAt: e.checkedAt,
+		}
+		e.mu.Unlock()
+	}
+	return statuses
+}
+
+// ServeHTTP 以 JSON 输出节点状态，便于挂在调试端口上查看
+func (p *Pool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p.Status())
+}
+
+// String 一行一个节点的状态表
+func (p *Pool) String() string {
+	var b strings.Builder
+	for i, s := range p.Status() {
+		state := "✔️"
+		if !s.Healthy {
+			state = "⚠️"
+		}
+		fmt.Fprintf(&b, "%d %s %s head %d lag %d %dms 请求 %d 错误 %d(%.2f)", i, state, s.URL, s.Head, s.Lag,
+			s.LatencyMs, s.Requests, s.Errors, s.ErrorRate)
+		if s.LastError != "" {
+			fmt.Fprintf(&b, " %s", s.LastError)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// record 记录一次转发结果，连续失败会在下一次排序时降级
+func (e *endpoint) record(err error, latency time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.requests++
+	e.outcomes[e.next] = err != nil
+	e.next = (e.next + 1) % window
+	e.filled = min(e.filled+1, window)
+	if err != nil {
+		e.errors++
+		e.lastError = err.Error()
+		return
+	}
+	e.observe(latency)
+}
+
+func (e *endpoint) observe(latency time.Duration) {
+	if e.latency == 0 {
+		e.latency = latency
+	} else {
+		e.latency = (e.latency*4 + latency) / 5
+	}
+}
+
+func (e *endpoint) errorRate() float64 {
+	if e.filled == 0 {
+		return 0
+	}
+	failed := 0
+	for i := 0; i < e.filled; i++ {
+		if e.outcomes[i] {
+			failed++
+		}
+	}
+	return float64(failed) / float64(e.filled)
+}
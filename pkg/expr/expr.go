@@ -0,0 +1,143 @@
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// ErrUnknown 表达式引用了环境中不存在的字段，按事件/方法分别编译时用来跳过不适用的事件
+var ErrUnknown = errors.New("unknown identifier")
+
+// Error 带列号的编译错误
+type Error struct {
+	Pos int
+	Msg string
+	Err error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("expr: column %d: %s", e.Pos, e.Msg)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Env 编译环境：可引用的字段及其类型，和 $name 引用的常量
+type Env struct {
+	fields      map[string]Type
+	unsupported map[string]string // 类型不支持的字段 → ABI 类型，引用时报错
+	consts      map[string]constValue
+}
+
+type constValue struct {
+	typ   Type
+	value any
+}
+
+func NewEnv() *Env {
+	return &Env{fields: make(map[string]Type), unsupported: make(map[string]string), consts: make(map[string]constValue)}
+}
+
+// Clone 复制环境，用于在公共字段上分别声明各事件的参数
+func (e *Env) Clone() *Env {
+	return &Env{fields: maps.Clone(e.fields), unsupported: maps.Clone(e.unsupported), consts: maps.Clone(e.consts)}
+}
+
+// Field 声明字段
+func (e *Env) Field(name string, t Type) {
+	e.fields[name] = t
+	delete(e.unsupported, name)
+}
+
+// Args 按 ABI 参数声明字段，没有名字的参数叫 arg0、arg1…；indexed 的动态类型只有 hash，按 bytes 处理
+func (e *Env) Args(args abi.Arguments) {
+	for i, arg := range args {
+		name := arg.Name
+		if name == "" {
+			name = fmt.Sprintf("arg%d", i)
+		}
+		if arg.Indexed && dynamicType(arg.Type) {
+			e.Field(name, Bytes)
+			continue
+		}
+		if t, ok := TypeOf(arg.Type); ok {
+			e.Field(name, t)
+		} else {
+			delete(e.fields, name)
+			e.unsupported[name] = arg.Type.String()
+		}
+	}
+}
+
+// Const 声明常量，值可以是数字、字符串、地址、布尔或它们的列表
+func (e *Env) Const(name string, v any) error {
+	value, t, err := constant(v)
+	if err != nil {
+		return fmt.Errorf("const %s: %w", name, err)
+	}
+	e.consts[name] = constValue{typ: t, value: value}
+	return nil
+}
+
+// Consts 批量声明常量
+func (e *Env) Consts(m map[string]any) error {
+	for name, v := range m {
+		if err := e.Const(name, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dynamicType(t abi.Type) bool {
+	switch t.T {
+	case abi.StringTy, abi.BytesTy, abi.SliceTy, abi.ArrayTy, abi.TupleTy:
+		return true
+	}
+	return false
+}
+
+// Program 编译后的表达式，结果一定是 bool
+type Program struct {
+	Source string
+	Fields []string // 引用到的字段
+	eval   evalFunc
+}
+
+// Compile 解析表达式并按环境做类型检查，字段不存在、类型不匹配、结果不是 bool 都在这里报错
+//
+//	value >= 200000 * 10**decimals && to in $exchanges
+//	method == "presale" && tx.value > 1 ether
+func Compile(src string, env *Env) (*Program, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, env: env, fields: make(map[string]bool)}
+	n, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	if n.typ != Bool {
+		return nil, &Error{Pos: 1, Msg: fmt.Sprintf("expression is %s, not bool", n.typ)}
+	}
+	prog := &Program{Source: src, eval: n.eval}
+	for name := range p.fields {
+		prog.Fields = append(prog.Fields, name)
+	}
+	return prog, nil
+}
+
+// Eval 用字段值求值，vars 的值为 abi 解出的原始类型
+func (p *Program) Eval(vars map[string]any) (bool, error) {
+	v, err := p.eval(vars)
+	if err != nil {
+		return false, fmt.Errorf("expr %q: %w", p.Source, err)
+	}
+	return v.(bool), nil
+}
+
+func (p *Program) String() string { return strings.TrimSpace(p.Source) }
@@ -0,0 +1,229 @@
+package expr
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	exchange = common.HexToAddress("0x28C6c06298d514Db089934071355E5743bf21d60")
+	other    = common.HexToAddress("0x1111111111111111111111111111111111111111")
+)
+
+func testEnv(t *testing.T) *Env {
+	t.Helper()
+	env := TxEnv(NewEnv())
+	for name, typ := range map[string]Type{"value": Int, "decimals": Int, "to": Address, "data": Bytes, "zero": Int, "flag": Bool} {
+		env.Field(name, typ)
+	}
+	if err := env.Consts(map[string]any{"exchanges": []any{exchange.Hex()}, "limit": float64(100)}); err != nil {
+		t.Fatal(err)
+	}
+	return env
+}
+
+func testVars() map[string]any {
+	return map[string]any{
+		"value":    new(big.Int).Mul(big.NewInt(300000), big.NewInt(1e6)),
+		"decimals": uint8(6),
+		"to":       exchange,
+		"data":     []byte{0x12, 0x34},
+		"zero":     big.NewInt(0),
+		"flag":     true,
+		"method":   "presale",
+		"tx.value": new(big.Int).Mul(big.NewInt(2), big.NewInt(1e18)),
+	}
+}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		// README 中的例子
+		{"value >= 200000 * 10**decimals && to in $exchanges", true},
+		{"value >= 400000 * 10**decimals && to in $exchanges", false},
+		{`method == "presale" && tx.value > 1 ether`, true},
+		{`method == 'presale' && tx.value > 3 ether`, false},
+
+		// 优先级：** 比一元负号优先且右结合，&& 比 || 优先
+		{"-2**2 == -4", true},
+		{"(-2)**2 == 4", true},
+		{"2**3**2 == 512", true},
+		{"1 + 2 * 3 == 7", true},
+		{"7 % 4 - 1 == 2", true},
+		{"true || false && false", true},
+		{"!flag || value > 0", true},
+		// 短路：右边不会求值，除零不会报错
+		{"false && value / zero > 0", false},
+		{"flag || value / zero > 0", true},
+
+		// 单位和小数
+		{"tx.value == 2 ether", true},
+		{"1.5 ether == 1500000000000000000", true},
+		{"0.5 gwei == 500000000 wei", true},
+		{"1_000 wei == 0x3e8", true},
+		{"limit == 100 && $limit < 101", true},
+
+		// 字符串常量按另一边转换为地址或字节
+		{`to == "0x28c6c06298d514db089934071355e5743bf21d60"`, true},
+		{`"0x1111111111111111111111111111111111111111" == to`, false},
+		{`to != 0x1111111111111111111111111111111111111111`, true},
+		{`data == "0x1234"`, true},
+		{`to in ["0x1111111111111111111111111111111111111111", "0x28C6c06298d514Db089934071355E5743bf21d60"]`, true},
+		{`to in ["0x1111111111111111111111111111111111111111"]`, false},
+		{`data in ["0x12", "0x1234"]`, true},
+		{`value in [1, 2, 3]`, false},
+		{"to in []", false},
+	}
+	env := testEnv(t)
+	for _, tt := range tests {
+		p, err := Compile(tt.src, env)
+		if err != nil {
+			t.Errorf("%s: compile: %v", tt.src, err)
+			continue
+		}
+		got, err := p.Eval(testVars())
+		if err != nil {
+			t.Errorf("%s: eval: %v", tt.src, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestCompileError(t *testing.T) {
+	env := testEnv(t)
+	nested, err := abi.NewType("uint256[][]", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.Args(abi.Arguments{{Name: "matrix", Type: nested}})
+
+	tests := []struct {
+		src     string
+		msg     string
+		unknown bool
+	}{
+		{"missing > 1", "unknown field missing", true},
+		{"$missing > 1", "unknown constant $missing", true},
+		{"matrix == 1", "unsupported type uint256[][]", false},
+		{"value + 1", "not bool", false},
+		{`method`, "not bool", false},
+		{"value > true", "can not compare", false},
+		{"to < 1", "can not compare", false},
+		{`to == "not an address"`, "can not compare", false},
+		{"value && flag", "needs bool operands", false},
+		{"value in to", "must be a list", false},
+		{"1.5 == 1", "fractional number", false},
+		{"0.1 wei == 0", "fractional number", false},
+		{"1 / 0 == 0", "division by zero", false},
+		{"2 ** 257 > 0", "out of range", false},
+		{"value > ", "unexpected end", false},
+		{`method == "presale`, "unterminated string", false},
+	}
+	for _, tt := range tests {
+		_, err := Compile(tt.src, env)
+		if err == nil {
+			t.Errorf("%s: want error containing %q", tt.src, tt.msg)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.msg) {
+			t.Errorf("%s: error %q, want %q", tt.src, err, tt.msg)
+		}
+		if errors.Is(err, ErrUnknown) != tt.unknown {
+			t.Errorf("%s: errors.Is(ErrUnknown) = %v, want %v", tt.src, !tt.unknown, tt.unknown)
+		}
+	}
+}
+
+func TestEvalError(t *testing.T) {
+	env := testEnv(t)
+	tests := []struct {
+		src  string
+		vars map[string]any
+		msg  string
+	}{
+		{"value / zero > 0", nil, "division by zero"},
+		{"value % zero > 0", nil, "division by zero"},
+		{"2 ** value > 0", map[string]any{"value": big.NewInt(257)}, "out of range"},
+		{"2 ** value > 0", map[string]any{"value": big.NewInt(-1)}, "out of range"},
+		{"value > 0", map[string]any{"value": nil}, "unsupported value"},
+	}
+	for _, tt := range tests {
+		p, err := Compile(tt.src, env)
+		if err != nil {
+			t.Fatalf("%s: compile: %v", tt.src, err)
+		}
+		vars := testVars()
+		for name, v := range tt.vars {
+			vars[name] = v
+		}
+		if _, err := p.Eval(vars); err == nil || !strings.Contains(err.Error(), tt.msg) {
+			t.Errorf("%s: err = %v, want %q", tt.src, err, tt.msg)
+		}
+	}
+	if _, err := Compile("2 ** 256 > 0", env); err != nil {
+		t.Errorf("2 ** 256: %v", err)
+	}
+}
+
+func TestCompileRule(t *testing.T) {
+	const erc20 = `[
+		{"type":"event","name":"Transfer","inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"value","type":"uint256"}]},
+		{"type":"event","name":"Approval","inputs":[{"name":"owner","type":"address","indexed":true},{"name":"spender","type":"address","indexed":true},{"name":"value","type":"uint256"}]}
+	]`
+	contractAbi, err := abi.JSON(strings.NewReader(erc20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []*abi.Event
+	for _, e := range contractAbi.Events {
+		events = append(events, &e)
+	}
+	envs := EventEnvs(NewEnv(), events)
+
+	// Approval 没有 to，只对 Transfer 编译
+	r, err := CompileRule("to == 0x28C6c06298d514Db089934071355E5743bf21d60 && value > 1", envs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Applies("Transfer") || r.Applies("Approval") {
+		t.Errorf("applies: Transfer %v, Approval %v", r.Applies("Transfer"), r.Applies("Approval"))
+	}
+	vars := map[string]any{"from": other, "to": exchange, "value": big.NewInt(2), "event": "Transfer", "log.address": other, "log.block": uint64(1), "log.index": uint(0)}
+	if ok, err := r.Match("Transfer", vars); err != nil || !ok {
+		t.Errorf("Transfer: match = %v, %v", ok, err)
+	}
+	if ok, err := r.Match("Approval", vars); err != nil || ok {
+		t.Errorf("Approval: match = %v, %v, want false", ok, err)
+	}
+
+	// 两个事件都有的字段对两个事件都编译
+	if r, err = CompileRule(`value > 1 && event != "Mint"`, envs); err != nil {
+		t.Fatal(err)
+	}
+	if !r.Applies("Transfer") || !r.Applies("Approval") {
+		t.Error("value: want both events")
+	}
+
+	// 所有事件都缺字段或出现类型错误时编译失败
+	if _, err := CompileRule("recipient == 0x28C6c06298d514Db089934071355E5743bf21d60", envs); !errors.Is(err, ErrUnknown) {
+		t.Errorf("no event has recipient: err = %v, want ErrUnknown", err)
+	}
+	if _, err := CompileRule("value == true", envs); err == nil || errors.Is(err, ErrUnknown) {
+		t.Errorf("type error: err = %v", err)
+	}
+
+	var nilRule *Rule
+	if ok, err := nilRule.Match("Transfer", nil); err != nil || !ok {
+		t.Errorf("nil rule: match = %v, %v, want true", ok, err)
+	}
+}
@@ -0,0 +1,103 @@
+package expr
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokString
+	tokIdent
+	tokConst // $name
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int // 从 1 开始的列号
+}
+
+// ops 按长度从长到短匹配
+var ops = []string{"**", "&&", "||", "==", "!=", "<=", ">=", "<", ">", "+", "-", "*", "/", "%", "!", "(", ")", "[", "]", ","}
+
+// lex 把表达式切分为 token，标识符可以包含点（tx.value）
+func lex(src string) ([]token, error) {
+	var tokens []token
+	runes := []rune(src)
+	for i := 0; i < len(runes); {
+		c := runes[i]
+		start := i
+		switch {
+		case unicode.IsSpace(c):
+			i++
+			continue
+		case c >= '0' && c <= '9':
+			for i < len(runes) && (isIdentRune(runes[i]) || runes[i] == '.') {
+				i++
+			}
+		case c == '"' || c == '\'':
+			i++
+			for i < len(runes) && runes[i] != c {
+				if runes[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			if i >= len(runes) {
+				return nil, &Error{Pos: start + 1, Msg: "unterminated string"}
+			}
+			i++
+		case c == '$' || isIdentStart(c):
+			i++
+			for i < len(runes) && (isIdentRune(runes[i]) || runes[i] == '.') {
+				i++
+			}
+		default:
+			rest := string(runes[i:])
+			op := ""
+			for _, o := range ops {
+				if strings.HasPrefix(rest, o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, &Error{Pos: i + 1, Msg: fmt.Sprintf("unexpected %q", c)}
+			}
+			tokens = append(tokens, token{kind: tokOp, text: op, pos: i + 1})
+			i += len([]rune(op))
+			continue
+		}
+		text := string(runes[start:i])
+		t := token{text: text, pos: start + 1}
+		switch {
+		case c >= '0' && c <= '9':
+			t.kind = tokNumber
+		case c == '"' || c == '\'':
+			t.kind = tokString
+		case c == '$':
+			t.kind, t.text = tokConst, text[1:]
+			if t.text == "" {
+				return nil, &Error{Pos: t.pos, Msg: "missing name after $"}
+			}
+		default:
+			t.kind = tokIdent
+		}
+		tokens = append(tokens, t)
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(runes) + 1}), nil
+}
+
+func isIdentStart(c rune) bool {
+	return c == '_' || unicode.IsLetter(c)
+}
+
+func isIdentRune(c rune) bool {
+	return c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
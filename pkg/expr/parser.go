@@ -0,0 +1,518 @@
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type evalFunc func(vars map[string]any) (any, error)
+
+// node 类型检查后的语法树节点，直接编译成求值闭包；子节点都是常量时在编译期折叠
+type node struct {
+	typ   Type
+	pos   int
+	konst bool
+	value any
+	eval  evalFunc
+}
+
+func constNode(pos int, t Type, v any) *node {
+	return &node{typ: t, pos: pos, konst: true, value: v, eval: func(map[string]any) (any, error) { return v, nil }}
+}
+
+// units 数字后缀
+var units = map[string]int{"wei": 0, "gwei": 9, "ether": 18}
+
+var comparisons = map[string]bool{"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true}
+
+// maxExp ** 的最大指数
+const maxExp = 256
+
+// parser 递归下降，优先级从低到高：||、&&、比较和 in、+ -、* / %、一元 ! -、**
+type parser struct {
+	tokens []token
+	i      int
+	env    *Env
+	fields map[string]bool
+}
+
+func (p *parser) peek() token { return p.tokens[p.i] }
+
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) isOp(text string) bool {
+	t := p.peek()
+	return t.kind == tokOp && t.text == text
+}
+
+func (p *parser) isWord(text string) bool {
+	t := p.peek()
+	return t.kind == tokIdent && t.text == text
+}
+
+func (p *parser) expect(text string) error {
+	if !p.isOp(text) {
+		return p.unexpected()
+	}
+	p.next()
+	return nil
+}
+
+func (p *parser) unexpected() error {
+	t := p.peek()
+	if t.kind == tokEOF {
+		return &Error{Pos: t.pos, Msg: "unexpected end of expression"}
+	}
+	return &Error{Pos: t.pos, Msg: fmt.Sprintf("unexpected %q", t.text)}
+}
+
+func (p *parser) parse() (*node, error) {
+	n, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokEOF {
+		return nil, p.unexpected()
+	}
+	return n, nil
+}
+
+func (p *parser) or() (*node, error) {
+	left, err := p.and()
+	for err == nil && p.isOp("||") {
+		op := p.next()
+		var right *node
+		if right, err = p.and(); err == nil {
+			left, err = p.logical(op, left, right)
+		}
+	}
+	return left, err
+}
+
+func (p *parser) and() (*node, error) {
+	left, err := p.comparison()
+	for err == nil && p.isOp("&&") {
+		op := p.next()
+		var right *node
+		if right, err = p.comparison(); err == nil {
+			left, err = p.logical(op, left, right)
+		}
+	}
+	return left, err
+}
+
+// logical 短路求值
+func (p *parser) logical(op token, left, right *node) (*node, error) {
+	if left.typ != Bool || right.typ != Bool {
+		return nil, &Error{Pos: op.pos, Msg: fmt.Sprintf("%s needs bool operands, got %s and %s", op.text, left.typ, right.typ)}
+	}
+	or := op.text == "||"
+	return fold(&node{typ: Bool, pos: op.pos, eval: func(vars map[string]any) (any, error) {
+		l, err := left.eval(vars)
+		if err != nil || l.(bool) == or {
+			return l, err
+		}
+		return right.eval(vars)
+	}}, left, right), nil
+}
+
+func (p *parser) comparison() (*node, error) {
+	left, err := p.additive()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); !(t.kind == tokOp && comparisons[t.text]) && !p.isWord("in") {
+		return left, nil
+	}
+	op := p.next()
+	right, err := p.additive()
+	if err != nil {
+		return nil, err
+	}
+	if op.text == "in" {
+		return p.in(op, left, right)
+	}
+	return p.compare(op, left, right)
+}
+
+func (p *parser) compare(op token, left, right *node) (*node, error) {
+	var ok bool
+	if left, right, ok = unify(left, right); !ok {
+		return nil, &Error{Pos: op.pos, Msg: fmt.Sprintf("can not compare %s with %s", left.typ, right.typ)}
+	}
+	if op.text != "==" && op.text != "!=" && left.typ != Int {
+		return nil, &Error{Pos: op.pos, Msg: fmt.Sprintf("%s needs int operands, got %s", op.text, left.typ)}
+	}
+	return fold(&node{typ: Bool, pos: op.pos, eval: func(vars map[string]any) (any, error) {
+		l, err := left.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		r, err := right.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		switch op.text {
+		case "==":
+			return equal(l, r), nil
+		case "!=":
+			return !equal(l, r), nil
+		}
+		c := l.(*big.Int).Cmp(r.(*big.Int))
+		switch op.text {
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		}
+		return c >= 0, nil
+	}}, left, right), nil
+}
+
+func (p *parser) in(op token, left, right *node) (*node, error) {
+	if !right.typ.IsList() {
+		return nil, &Error{Pos: op.pos, Msg: fmt.Sprintf("right side of in must be a list, got %s", right.typ)}
+	}
+	if right.typ.Elem() != left.typ {
+		if right.konst {
+			v, ok := coerceConst(right.value, right.typ, List|left.typ)
+			if !ok {
+				return nil, &Error{Pos: op.pos, Msg: fmt.Sprintf("can not find %s in %s", left.typ, right.typ)}
+			}
+			right = constNode(right.pos, List|left.typ, v)
+		} else if left.konst {
+			v, ok := coerceConst(left.value, left.typ, right.typ.Elem())
+			if !ok {
+				return nil, &Error{Pos: op.pos, Msg: fmt.Sprintf("can not find %s in %s", left.typ, right.typ)}
+			}
+			left = constNode(left.pos, right.typ.Elem(), v)
+		} else {
+			return nil, &Error{Pos: op.pos, Msg: fmt.Sprintf("can not find %s in %s", left.typ, right.typ)}
+		}
+	}
+	// 常量列表预先建好集合
+	var set map[string]bool
+	if right.konst {
+		set = make(map[string]bool)
+		for _, item := range right.value.([]any) {
+			set[key(item)] = true
+		}
+	}
+	return fold(&node{typ: Bool, pos: op.pos, eval: func(vars map[string]any) (any, error) {
+		l, err := left.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		if set != nil {
+			return set[key(l)], nil
+		}
+		r, err := right.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range r.([]any) {
+			if equal(l, item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}}, left, right), nil
+}
+
+// key 标量值的集合键
+func key(v any) string {
+	switch x := v.(type) {
+	case *big.Int:
+		return "i" + x.String()
+	case []byte:
+		return "b" + string(x)
+	case common.Address:
+		return "a" + string(x[:])
+	case string:
+		return "s" + x
+	}
+	return fmt.Sprint(v)
+}
+
+// unify 两边类型不同时尝试把字符串常量转换为另一边的地址或字节
+func unify(left, right *node) (*node, *node, bool) {
+	if left.typ == right.typ {
+		return left, right, true
+	}
+	if right.konst {
+		if v, ok := coerceConst(right.value, right.typ, left.typ); ok {
+			return left, constNode(right.pos, left.typ, v), true
+		}
+	}
+	if left.konst {
+		if v, ok := coerceConst(left.value, left.typ, right.typ); ok {
+			return constNode(left.pos, right.typ, v), right, true
+		}
+	}
+	return left, right, false
+}
+
+func (p *parser) additive() (*node, error) {
+	left, err := p.multiplicative()
+	for err == nil && (p.isOp("+") || p.isOp("-")) {
+		op := p.next()
+		var right *node
+		if right, err = p.multiplicative(); err == nil {
+			left, err = arith(op, left, right)
+		}
+	}
+	return left, err
+}
+
+func (p *parser) multiplicative() (*node, error) {
+	left, err := p.unary()
+	for err == nil && (p.isOp("*") || p.isOp("/") || p.isOp("%")) {
+		op := p.next()
+		var right *node
+		if right, err = p.unary(); err == nil {
+			left, err = arith(op, left, right)
+		}
+	}
+	return left, err
+}
+
+func (p *parser) unary() (*node, error) {
+	if !p.isOp("!") && !p.isOp("-") {
+		return p.power()
+	}
+	op := p.next()
+	operand, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	if op.text == "!" {
+		if operand.typ != Bool {
+			return nil, &Error{Pos: op.pos, Msg: fmt.Sprintf("! needs bool, got %s", operand.typ)}
+		}
+		return fold(&node{typ: Bool, pos: op.pos, eval: func(vars map[string]any) (any, error) {
+			v, err := operand.eval(vars)
+			if err != nil {
+				return nil, err
+			}
+			return !v.(bool), nil
+		}}, operand), nil
+	}
+	if operand.typ != Int {
+		return nil, &Error{Pos: op.pos, Msg: fmt.Sprintf("- needs int, got %s", operand.typ)}
+	}
+	return fold(&node{typ: Int, pos: op.pos, eval: func(vars map[string]any) (any, error) {
+		v, err := operand.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Int).Neg(v.(*big.Int)), nil
+	}}, operand), nil
+}
+
+// power ** 右结合，比一元负号优先：-2**2 == -4
+func (p *parser) power() (*node, error) {
+	base, err := p.primary()
+	if err != nil || !p.isOp("**") {
+		return base, err
+	}
+	op := p.next()
+	exp, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	return arith(op, base, exp)
+}
+
+func arith(op token, left, right *node) (*node, error) {
+	if left.typ != Int || right.typ != Int {
+		return nil, &Error{Pos: op.pos, Msg: fmt.Sprintf("%s needs int operands, got %s and %s", op.text, left.typ, right.typ)}
+	}
+	n := &node{typ: Int, pos: op.pos, eval: func(vars map[string]any) (any, error) {
+		l, err := left.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		r, err := right.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		x, y := l.(*big.Int), r.(*big.Int)
+		switch op.text {
+		case "+":
+			return new(big.Int).Add(x, y), nil
+		case "-":
+			return new(big.Int).Sub(x, y), nil
+		case "*":
+			return new(big.Int).Mul(x, y), nil
+		case "**":
+			if y.Sign() < 0 || y.Cmp(big.NewInt(maxExp)) > 0 {
+				return nil, fmt.Errorf("exponent %s out of range [0, %d]", y, maxExp)
+			}
+			return new(big.Int).Exp(x, y, nil), nil
+		}
+		if y.Sign() == 0 {
+			return nil, errors.New("division by zero")
+		}
+		if op.text == "/" {
+			return new(big.Int).Quo(x, y), nil
+		}
+		return new(big.Int).Rem(x, y), nil
+	}}
+	if left.konst && right.konst {
+		v, err := n.eval(nil)
+		if err != nil {
+			return nil, &Error{Pos: op.pos, Msg: err.Error()}
+		}
+		return constNode(op.pos, Int, v), nil
+	}
+	return n, nil
+}
+
+// fold 子节点全是常量时在编译期求值
+func fold(n *node, children ...*node) *node {
+	for _, c := range children {
+		if !c.konst {
+			return n
+		}
+	}
+	v, err := n.eval(nil)
+	if err != nil {
+		return n
+	}
+	return constNode(n.pos, n.typ, v)
+}
+
+func (p *parser) primary() (*node, error) {
+	if t := p.peek(); t.kind == tokEOF || t.kind == tokOp && t.text != "(" && t.text != "[" {
+		return nil, p.unexpected()
+	}
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		return p.number(t)
+	case tokString:
+		s, err := unquote(t.text)
+		if err != nil {
+			return nil, &Error{Pos: t.pos, Msg: err.Error()}
+		}
+		return constNode(t.pos, String, s), nil
+	case tokConst:
+		c, ok := p.env.consts[t.text]
+		if !ok {
+			return nil, &Error{Pos: t.pos, Msg: fmt.Sprintf("unknown constant $%s", t.text), Err: ErrUnknown}
+		}
+		return constNode(t.pos, c.typ, c.value), nil
+	case tokOp:
+		if t.text == "(" {
+			n, err := p.or()
+			if err != nil {
+				return nil, err
+			}
+			return n, p.expect(")")
+		}
+		return p.list(t)
+	}
+	return p.ident(t)
+}
+
+// ident 依次查找关键字、字段和常量
+func (p *parser) ident(t token) (*node, error) {
+	switch t.text {
+	case "true", "false":
+		return constNode(t.pos, Bool, t.text == "true"), nil
+	}
+	if typ, ok := p.env.fields[t.text]; ok {
+		p.fields[t.text] = true
+		name := t.text
+		return &node{typ: typ, pos: t.pos, eval: func(vars map[string]any) (any, error) {
+			v, ok := vars[name]
+			if !ok {
+				return nil, fmt.Errorf("field %s missing", name)
+			}
+			return normalize(v)
+		}}, nil
+	}
+	if abiType, ok := p.env.unsupported[t.text]; ok {
+		return nil, &Error{Pos: t.pos, Msg: fmt.Sprintf("field %s has unsupported type %s", t.text, abiType)}
+	}
+	if c, ok := p.env.consts[t.text]; ok {
+		return constNode(t.pos, c.typ, c.value), nil
+	}
+	return nil, &Error{Pos: t.pos, Msg: fmt.Sprintf("unknown field %s", t.text), Err: ErrUnknown}
+}
+
+// list 列表字面量，元素必须是同一类型的常量
+func (p *parser) list(open token) (*node, error) {
+	var items []any
+	elem := Invalid
+	for !p.isOp("]") {
+		if len(items) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		n, err := p.additive()
+		if err != nil {
+			return nil, err
+		}
+		if !n.konst || n.typ.IsList() {
+			return nil, &Error{Pos: n.pos, Msg: "list items must be constants"}
+		}
+		if elem != Invalid && n.typ != elem {
+			return nil, &Error{Pos: n.pos, Msg: fmt.Sprintf("%s in list of %s", n.typ, elem)}
+		}
+		items, elem = append(items, n.value), n.typ
+	}
+	p.next()
+	return constNode(open.pos, List|elem, items), nil
+}
+
+// number 十进制或 0x 十六进制整数，0x 加 40 位十六进制为地址；可以带 wei、gwei、ether 单位，带单位时允许小数
+func (p *parser) number(t token) (*node, error) {
+	text := strings.ReplaceAll(t.text, "_", "")
+	if strings.HasPrefix(text, "0x") || strings.HasPrefix(text, "0X") {
+		if len(text) == 42 && common.IsHexAddress(text) {
+			return constNode(t.pos, Address, common.HexToAddress(text)), nil
+		}
+		n, ok := new(big.Int).SetString(text[2:], 16)
+		if !ok {
+			return nil, &Error{Pos: t.pos, Msg: fmt.Sprintf("invalid number %s", t.text)}
+		}
+		return constNode(t.pos, Int, n), nil
+	}
+	r, ok := new(big.Rat).SetString(text)
+	if !ok {
+		return nil, &Error{Pos: t.pos, Msg: fmt.Sprintf("invalid number %s", t.text)}
+	}
+	if u := p.peek(); u.kind == tokIdent {
+		if decimals, ok := units[u.text]; ok {
+			p.next()
+			r.Mul(r, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)))
+		}
+	}
+	if !r.IsInt() {
+		return nil, &Error{Pos: t.pos, Msg: fmt.Sprintf("fractional number %s", t.text)}
+	}
+	return constNode(t.pos, Int, new(big.Int).Set(r.Num())), nil
+}
+
+// unquote 支持单引号和双引号字符串
+func unquote(s string) (string, error) {
+	if s[0] == '\'' {
+		s = `"` + strings.ReplaceAll(strings.ReplaceAll(s[1:len(s)-1], `\'`, `'`), `"`, `\"`) + `"`
+	}
+	return strconv.Unquote(s)
+}
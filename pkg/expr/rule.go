@@ -0,0 +1,194 @@
+package expr
+
+import (
+	"chainget/pkg/decode"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Rule 同一个表达式按每个事件或方法分别编译：引用了某个事件没有的字段时该事件不适用，
+// 所有事件都不适用或出现类型错误时编译失败
+type Rule struct {
+	Source   string
+	programs map[string]*Program
+}
+
+// CompileRule envs 为事件名或方法名 → 编译环境
+func CompileRule(src string, envs map[string]*Env) (*Rule, error) {
+	r := &Rule{Source: src, programs: make(map[string]*Program)}
+	names := make([]string, 0, len(envs))
+	for name := range envs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var unknown error
+	for _, name := range names {
+		p, err := Compile(src, envs[name])
+		if errors.Is(err, ErrUnknown) {
+			if unknown == nil {
+				unknown = withName(name, err)
+			}
+			continue
+		}
+		if err != nil {
+			return nil, withName(name, err)
+		}
+		r.programs[name] = p
+	}
+	if len(r.programs) == 0 {
+		if unknown == nil {
+			return nil, errors.New("expr: nothing to compile against")
+		}
+		return nil, unknown
+	}
+	return r, nil
+}
+
+// withName 错误前加上事件或方法名，空名字（未解码的交易、日志）不加
+func withName(name string, err error) error {
+	if name == "" {
+		return err
+	}
+	return fmt.Errorf("%s: %w", name, err)
+}
+
+// Applies 表达式对该事件或方法能否求值
+func (r *Rule) Applies(name string) bool {
+	if r == nil {
+		return true
+	}
+	_, ok := r.programs[name]
+	return ok
+}
+
+// Match nil 表示不过滤；不适用的事件或方法返回 false
+func (r *Rule) Match(name string, vars map[string]any) (bool, error) {
+	if r == nil {
+		return true, nil
+	}
+	p, ok := r.programs[name]
+	if !ok {
+		return false, nil
+	}
+	return p.Eval(vars)
+}
+
+func (r *Rule) String() string {
+	if r == nil {
+		return ""
+	}
+	return r.Source
+}
+
+// EventEnv 事件参数，以及 event（事件名）、log.address、log.block 和 log.index；同名参数优先
+func EventEnv(base *Env, event abi.Event) *Env {
+	env := base.Clone()
+	env.Field("event", String)
+	env.Field("log.address", Address)
+	env.Field("log.block", Int)
+	env.Field("log.index", Int)
+	env.Args(event.Inputs)
+	return env
+}
+
+// EventEnvs ABI 中每个事件一个环境
+func EventEnvs(base *Env, events []*abi.Event) map[string]*Env {
+	envs := make(map[string]*Env, len(events))
+	for _, e := range events {
+		envs[e.Name] = EventEnv(base, *e)
+	}
+	return envs
+}
+
+// RecordVars 解码后事件记录的字段值
+func RecordVars(r *decode.Record) map[string]any {
+	vars := map[string]any{
+		"event":       r.Event,
+		"log.address": r.Address,
+		"log.block":   r.BlockNumber,
+		"log.index":   r.LogIndex,
+	}
+	for _, f := range r.Fields {
+		vars[f.Name] = f.Value
+	}
+	return vars
+}
+
+// TxEnv pending 交易的字段：tx.from、tx.to、tx.value、tx.gas、tx.gasPrice、tx.gasTipCap、tx.nonce、tx.hash、tx.data，
+// 以及 method（方法名，ABI 中没有时为选择器的十六进制）
+func TxEnv(base *Env) *Env {
+	env := base.Clone()
+	for name, t := range map[string]Type{
+		"tx.from": Address, "tx.to": Address, "tx.value": Int, "tx.gas": Int, "tx.gasPrice": Int,
+		"tx.gasTipCap": Int, "tx.nonce": Int, "tx.hash": Bytes, "tx.data": Bytes, "method": String,
+	} {
+		env.Field(name, t)
+	}
+	return env
+}
+
+// MethodEnv 交易字段加方法参数，同名参数优先
+func MethodEnv(base *Env, method abi.Method) *Env {
+	env := TxEnv(base)
+	env.Args(method.Inputs)
+	return env
+}
+
+// TxVars pending 交易的字段值，from 为已恢复的发送者；method 和 args 为 DecodeCall 的结果，未解码时 method 为选择器
+func TxVars(tx *types.Transaction, from common.Address, method *abi.Method, args map[string]any) map[string]any {
+	vars := map[string]any{
+		"tx.from":      from,
+		"tx.value":     tx.Value(),
+		"tx.gas":       tx.Gas(),
+		"tx.gasPrice":  tx.GasPrice(),
+		"tx.gasTipCap": tx.GasTipCap(),
+		"tx.nonce":     tx.Nonce(),
+		"tx.hash":      tx.Hash(),
+		"tx.data":      tx.Data(),
+		"tx.to":        common.Address{},
+		"method":       "",
+	}
+	if tx.To() != nil {
+		vars["tx.to"] = *tx.To()
+	}
+	if len(tx.Data()) >= 4 {
+		vars["method"] = hexutil.Encode(tx.Data()[:4])
+	}
+	if method != nil {
+		vars["method"] = method.RawName
+	}
+	for name, v := range args {
+		vars[name] = v
+	}
+	return vars
+}
+
+// DecodeCall 按 ABI 解码交易的方法和参数，没有名字的参数按位置命名为 arg0、arg1…
+func DecodeCall(contractAbi abi.ABI, data []byte) (*abi.Method, map[string]any, error) {
+	if len(data) < 4 {
+		return nil, nil, errors.New("no selector")
+	}
+	method, err := contractAbi.MethodById(data[:4])
+	if err != nil {
+		return nil, nil, err
+	}
+	values, err := method.Inputs.Unpack(data[4:])
+	if err != nil {
+		return nil, nil, fmt.Errorf("decode %s: %w", method.Name, err)
+	}
+	args := make(map[string]any, len(values))
+	for i, in := range method.Inputs {
+		name := in.Name
+		if name == "" {
+			name = fmt.Sprintf("arg%d", i)
+		}
+		args[name] = values[i]
+	}
+	return method, args, nil
+}
@@ -0,0 +1,216 @@
+package expr
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Type 表达式的值类型，运行时分别对应 *big.Int、bool、string、common.Address、[]byte 和 []any
+type Type uint8
+
+const (
+	Invalid Type = iota // 空列表的元素类型，可以和任意类型比较
+	Int
+	Bool
+	String
+	Address
+	Bytes
+
+	List Type = 0x80 // 与元素类型按位或，如 List|Address
+)
+
+func (t Type) IsList() bool { return t&List != 0 }
+
+// Elem 列表的元素类型
+func (t Type) Elem() Type { return t &^ List }
+
+func (t Type) String() string {
+	if t.IsList() {
+		return "[]" + t.Elem().String()
+	}
+	switch t {
+	case Int:
+		return "int"
+	case Bool:
+		return "bool"
+	case String:
+		return "string"
+	case Address:
+		return "address"
+	case Bytes:
+		return "bytes"
+	}
+	return "any"
+}
+
+// TypeOf ABI 类型对应的表达式类型，元组、嵌套数组等不支持
+func TypeOf(t abi.Type) (Type, bool) {
+	switch t.T {
+	case abi.IntTy, abi.UintTy:
+		return Int, true
+	case abi.BoolTy:
+		return Bool, true
+	case abi.StringTy:
+		return String, true
+	case abi.AddressTy:
+		return Address, true
+	case abi.BytesTy, abi.FixedBytesTy, abi.HashTy:
+		return Bytes, true
+	case abi.SliceTy, abi.ArrayTy:
+		elem, ok := TypeOf(*t.Elem)
+		if !ok || elem.IsList() {
+			return Invalid, false
+		}
+		return List | elem, true
+	}
+	return Invalid, false
+}
+
+// normalize 把 abi 解出的值转换为表达式的运行时值
+func normalize(v any) (any, error) {
+	switch x := v.(type) {
+	case *big.Int:
+		return x, nil
+	case bool, string, common.Address, []byte:
+		return x, nil
+	case common.Hash:
+		return x.Bytes(), nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return big.NewInt(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return new(big.Int).SetUint64(rv.Uint()), nil
+	case reflect.Array, reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, rv.Len())
+			reflect.Copy(reflect.ValueOf(b), rv)
+			return b, nil
+		}
+		items := make([]any, rv.Len())
+		for i := range items {
+			item, err := normalize(rv.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("unsupported value %T", v)
+}
+
+// constant 把配置中的常量转换为表达式的值：十六进制地址为 address，列表元素类型必须一致
+func constant(v any) (any, Type, error) {
+	switch x := v.(type) {
+	case string:
+		if common.IsHexAddress(x) && len(x) == 42 {
+			return common.HexToAddress(x), Address, nil
+		}
+		return x, String, nil
+	case bool:
+		return x, Bool, nil
+	case float64:
+		if x != float64(int64(x)) {
+			return nil, Invalid, fmt.Errorf("fractional number %v", x)
+		}
+		return big.NewInt(int64(x)), Int, nil
+	case []any:
+		items := make([]any, len(x))
+		elem := Invalid
+		for i, item := range x {
+			value, t, err := constant(item)
+			if err != nil {
+				return nil, Invalid, fmt.Errorf("item %d: %w", i, err)
+			}
+			if t.IsList() {
+				return nil, Invalid, fmt.Errorf("item %d: nested list", i)
+			}
+			if elem != Invalid && t != elem {
+				return nil, Invalid, fmt.Errorf("item %d: %s in list of %s", i, t, elem)
+			}
+			items[i], elem = value, t
+		}
+		return items, List | elem, nil
+	}
+	value, err := normalize(v)
+	if err != nil {
+		return nil, Invalid, err
+	}
+	switch value.(type) {
+	case *big.Int:
+		return value, Int, nil
+	case common.Address:
+		return value, Address, nil
+	case []byte:
+		return value, Bytes, nil
+	}
+	return nil, Invalid, fmt.Errorf("unsupported constant %T", v)
+}
+
+// coerceConst 字符串常量按需要转换为地址或字节，常量列表逐个转换
+func coerceConst(v any, from, to Type) (any, bool) {
+	if from == to {
+		return v, true
+	}
+	if from.IsList() && to.IsList() {
+		if from.Elem() == Invalid {
+			return v, true
+		}
+		items := v.([]any)
+		out := make([]any, len(items))
+		for i, item := range items {
+			converted, ok := coerceConst(item, from.Elem(), to.Elem())
+			if !ok {
+				return nil, false
+			}
+			out[i] = converted
+		}
+		return out, true
+	}
+	s, ok := v.(string)
+	if from != String || !ok {
+		return nil, false
+	}
+	switch to {
+	case Address:
+		if common.IsHexAddress(s) {
+			return common.HexToAddress(s), true
+		}
+	case Bytes:
+		if b, err := hexutil.Decode(s); err == nil {
+			return b, true
+		}
+	}
+	return nil, false
+}
+
+func equal(a, b any) bool {
+	switch x := a.(type) {
+	case *big.Int:
+		y, ok := b.(*big.Int)
+		return ok && x.Cmp(y) == 0
+	case []byte:
+		y, ok := b.([]byte)
+		return ok && bytes.Equal(x, y)
+	case []any:
+		y, ok := b.([]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
@@ -0,0 +1,103 @@
+package factory
+
+import (
+	"chainget/pkg/helper"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"gopkg.in/yaml.v3"
+)
+
+// Factory 工厂合约：Event 事件的 ChildArg 参数是新建的子合约地址，子合约事件用 ChildABI 解码
+type Factory struct {
+	Name     string
+	Address  common.Address
+	ABI      abi.ABI
+	Event    string
+	ChildArg string
+	ChildABI abi.ABI
+	Amounts  map[string]string // 金额参数的代币来源，格式同 decode.Decoder.Amounts
+}
+
+// Config 工厂配置，ABI 为 abis 目录下的文件名
+//
+//	factories:
+//	  - name: binance-ido
+//	    address: "0xe0C7897d48847b6916094bF5cD8216449Ea8fB86"
+//	    abi: binance_ido_abi.json
+//	    event: NewIDOContract
+//	    childArg: idoAddress
+//	    childAbi: binance_ido_abi.json
+//	    amounts: {offeringAmountPool: "offeringToken()", raisingAmountPool: "lpToken()"}
+type Config struct {
+	Name     string            `yaml:"name"`
+	Address  string            `yaml:"address"`
+	ABI      string            `yaml:"abi"`
+	Event    string            `yaml:"event"`
+	ChildArg string            `yaml:"childArg"`
+	ChildABI string            `yaml:"childAbi"`
+	Amounts  map[string]string `yaml:"amounts"` // 参数名 → 代币来源，见 decode.Decoder.Amounts
+}
+
+// Load 读取 factories 配置文件
+func Load(path string) ([]*Factory, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Factories []Config `yaml:"factories"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	factories := make([]*Factory, 0, len(file.Factories))
+	for _, c := range file.Factories {
+		f, err := c.Factory()
+		if err != nil {
+			return nil, err
+		}
+		factories = append(factories, f)
+	}
+	return factories, nil
+}
+
+// Factory 解析 ABI 并校验事件和参数
+func (c Config) Factory() (*Factory, error) {
+	if !common.IsHexAddress(c.Address) {
+		return nil, fmt.Errorf("factory %s: invalid address %q", c.Name, c.Address)
+	}
+	f := &Factory{Name: c.Name, Address: common.HexToAddress(c.Address), Event: c.Event, ChildArg: c.ChildArg, Amounts: c.Amounts}
+	var err error
+	if f.ABI, err = abi.JSON(strings.NewReader(helper.ReadAbiJson(c.ABI))); err != nil {
+		return nil, fmt.Errorf("factory %s: abi: %w", c.Name, err)
+	}
+	childABI := c.ChildABI
+	if childABI == "" {
+		childABI = c.ABI
+	}
+	if f.ChildABI, err = abi.JSON(strings.NewReader(helper.ReadAbiJson(childABI))); err != nil {
+		return nil, fmt.Errorf("factory %s: childAbi: %w", c.Name, err)
+	}
+	return f, f.validate()
+}
+
+// validate 创建事件必须存在，且 ChildArg 是 address 类型参数
+func (f *Factory) validate() error {
+	event, ok := f.ABI.Events[f.Event]
+	if !ok {
+		return fmt.Errorf("factory %s: event %s not found in abi", f.Name, f.Event)
+	}
+	for _, arg := range event.Inputs {
+		if arg.Name == f.ChildArg {
+			if arg.Type.T != abi.AddressTy {
+				return fmt.Errorf("factory %s: %s.%s is %s, not address", f.Name, f.Event, f.ChildArg, arg.Type)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("factory %s: event %s has no argument %s", f.Name, f.Event, f.ChildArg)
+}
@@ -0,0 +1,129 @@
+package factory
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Child 工厂创建的子合约
+type Child struct {
+	Address common.Address `json:"address"`
+	Factory string         `json:"factory"`
+	Block   uint64         `json:"block"`
+	TxHash  common.Hash    `json:"txHash"`
+}
+
+// Store 把已知子合约和最后处理的区块保存到 JSON 文件，重启后继续监听并补齐中断期间的日志
+type Store struct {
+	path string
+
+	mu        sync.Mutex
+	children  map[common.Address]*Child
+	lastBlock uint64
+}
+
+type storeFile struct {
+	LastBlock uint64   `json:"lastBlock"`
+	Children  []*Child `json:"children"`
+}
+
+// OpenStore 打开存储文件，文件不存在时为空
+func OpenStore(path string) (*Store, error) {
+	s := &Store{path: path, children: make(map[common.Address]*Child)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f storeFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	s.lastBlock = f.LastBlock
+	for _, c := range f.Children {
+		s.children[c.Address] = c
+	}
+	return s, nil
+}
+
+// Children 返回所有子合约
+func (s *Store) Children() []*Child {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	children := make([]*Child, 0, len(s.children))
+	for _, c := range s.children {
+		children = append(children, c)
+	}
+	return children
+}
+
+// Get 按地址查找子合约
+func (s *Store) Get(addr common.Address) (*Child, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.children[addr]
+	return c, ok
+}
+
+// Add 记录新子合约并落盘，已存在时返回 false
+func (s *Store) Add(c *Child) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.children[c.Address]; ok {
+		return false, nil
+	}
+	s.children[c.Address] = c
+	return true, s.save()
+}
+
+// LastBlock 最后处理完日志的区块
+func (s *Store) LastBlock() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastBlock
+}
+
+// SetLastBlock 更新最后处理的区块并落盘，只会前进
+func (s *Store) SetLastBlock(number uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if number <= s.lastBlock {
+		return nil
+	}
+	s.lastBlock = number
+	return s.save()
+}
+
+// save 先写临时文件再改名，避免中途退出写坏文件
+func (s *Store) save() error {
+	f := storeFile{LastBlock: s.lastBlock, Children: make([]*Child, 0, len(s.children))}
+	for _, c := range s.children {
+		f.Children = append(f.Children, c)
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
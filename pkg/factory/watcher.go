@@ -0,0 +1,286 @@
+package factory
+
+import (
+	"chainget/pkg/decode"
+	"context"
+	"errors"
+	"log"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// backfillRange 补日志时单次 eth_getLogs 的区块跨度
+const backfillRange = 5000
+
+// Client 订阅和补日志需要的 RPC 方法，*ethclient.Client 和 poll.Client 都满足
+type Client interface {
+	ethereum.LogFilterer
+	ethereum.BlockNumberReader
+}
+
+// Watcher 订阅工厂和它创建的全部子合约的日志；新子合约出现时落盘并重新订阅，
+// 订阅不会回放历史，所以从子合约创建区块起用 eth_getLogs 补齐重新订阅前的日志
+type Watcher struct {
+	client    Client
+	store     *Store
+	factories map[common.Address]*Factory
+
+	mu       sync.Mutex
+	decoders map[common.Address]*decode.Decoder
+	seen     map[logKey]struct{}
+
+	// OnRecord 每条解码成功的日志回调一次
+	OnRecord func(*decode.Record)
+	// OnChild 发现新子合约时回调
+	OnChild func(*Factory, *Child)
+	// BlockTime 查询区块时间，为空时记录不带时间
+	BlockTime func(number uint64) time.Time
+	// NewDecoder 为工厂和子合约创建解码器，可以在这里配置精度和时间字段
+	NewDecoder func(*Factory, bool) *decode.Decoder
+}
+
+type logKey struct {
+	block   common.Hash
+	index   uint
+	removed bool
+}
+
+// span 需要补日志的地址和起始区块，addrs 为空表示全部地址
+type span struct {
+	addrs []common.Address
+	from  uint64
+}
+
+func NewWatcher(client Client, store *Store, factories []*Factory) *Watcher {
+	w := &Watcher{
+		client:    client,
+		store:     store,
+		factories: make(map[common.Address]*Factory),
+		decoders:  make(map[common.Address]*decode.Decoder),
+		seen:      make(map[logKey]struct{}),
+	}
+	for _, f := range factories {
+		w.factories[f.Address] = f
+	}
+	return w
+}
+
+// Run 阻塞监听直到 ctx 取消或订阅出错
+func (w *Watcher) Run(ctx context.Context) error {
+	var pending []span
+	if last := w.store.LastBlock(); last > 0 {
+		pending = append(pending, span{from: last + 1})
+	}
+	for {
+		addrs := w.addresses()
+		logs := make(chan types.Log, 256)
+		sub, err := w.client.SubscribeFilterLogs(ctx, ethereum.FilterQuery{Addresses: addrs}, logs)
+		if err != nil {
+			return err
+		}
+		log.Printf("✔️ 监听 %d 个工厂、%d 个子合约", len(w.factories), len(addrs)-len(w.factories))
+
+		// 先订阅再补日志，补日志期间新到的日志留在 channel 里，重复的按 seen 去重
+		resubscribe := false
+		for len(pending) > 0 && !resubscribe {
+			s := pending[0]
+			pending = pending[1:]
+			children, err := w.backfill(ctx, s)
+			if err != nil {
+				sub.Unsubscribe()
+				return err
+			}
+			for _, c := range children {
+				pending = append(pending, span{addrs: []common.Address{c.Address}, from: c.Block})
+			}
+			resubscribe = len(children) > 0
+		}
+
+		for !resubscribe {
+			select {
+			case <-ctx.Done():
+				sub.Unsubscribe()
+				return ctx.Err()
+			case err := <-sub.Err():
+				return err
+			case l := <-logs:
+				if c := w.handle(l, true); c != nil {
+					pending = append(pending, span{addrs: []common.Address{c.Address}, from: c.Block})
+					resubscribe = true
+				}
+			}
+		}
+		sub.Unsubscribe()
+		// 已经送达但还没处理的日志不能丢
+		for drained := false; !drained; {
+			select {
+			case l := <-logs:
+				if c := w.handle(l, true); c != nil {
+					pending = append(pending, span{addrs: []common.Address{c.Address}, from: c.Block})
+				}
+			default:
+				drained = true
+			}
+		}
+	}
+}
+
+// addresses 工厂加所有已知子合约
+func (w *Watcher) addresses() []common.Address {
+	addrs := make([]common.Address, 0, len(w.factories))
+	for addr := range w.factories {
+		addrs = append(addrs, addr)
+	}
+	for _, c := range w.store.Children() {
+		if _, ok := w.factories[c.Address]; !ok {
+			addrs = append(addrs, c.Address)
+		}
+	}
+	return addrs
+}
+
+// backfill 按 backfillRange 分段补 [s.from, head] 的日志，返回期间新发现的子合约
+func (w *Watcher) backfill(ctx context.Context, s span) ([]*Child, error) {
+	head, err := w.client.BlockNumber(ctx)
+	if err != nil {
+		return nil, err
+	}
+	addrs := s.addrs
+	if len(addrs) == 0 {
+		addrs = w.addresses()
+	}
+	var children []*Child
+	for from := s.from; from <= head; from += backfillRange {
+		to := min(from+backfillRange-1, head)
+		logs, err := w.client.FilterLogs(ctx, ethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(from),
+			ToBlock:   new(big.Int).SetUint64(to),
+			Addresses: addrs,
+		})
+		if err != nil {
+			return children, err
+		}
+		for _, l := range logs {
+			if c := w.handle(l, len(s.addrs) == 0); c != nil {
+				children = append(children, c)
+			}
+		}
+	}
+	if s.from <= head {
+		log.Printf("✔️ 补齐 %d 个地址 %d-%d 区块的日志", len(addrs), s.from, head)
+	}
+	// 全部地址都补到了 head 才能推进 lastBlock
+	if len(s.addrs) == 0 {
+		if err := w.store.SetLastBlock(head); err != nil {
+			log.Printf("⚠️ 保存区块进度失败: %v", err)
+		}
+	}
+	return children, nil
+}
+
+// handle 去重、解码并回调，日志是工厂的创建事件且子合约是新的时返回子合约；
+// 只补单个子合约时 progress 为 false，不推进 lastBlock
+func (w *Watcher) handle(l types.Log, progress bool) *Child {
+	key := logKey{block: l.BlockHash, index: l.Index, removed: l.Removed}
+	w.mu.Lock()
+	if _, ok := w.seen[key]; ok {
+		w.mu.Unlock()
+		return nil
+	}
+	if len(w.seen) > 10000 {
+		clear(w.seen)
+	}
+	w.seen[key] = struct{}{}
+	w.mu.Unlock()
+
+	// 本区块的日志可能还没处理完，重启后从本区块重新补
+	if progress && l.BlockNumber > 0 && !l.Removed {
+		if err := w.store.SetLastBlock(l.BlockNumber - 1); err != nil {
+			log.Printf("⚠️ 保存区块进度失败: %v", err)
+		}
+	}
+
+	decoder := w.decoder(l.Address)
+	if decoder == nil {
+		return nil
+	}
+	var blockTime time.Time
+	if w.BlockTime != nil {
+		blockTime = w.BlockTime(l.BlockNumber)
+	}
+	record, err := decoder.Decode(l, blockTime)
+	if errors.Is(err, decode.ErrUnknownEvent) {
+		return nil
+	}
+	if err != nil {
+		log.Printf("❌ 解码事件失败 tx: %s: %v", l.TxHash.Hex(), err)
+		return nil
+	}
+	if w.OnRecord != nil {
+		w.OnRecord(record)
+	}
+
+	f, ok := w.factories[l.Address]
+	if !ok || record.Event != f.Event || l.Removed {
+		return nil
+	}
+	value, _ := record.Get(f.ChildArg)
+	addr, ok := value.(common.Address)
+	if !ok || addr == (common.Address{}) {
+		return nil
+	}
+	c := &Child{Address: addr, Factory: f.Name, Block: l.BlockNumber, TxHash: l.TxHash}
+	added, err := w.store.Add(c)
+	if err != nil {
+		log.Printf("⚠️ 保存子合约 %s 失败: %v", addr.Hex(), err)
+	}
+	if !added {
+		return nil
+	}
+	log.Printf("✔️ %s 创建子合约 %s", f.Name, addr.Hex())
+	if w.OnChild != nil {
+		w.OnChild(f, c)
+	}
+	return c
+}
+
+// decoder 工厂用工厂 ABI，子合约用所属工厂的 ChildABI
+func (w *Watcher) decoder(addr common.Address) *decode.Decoder {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if d, ok := w.decoders[addr]; ok {
+		return d
+	}
+	f, child := w.factories[addr], false
+	if c, ok := w.store.Get(addr); f == nil && ok {
+		f, child = w.factoryByName(c.Factory), true
+	}
+	if f == nil {
+		return nil
+	}
+	var d *decode.Decoder
+	switch {
+	case w.NewDecoder != nil:
+		d = w.NewDecoder(f, child)
+	case child:
+		d = decode.NewDecoder(f.ChildABI)
+	default:
+		d = decode.NewDecoder(f.ABI)
+	}
+	w.decoders[addr] = d
+	return d
+}
+
+func (w *Watcher) factoryByName(name string) *Factory {
+	for _, f := range w.factories {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
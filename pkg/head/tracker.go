@@ -0,0 +1,238 @@
+package head
+
+import (
+	"context"
+	"log"
+	"math/big"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+const (
+	// window 出块间隔统计的样本数
+	window = 256
+	// keep 缓存的最近区块头数量
+	keep = 128
+	// maxFill 重新订阅或漏推时最多补拉的区块数
+	maxFill = 64
+)
+
+// Client 需要的 RPC 方法，*ethclient.Client 和 poll.Client 都满足
+type Client interface {
+	SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
+// Head 推送给订阅者的新区块
+type Head struct {
+	Header   *types.Header
+	Interval time.Duration // 与父区块的出块间隔，父区块未知时为 0
+	Missed   int           // 与父区块之间错过的 slot 数，SlotTime 为 0 时不检测
+	Reorg    bool          // 高度没有前进或父哈希与缓存的上一个区块不一致，链头切换到了另一条分叉
+}
+
+// Stats 最近 window 个出块间隔的统计
+type Stats struct {
+	Count  int           `json:"count"`
+	Mean   time.Duration `json:"mean"`
+	Min    time.Duration `json:"min"`
+	Max    time.Duration `json:"max"`
+	P50    time.Duration `json:"p50"`
+	P95    time.Duration `json:"p95"`
+	Missed int           `json:"missed"` // 启动以来错过的 slot 总数
+	Reorgs int           `json:"reorgs"`
+}
+
+// Tracker 进程内共用的区块头服务：订阅新区块，缓存最近的区块头，推送给订阅者并统计出块间隔
+type Tracker struct {
+	client   Client
+	SlotTime time.Duration // 出块周期，以太坊 12s，为 0 时不检测错过的 slot
+
+	feed event.Feed
+
+	mu        sync.RWMutex
+	latest    *types.Header
+	headers   map[uint64]*types.Header
+	intervals []time.Duration // 环形缓冲
+	next      int
+	missed    int
+	reorgs    int
+	ready     chan struct{}
+	readyOnce sync.Once
+}
+
+func NewTracker(client Client, slotTime time.Duration) *Tracker {
+	return &Tracker{
+		client:   client,
+		SlotTime: slotTime,
+		headers:  make(map[uint64]*types.Header),
+		ready:    make(chan struct{}),
+	}
+}
+
+// Run 订阅新区块直到 ctx 取消，订阅断开时 1 秒后重新订阅，期间漏掉的区块按区块号补拉
+func (t *Tracker) Run(ctx context.Context) error {
+	for {
+		err := t.run(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		log.Printf("⚠️ 区块订阅中断，1 秒后重新订阅: %v", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (t *Tracker) run(ctx context.Context) error {
+	headers := make(chan *types.Header, 64)
+	sub, err := t.client.SubscribeNewHead(ctx, headers)
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-sub.Err():
+			return err
+		case h := <-headers:
+			t.fill(ctx, h)
+			t.add(h)
+		}
+	}
+}
+
+// fill 补拉上一个区块和 h 之间缺失的区块，保证订阅者按区块号连续收到
+func (t *Tracker) fill(ctx context.Context, h *types.Header) {
+	t.mu.RLock()
+	latest := t.latest
+	t.mu.RUnlock()
+	if latest == nil || h.Number.Uint64() <= latest.Number.Uint64()+1 {
+		return
+	}
+	from := latest.Number.Uint64() + 1
+	if n := h.Number.Uint64(); n > maxFill {
+		from = max(from, n-maxFill)
+	}
+	for n := from; n < h.Number.Uint64(); n++ {
+		missing, err := t.client.HeaderByNumber(ctx, new(big.Int).SetUint64(n))
+		if err != nil {
+			log.Printf("⚠️ 补拉区块 %d 失败: %v", n, err)
+			return
+		}
+		t.add(missing)
+	}
+}
+
+// add 记录区块头、更新统计并推送
+func (t *Tracker) add(h *types.Header) {
+	number := h.Number.Uint64()
+	e := &Head{Header: h}
+
+	t.mu.Lock()
+	if t.latest != nil && t.latest.Hash() == h.Hash() {
+		t.mu.Unlock()
+		return
+	}
+	// 高度没有前进或父哈希对不上都说明链头切换到了另一条分叉
+	if t.latest != nil && number <= t.latest.Number.Uint64() {
+		e.Reorg = true
+	}
+	parent, hasParent := t.headers[number-1]
+	if hasParent && parent.Hash() != h.ParentHash {
+		e.Reorg = true
+	}
+	if hasParent && parent.Hash() == h.ParentHash {
+		e.Interval = time.Duration(h.Time-parent.Time) * time.Second
+	}
+	if e.Reorg {
+		t.reorgs++
+	} else if hasParent {
+		if t.SlotTime > 0 && e.Interval >= 2*t.SlotTime {
+			e.Missed = int(e.Interval/t.SlotTime) - 1
+			t.missed += e.Missed
+		}
+		t.record(e.Interval)
+	}
+	t.headers[number] = h
+	// 重组后更高的旧区块作废
+	for n := range t.headers {
+		if n > number || n+keep <= number {
+			delete(t.headers, n)
+		}
+	}
+	t.latest = h
+	t.mu.Unlock()
+
+	t.readyOnce.Do(func() { close(t.ready) })
+	t.feed.Send(e)
+}
+
+func (t *Tracker) record(d time.Duration) {
+	if len(t.intervals) < window {
+		t.intervals = append(t.intervals, d)
+		return
+	}
+	t.intervals[t.next] = d
+	t.next = (t.next + 1) % window
+}
+
+// Subscribe 订阅新区块，ch 太慢会阻塞推送，订阅者应使用带缓冲的 channel
+func (t *Tracker) Subscribe(ch chan<- *Head) event.Subscription {
+	return t.feed.Subscribe(ch)
+}
+
+// Latest 最新区块头，还没收到时为 nil
+func (t *Tracker) Latest() *types.Header {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.latest
+}
+
+// Wait 等到收到第一个区块
+func (t *Tracker) Wait(ctx context.Context) (*types.Header, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-t.ready:
+		return t.Latest(), nil
+	}
+}
+
+// Header 缓存中的区块头，只保留最近 keep 个
+func (t *Tracker) Header(number uint64) (*types.Header, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	h, ok := t.headers[number]
+	return h, ok
+}
+
+// Stats 出块间隔统计
+func (t *Tracker) Stats() Stats {
+	t.mu.RLock()
+	intervals := slices.Clone(t.intervals)
+	s := Stats{Count: len(intervals), Missed: t.missed, Reorgs: t.reorgs}
+	t.mu.RUnlock()
+	if len(intervals) == 0 {
+		return s
+	}
+	slices.Sort(intervals)
+	var sum time.Duration
+	for _, d := range intervals {
+		sum += d
+	}
+	s.Mean = sum / time.Duration(len(intervals))
+	s.Min, s.Max = intervals[0], intervals[len(intervals)-1]
+	s.P50 = intervals[len(intervals)/2]
+	s.P95 = intervals[min(len(intervals)-1, len(intervals)*95/100)]
+	return s
+}
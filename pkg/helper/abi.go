@@ -0,0 +1,183 @@
+package helper
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/lmittmann/w3"
+)
+
+// PackCall 按函数签名（如 "presale(uint256)"）和参数生成 calldata，参数可以是字符串、数字或布尔
+func PackCall(signature string, args ...any) ([]byte, error) {
+	fn, err := w3.NewFunc(signature, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(args) != len(fn.Args) {
+		return nil, fmt.Errorf("%s: expected %d args, got %d", fn.Signature, len(fn.Args), len(args))
+	}
+	values := make([]any, len(args))
+	for i, arg := range args {
+		if values[i], err = ConvertArg(fn.Args[i].Type, arg); err != nil {
+			return nil, fmt.Errorf("%s: arg %d: %w", fn.Signature, i, err)
+		}
+	}
+	return fn.EncodeArgs(values...)
+}
+
+// ParseSelector 解析 "0x12345678" 或 "presale(uint256)" 形式的方法选择器
+func ParseSelector(s string) ([4]byte, error) {
+	var selector [4]byte
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "0x") {
+		b, err := hexutil.Decode(s)
+		if err != nil || len(b) != 4 {
+			return selector, fmt.Errorf("invalid selector %q", s)
+		}
+		copy(selector[:], b)
+		return selector, nil
+	}
+	fn, err := w3.NewFunc(s, "")
+	if err != nil {
+		return selector, err
+	}
+	copy(selector[:], fn.Selector[:])
+	return selector, nil
+}
+
+// ConvertArg 把配置或命令行里的参数转换为 abi 打包需要的 Go 类型
+func ConvertArg(t abi.Type, v any) (any, error) {
+	switch t.T {
+	case abi.AddressTy:
+		s, ok := v.(string)
+		if !ok || !common.IsHexAddress(s) {
+			return nil, fmt.Errorf("invalid address %v", v)
+		}
+		return common.HexToAddress(s), nil
+	case abi.BoolTy:
+		switch b := v.(type) {
+		case bool:
+			return b, nil
+		case string:
+			if b == "true" || b == "false" {
+				return b == "true", nil
+			}
+		}
+		return nil, fmt.Errorf("invalid bool %v", v)
+	case abi.StringTy:
+		return fmt.Sprint(v), nil
+	case abi.BytesTy:
+		s, _ := v.(string)
+		return hexutil.Decode(s)
+	case abi.FixedBytesTy:
+		s, _ := v.(string)
+		b, err := hexutil.Decode(s)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) > t.Size {
+			return nil, fmt.Errorf("bytes%d overflow: %d bytes", t.Size, len(b))
+		}
+		arr := reflect.New(t.GetType()).Elem()
+		reflect.Copy(arr, reflect.ValueOf(b))
+		return arr.Interface(), nil
+	case abi.IntTy, abi.UintTy:
+		n, err := toBig(v)
+		if err != nil {
+			return nil, err
+		}
+		return convertInt(t, n)
+	case abi.SliceTy, abi.ArrayTy:
+		items, err := toList(v)
+		if err != nil {
+			return nil, err
+		}
+		if t.T == abi.ArrayTy && len(items) != t.Size {
+			return nil, fmt.Errorf("expected %d items, got %d", t.Size, len(items))
+		}
+		var out reflect.Value
+		if t.T == abi.SliceTy {
+			out = reflect.MakeSlice(t.GetType(), len(items), len(items))
+		} else {
+			out = reflect.New(t.GetType()).Elem()
+		}
+		for i, item := range items {
+			elem, err := ConvertArg(*t.Elem, item)
+			if err != nil {
+				return nil, fmt.Errorf("item %d: %w", i, err)
+			}
+			out.Index(i).Set(reflect.ValueOf(elem))
+		}
+		return out.Interface(), nil
+	}
+	return nil, fmt.Errorf("unsupported abi type %s", t.String())
+}
+
+// convertInt 按位宽转换整数，uint8~uint64/int8~int64 需要对应的 Go 原生类型
+func convertInt(t abi.Type, n *big.Int) (any, error) {
+	if t.T == abi.UintTy && n.Sign() < 0 {
+		return nil, fmt.Errorf("negative value %s for %s", n, t.String())
+	}
+	bits := n.BitLen()
+	if t.T == abi.IntTy {
+		bits++
+	}
+	if bits > t.Size {
+		return nil, fmt.Errorf("value %s overflows %s", n, t.String())
+	}
+	goType := t.GetType()
+	if goType == reflect.TypeOf(new(big.Int)) {
+		return n, nil
+	}
+	if t.T == abi.UintTy {
+		return reflect.ValueOf(n.Uint64()).Convert(goType).Interface(), nil
+	}
+	return reflect.ValueOf(n.Int64()).Convert(goType).Interface(), nil
+}
+
+func toBig(v any) (*big.Int, error) {
+	switch n := v.(type) {
+	case *big.Int:
+		return n, nil
+	case int:
+		return big.NewInt(int64(n)), nil
+	case int64:
+		return big.NewInt(n), nil
+	case uint64:
+		return new(big.Int).SetUint64(n), nil
+	case float64:
+		if n != math.Trunc(n) {
+			return nil, fmt.Errorf("invalid integer %v", n)
+		}
+		f, _ := big.NewFloat(n).Int(nil)
+		return f, nil
+	case string:
+		return ParseBig(n)
+	}
+	return nil, fmt.Errorf("invalid integer %v", v)
+}
+
+// toList 支持 []any 或逗号分隔的字符串
+func toList(v any) ([]any, error) {
+	switch l := v.(type) {
+	case []any:
+		return l, nil
+	case string:
+		if l == "" {
+			return nil, nil
+		}
+		parts := strings.Split(l, ",")
+		items := make([]any, len(parts))
+		for i, p := range parts {
+			items[i] = strings.TrimSpace(p)
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("invalid list %v", v)
+}
@@ -1,9 +1,14 @@
 package helper
 
 import (
+	"fmt"
 	"log"
+	"math/big"
 	"os"
 	"path/filepath"
+	"strings"
+
+	"github.com/lmittmann/w3"
 )
 
 // ReadAbiJson 获取abi
@@ -19,3 +24,28 @@ func ReadAbiJson(fileName string) string {
 	}
 	return string(content)
 }
+
+// ParseEther 解析 "0.01 ether"、"2 gwei"、"1000"、"-0.05" 这类金额为 wei，不带单位时按 ether 处理
+func ParseEther(s string) (*big.Int, error) {
+	s = strings.TrimSpace(s)
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimSpace(strings.TrimPrefix(s, "-"))
+	if s != "" && !strings.Contains(s, " ") && !strings.HasPrefix(s, "0x") {
+		s += " ether"
+	}
+	n, err := ParseBig(s)
+	if err != nil || !neg {
+		return n, err
+	}
+	return n.Neg(n), nil
+}
+
+// ParseBig 解析十进制、0x 十六进制或带 ether/gwei 单位的整数
+func ParseBig(s string) (n *big.Int, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			n, err = nil, fmt.Errorf("invalid number %q: %v", s, r)
+		}
+	}()
+	return w3.I(strings.TrimSpace(s)), nil
+}
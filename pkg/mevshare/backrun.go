@@ -0,0 +1,152 @@
+package mevshare
+
+import (
+	"chainget/pkg/bundle"
+	"chainget/pkg/helper"
+	"chainget/pkg/nonce"
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"log"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/lmittmann/flashbots"
+	"github.com/lmittmann/w3"
+	"github.com/lmittmann/w3/module/eth"
+	"github.com/lmittmann/w3/w3types"
+)
+
+// Backrunner 消费 hint，命中规则后提交 [hint 交易, 我方交易] 的 mev_sendBundle
+type Backrunner struct {
+	Rules  []*Rule
+	Blocks uint64 // bundle 有效区块数
+	DryRun bool   // 只打印不提交
+
+	client *w3.Client // 以太坊节点
+	relay  *w3.Client // 带签名的 relay 连接
+	signer *bundle.Signer
+	fees   *bundle.FeeOracle
+	nonces *nonce.Manager
+}
+
+// NewBackrunner nonces 须与同一私钥的其他发送方共用，并由调用方按新区块同步（Run 或 SyncAll），
+// 否则过期 bundle 占用的 nonce 不会归还
+func NewBackrunner(client *w3.Client, relayURL string, key *ecdsa.PrivateKey, chainID *big.Int, nonces *nonce.Manager, rules []*Rule) (*Backrunner, error) {
+	relay, err := flashbots.Dial(relayURL, key)
+	if err != nil {
+		return nil, err
+	}
+	return &Backrunner{
+		Rules:  rules,
+		Blocks: 5,
+		client: client,
+		relay:  relay,
+		signer: bundle.NewSigner(key, chainID),
+		fees:   bundle.NewFeeOracle(client),
+		nonces: nonces,
+	}, nil
+}
+
+func (b *Backrunner) Close() error {
+	return b.relay.Close()
+}
+
+// Run 订阅事件流并逐个处理 hint，直到 ctx 取消
+func (b *Backrunner) Run(ctx context.Context, stream *Client) error {
+	hints := make(chan *Hint, 256)
+	errc := make(chan error, 1)
+	go func() { errc <- stream.Subscribe(ctx, hints) }()
+	for {
+		select {
+		case err := <-errc:
+			return err
+		case hint := <-hints:
+			for _, rule := range b.Rules {
+				if !rule.Match(hint) {
+					continue
+				}
+				hash, err := b.Backrun(ctx, hint, rule)
+				if err != nil {
+					log.Printf("❌ 规则 %s backrun %s 失败: %v", rule.Name, hint.Hash.Hex(), err)
+					continue
+				}
+				log.Printf("✔️ 规则 %s backrun %s 已提交 bundleHash: %s", rule.Name, hint.Hash.Hex(), hash.Hex())
+			}
+		}
+	}
+}
+
+// Backrun 按规则签名我方交易，提交引用 hint 交易 hash 的 bundle；dry-run 或提交失败时归还 nonce
+func (b *Backrunner) Backrun(ctx context.Context, hint *Hint, rule *Rule) (common.Hash, error) {
+	req, reservation, err := b.buildBundle(ctx, hint, rule)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if b.DryRun {
+		reservation.Release()
+		log.Printf("✔️ dry-run 规则 %s 命中 %s，区块 %d-%d，不提交", rule.Name, hint.Hash.Hex(), req.Inclusion.Block, req.Inclusion.MaxBlock)
+		return common.Hash{}, nil
+	}
+	var hash common.Hash
+	if err := b.relay.CallCtx(ctx, SendBundle(req).Returns(&hash)); err != nil {
+		reservation.Release()
+		return common.Hash{}, err
+	}
+	return hash, nil
+}
+
+func (b *Backrunner) buildBundle(ctx context.Context, hint *Hint, rule *Rule) (*SendBundleRequest, *nonce.Reservation, error) {
+	data, err := helper.PackCall(rule.Action.Sig, rule.Action.Args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	value := new(big.Int)
+	if rule.Action.Value != "" {
+		if value, err = helper.ParseEther(rule.Action.Value); err != nil {
+			return nil, nil, err
+		}
+	}
+	var (
+		head  *big.Int
+		gas   = rule.Action.Gas
+		calls = []w3types.RPCCaller{
+			eth.BlockNumber().Returns(&head),
+		}
+	)
+	if gas == 0 {
+		calls = append(calls, eth.EstimateGas(&w3types.Message{From: b.signer.Address, To: &rule.Action.To, Value: value, Input: data}, nil).Returns(&gas))
+	}
+	if err := b.client.CallCtx(ctx, calls...); err != nil {
+		return nil, nil, fmt.Errorf("获取交易参数失败: %w", err)
+	}
+	fees, err := b.fees.Suggest(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	//多个 hint 的 bundle 可能同时有效，各自占用不同 nonce，过了 MaxBlock 未上链自动归还
+	reservation, err := b.nonces.Reserve(ctx, b.signer.Address, 1, head.Uint64()+b.Blocks)
+	if err != nil {
+		return nil, nil, err
+	}
+	tx, err := b.signer.Sign(fees.Tx(b.signer.ChainID, reservation.First(), rule.Action.To, value, gas, data))
+	if err != nil {
+		reservation.Release()
+		return nil, nil, err
+	}
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		reservation.Release()
+		return nil, nil, err
+	}
+	hash := hint.Hash
+	return &SendBundleRequest{
+		Version: "v0.1",
+		Inclusion: Inclusion{
+			Block:    hexutil.Uint64(head.Uint64() + 1),
+			MaxBlock: hexutil.Uint64(head.Uint64() + b.Blocks),
+		},
+		Body: []BundleBody{{Hash: &hash}, {Tx: raw}},
+	}, reservation, nil
+}
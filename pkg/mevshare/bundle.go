@@ -0,0 +1,82 @@
+package mevshare
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/lmittmann/w3/w3types"
+)
+
+// SendBundleRequest mev_sendBundle 请求，Body 中可以用 hash 引用 hint 里的交易
+type SendBundleRequest struct {
+	Version   string       `json:"version"`
+	Inclusion Inclusion    `json:"inclusion"`
+	Body      []BundleBody `json:"body"`
+	Validity  *Validity    `json:"validity,omitempty"`
+	Privacy   *Privacy     `json:"privacy,omitempty"`
+}
+
+// Inclusion bundle 有效的区块范围
+type Inclusion struct {
+	Block    hexutil.Uint64 `json:"block"`
+	MaxBlock hexutil.Uint64 `json:"maxBlock,omitempty"`
+}
+
+// BundleBody 一个 bundle 元素：引用的交易 hash 或我方签名交易
+type BundleBody struct {
+	Hash      *common.Hash  `json:"hash,omitempty"`
+	Tx        hexutil.Bytes `json:"tx,omitempty"`
+	CanRevert bool          `json:"canRevert,omitempty"`
+}
+
+// Validity 返还给用户的收益比例
+type Validity struct {
+	Refund []Refund `json:"refund,omitempty"`
+}
+
+type Refund struct {
+	BodyIdx int `json:"bodyIdx"`
+	Percent int `json:"percent"`
+}
+
+// Privacy 我方 bundle 对外公开的字段和允许的 builder
+type Privacy struct {
+	Hints    []string `json:"hints,omitempty"`
+	Builders []string `json:"builders,omitempty"`
+}
+
+// SendBundle 调用 mev_sendBundle，需要使用 flashbots.Dial 建立带签名的连接
+func SendBundle(r *SendBundleRequest) w3types.RPCCallerFactory[common.Hash] {
+	return &sendBundleFactory{param: r}
+}
+
+type sendBundleFactory struct {
+	param  *SendBundleRequest
+	result struct {
+		BundleHash common.Hash `json:"bundleHash"`
+	}
+	returns *common.Hash
+}
+
+func (f *sendBundleFactory) Returns(hash *common.Hash) w3types.RPCCaller {
+	f.returns = hash
+	return f
+}
+
+func (f *sendBundleFactory) CreateRequest() (rpc.BatchElem, error) {
+	return rpc.BatchElem{
+		Method: "mev_sendBundle",
+		Args:   []any{f.param},
+		Result: &f.result,
+	}, nil
+}
+
+func (f *sendBundleFactory) HandleResponse(elem rpc.BatchElem) error {
+	if err := elem.Error; err != nil {
+		return err
+	}
+	if f.returns != nil {
+		*f.returns = f.result.BundleHash
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package mevshare
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	MainnetStreamURL = "https://mev-share.flashbots.net"
+	SepoliaStreamURL = "https://mev-share-sepolia.flashbots.net"
+)
+
+// Client MEV-Share SSE 事件流客户端
+type Client struct {
+	URL           string
+	HTTP          *http.Client
+	RetryInterval time.Duration // 断线重连间隔
+}
+
+func NewClient(url string) *Client {
+	return &Client{URL: url, HTTP: http.DefaultClient, RetryInterval: 3 * time.Second}
+}
+
+// Subscribe 订阅事件流，解析出的 hint 发送到 ch，断线后自动重连，直到 ctx 取消
+func (c *Client) Subscribe(ctx context.Context, ch chan<- *Hint) error {
+	for {
+		err := c.stream(ctx, ch)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		log.Printf("⚠️ mev-share 事件流断开: %v，%s 后重连", err, c.RetryInterval)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(c.RetryInterval):
+		}
+	}
+}
+
+// stream 读取一次连接上的事件，连接断开时返回
+func (c *Client) stream(ctx context.Context, ch chan<- *Hint) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.URL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var (
+		scanner = bufio.NewScanner(resp.Body)
+		data    bytes.Buffer
+	)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		switch {
+		case len(line) == 0: //空行表示一个事件结束
+			if data.Len() == 0 {
+				continue
+			}
+			hint := new(Hint)
+			if err := json.Unmarshal(data.Bytes(), hint); err != nil {
+				log.Printf("⚠️ 解析 mev-share 事件失败: %v", err)
+			} else {
+				select {
+				case ch <- hint:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			data.Reset()
+		case line[0] == ':': //注释，服务端心跳
+		case bytes.HasPrefix(line, []byte("data:")):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.Write(bytes.TrimPrefix(bytes.TrimPrefix(line, []byte("data:")), []byte(" ")))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("stream closed")
+}
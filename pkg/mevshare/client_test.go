@@ -0,0 +1,107 @@
+package mevshare
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	target   = common.HexToAddress("0x332C7bD5F4B2a9e1D7b1C3a4e5f60718293a4b5c")
+	presale  = []byte{0xe6, 0xab, 0x14, 0x34} // presale(uint256)
+	transfer = common.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+)
+
+// ruleConfig 带上 backrun 动作的规则配置
+func ruleConfig(name string, selectors, topics []string) RuleConfig {
+	c := RuleConfig{Name: name, Selectors: selectors, Topics: topics}
+	c.Action.To, c.Action.Sig, c.Action.Args = target.Hex(), "presale(uint256)", []any{1}
+	return c
+}
+
+func TestRuleConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		selectors []string
+		topics    []string
+	}{
+		{"hex", []string{"0xe6ab1434"}, []string{transfer.Hex()}},
+		{"signature", []string{"presale(uint256)"}, []string{"Transfer(address,address,uint256)"}},
+		{"named args", []string{"presale(uint256 amount)"}, []string{"Transfer(address indexed from, address indexed to, uint256 value)"}},
+	}
+	for _, tt := range tests {
+		r, err := ruleConfig(tt.name, tt.selectors, tt.topics).Rule()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if string(r.Selectors[0]) != string(presale) {
+			t.Errorf("%s: selector = %x, want %x", tt.name, r.Selectors[0], presale)
+		}
+		if r.Topics[0] != transfer {
+			t.Errorf("%s: topic = %s, want %s", tt.name, r.Topics[0].Hex(), transfer.Hex())
+		}
+	}
+
+	for _, c := range []RuleConfig{
+		ruleConfig("short selector", []string{"0xe6ab14"}, nil),
+		ruleConfig("short topic", nil, []string{"0xddf252ad"}),
+		ruleConfig("bad signature", []string{"presale(uint)x"}, nil),
+	} {
+		if _, err := c.Rule(); err == nil {
+			t.Errorf("%s: want error", c.Name)
+		}
+	}
+}
+
+// TestSubscribe 用本地 Server 替身推送 hint，客户端解析后按规则匹配
+func TestSubscribe(t *testing.T) {
+	srv := NewServer()
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	hints := make(chan *Hint, 4)
+	go NewClient(ts.URL).Subscribe(ctx, hints)
+	for srv.Subscribers() == 0 {
+		select {
+		case <-ctx.Done():
+			t.Fatal("client did not connect")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	c := ruleConfig("presale", []string{"presale(uint256)"}, nil)
+	c.To = []string{target.Hex()}
+	rule, err := c.Rule()
+	if err != nil {
+		t.Fatal(err)
+	}
+	published := []*Hint{
+		{Hash: common.HexToHash("0x01"), Txs: []HintTx{{To: &target, FunctionSelector: presale}}},
+		{Hash: common.HexToHash("0x02"), Txs: []HintTx{{To: &target, FunctionSelector: []byte{1, 2, 3, 4}}}},
+		{Hash: common.HexToHash("0x03"), Txs: []HintTx{{To: &target, CallData: append(presale, make([]byte, 32)...)}}},
+	}
+	want := []bool{true, false, true}
+	for _, h := range published {
+		if err := srv.Publish(h); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, h := range published {
+		select {
+		case got := <-hints:
+			if got.Hash != h.Hash {
+				t.Fatalf("hint %d: hash = %s, want %s", i, got.Hash.Hex(), h.Hash.Hex())
+			}
+			if rule.Match(got) != want[i] {
+				t.Errorf("hint %s: match = %v, want %v", got.Hash.Hex(), !want[i], want[i])
+			}
+		case <-ctx.Done():
+			t.Fatalf("hint %d not received", i)
+		}
+	}
+}
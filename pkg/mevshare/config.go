@@ -0,0 +1,100 @@
+package mevshare
+
+import (
+	"chainget/pkg/helper"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/lmittmann/w3"
+)
+
+// RuleConfig config.yaml 里的规则，选择器和 topic 既可以写十六进制，也可以写函数/事件签名
+//
+//	rules:
+//	  - name: presale
+//	    to: ["0x332C7b..."]
+//	    selectors: ["enablePresale()"]
+//	    action: {to: "0x332C7b...", sig: "presale(uint256)", args: [1], value: "0.01"}
+type RuleConfig struct {
+	Name         string   `mapstructure:"name"`
+	To           []string `mapstructure:"to"`
+	Selectors    []string `mapstructure:"selectors"`
+	LogAddresses []string `mapstructure:"logAddresses"`
+	Topics       []string `mapstructure:"topics"`
+	Action       struct {
+		To    string `mapstructure:"to"`
+		Sig   string `mapstructure:"sig"`
+		Args  []any  `mapstructure:"args"`
+		Value string `mapstructure:"value"`
+		Gas   uint64 `mapstructure:"gas"`
+	} `mapstructure:"action"`
+}
+
+// Rule 把配置转换为规则
+func (c RuleConfig) Rule() (*Rule, error) {
+	r := &Rule{
+		Name: c.Name,
+		Action: Action{
+			Sig:   c.Action.Sig,
+			Args:  c.Action.Args,
+			Value: c.Action.Value,
+			Gas:   c.Action.Gas,
+		},
+	}
+	var err error
+	if r.To, err = parseAddresses(c.To); err != nil {
+		return nil, fmt.Errorf("rule %s: to: %w", c.Name, err)
+	}
+	if r.LogAddresses, err = parseAddresses(c.LogAddresses); err != nil {
+		return nil, fmt.Errorf("rule %s: logAddresses: %w", c.Name, err)
+	}
+	for _, s := range c.Selectors {
+		sel, err := helper.ParseSelector(s)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: selector: %w", c.Name, err)
+		}
+		r.Selectors = append(r.Selectors, sel[:])
+	}
+	for _, s := range c.Topics {
+		topic, err := parseTopic(s)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: topic: %w", c.Name, err)
+		}
+		r.Topics = append(r.Topics, topic)
+	}
+	if c.Action.Sig == "" || !common.IsHexAddress(c.Action.To) {
+		return nil, fmt.Errorf("rule %s: action needs to and sig", c.Name)
+	}
+	r.Action.To = common.HexToAddress(c.Action.To)
+	return r, nil
+}
+
+// parseTopic 解析 32 字节十六进制 topic 或事件签名，签名可以带参数名和 indexed
+func parseTopic(s string) (common.Hash, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "0x") {
+		b, err := hexutil.Decode(s)
+		if err != nil || len(b) != common.HashLength {
+			return common.Hash{}, fmt.Errorf("invalid topic %q", s)
+		}
+		return common.BytesToHash(b), nil
+	}
+	event, err := w3.NewEvent(s)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return event.Topic0, nil
+}
+
+func parseAddresses(list []string) ([]common.Address, error) {
+	addrs := make([]common.Address, 0, len(list))
+	for _, s := range list {
+		if !common.IsHexAddress(s) {
+			return nil, fmt.Errorf("invalid address %q", s)
+		}
+		addrs = append(addrs, common.HexToAddress(s))
+	}
+	return addrs, nil
+}
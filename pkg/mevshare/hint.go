@@ -0,0 +1,87 @@
+package mevshare
+
+import (
+	"bytes"
+	"slices"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Hint MEV-Share 事件流推送的交易提示，发送者选择公开哪些字段，未公开的字段为空
+type Hint struct {
+	Hash        common.Hash  `json:"hash"`
+	Logs        []HintLog    `json:"logs"`
+	Txs         []HintTx     `json:"txs"`
+	MevGasPrice *hexutil.Big `json:"mevGasPrice,omitempty"`
+	GasUsed     *hexutil.Big `json:"gasUsed,omitempty"`
+}
+
+// HintLog 公开的日志
+type HintLog struct {
+	Address common.Address `json:"address"`
+	Topics  []common.Hash  `json:"topics"`
+	Data    hexutil.Bytes  `json:"data,omitempty"`
+}
+
+// HintTx 公开的交易字段
+type HintTx struct {
+	To               *common.Address `json:"to,omitempty"`
+	FunctionSelector hexutil.Bytes   `json:"functionSelector,omitempty"`
+	CallData         hexutil.Bytes   `json:"callData,omitempty"`
+}
+
+// Rule hint 匹配规则，同一类条件之间是或，不同类条件之间是且，空条件不参与匹配
+type Rule struct {
+	Name         string
+	To           []common.Address // 交易接收地址
+	Selectors    [][]byte         // 4 字节方法选择器
+	LogAddresses []common.Address // 日志合约地址
+	Topics       []common.Hash    // 日志 topic0
+	Action       Action           // 命中后发送的 backrun 调用
+}
+
+// Action backrun 交易内容，参数格式同 helper.PackCall
+type Action struct {
+	To    common.Address
+	Sig   string
+	Args  []any
+	Value string // ETH 数量
+	Gas   uint64 // 为 0 时按当前状态估算，依赖 hint 交易执行结果的调用需要指定
+}
+
+// Match 判断 hint 是否命中规则
+func (r *Rule) Match(h *Hint) bool {
+	if len(r.To) > 0 && !slices.ContainsFunc(h.Txs, func(tx HintTx) bool {
+		return tx.To != nil && slices.Contains(r.To, *tx.To)
+	}) {
+		return false
+	}
+	if len(r.Selectors) > 0 && !slices.ContainsFunc(h.Txs, func(tx HintTx) bool {
+		return slices.ContainsFunc(r.Selectors, func(sel []byte) bool { return tx.selector() != nil && bytes.Equal(sel, tx.selector()) })
+	}) {
+		return false
+	}
+	if len(r.LogAddresses) > 0 && !slices.ContainsFunc(h.Logs, func(l HintLog) bool {
+		return slices.Contains(r.LogAddresses, l.Address)
+	}) {
+		return false
+	}
+	if len(r.Topics) > 0 && !slices.ContainsFunc(h.Logs, func(l HintLog) bool {
+		return len(l.Topics) > 0 && slices.Contains(r.Topics, l.Topics[0])
+	}) {
+		return false
+	}
+	return true
+}
+
+// selector 公开了 callData 但没公开 functionSelector 时从 callData 取
+func (tx HintTx) selector() []byte {
+	if len(tx.FunctionSelector) == 4 {
+		return tx.FunctionSelector
+	}
+	if len(tx.CallData) >= 4 {
+		return tx.CallData[:4]
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package mevshare
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// Server 本地 MEV-Share 事件流替身，实现 http.Handler，可直接交给 httptest.NewServer 使用，
+// Publish 的 hint 会推送给所有已连接的订阅者
+type Server struct {
+	mu   sync.Mutex
+	subs map[chan []byte]struct{}
+}
+
+func NewServer() *Server {
+	return &Server{subs: make(map[chan []byte]struct{})}
+}
+
+// Publish 推送一个 hint
+func (s *Server) Publish(h *Hint) error {
+	data, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for sub := range s.subs {
+		select {
+		case sub <- data:
+		default: //订阅者太慢时丢弃，与线上行为一致
+		}
+	}
+	return nil
+}
+
+// Subscribers 当前连接数，测试中用于等待客户端连上
+func (s *Server) Subscribers() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.subs)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	sub := make(chan []byte, 64)
+	s.mu.Lock()
+	s.subs[sub] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.subs, sub)
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ":ping\n\n")
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case data := <-sub:
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			flusher.Flush()
+		}
+	}
+}
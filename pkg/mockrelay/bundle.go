@@ -0,0 +1,447 @@
+package mockrelay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+)
+
+// BundleStatus bundle 在模拟 relay 中的状态
+type BundleStatus int
+
+const (
+	BundlePending   BundleStatus = iota // 等待目标区块
+	BundleIncluded                      // 已被打包进目标区块
+	BundleFailed                        // 模拟失败或未能全部上链
+	BundleCancelled                     // 被 eth_cancelBundle 取消或被同 UUID 的新 bundle 替换
+	BundleExpired                       // 目标区块已过
+)
+
+func (s BundleStatus) String() string {
+	switch s {
+	case BundlePending:
+		return "pending"
+	case BundleIncluded:
+		return "included"
+	case BundleFailed:
+		return "failed"
+	case BundleCancelled:
+		return "cancelled"
+	case BundleExpired:
+		return "expired"
+	}
+	return fmt.Sprintf("BundleStatus(%d)", int(s))
+}
+
+// Bundle relay 收到的一个 bundle
+type Bundle struct {
+	Hash              common.Hash
+	Signer            common.Address
+	Txs               types.Transactions
+	BlockNumber       uint64
+	MinTimestamp      uint64
+	MaxTimestamp      uint64
+	RevertingTxHashes []common.Hash
+	ReplacementUUID   string
+	Status            BundleStatus
+	Error             string //失败原因
+
+	ReceivedAt  time.Time
+	SimulatedAt time.Time
+	IncludedAt  time.Time
+	Simulation  *CallBundleResponse //出块前最后一次模拟结果
+}
+
+type sendBundleArgs struct {
+	Txs               []hexutil.Bytes `json:"txs"`
+	BlockNumber       *hexutil.Big    `json:"blockNumber"`
+	MinTimestamp      uint64          `json:"minTimestamp,omitempty"`
+	MaxTimestamp      uint64          `json:"maxTimestamp,omitempty"`
+	RevertingTxHashes []common.Hash   `json:"revertingTxHashes,omitempty"`
+	ReplacementUUID   string          `json:"replacementUuid,omitempty"`
+}
+
+type cancelBundleArgs struct {
+	ReplacementUUID string `json:"replacementUuid"`
+}
+
+// zeroUUID lmittmann/flashbots 在未设置 UUID 时也会带上全零 UUID
+const zeroUUID = "00000000-0000-0000-0000-000000000000"
+
+// Bundle 按 hash 查询 bundle
+func (r *Relay) Bundle(hash common.Hash) (*Bundle, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b, ok := r.bundles[hash]
+	return b, ok
+}
+
+// Bundles 按接收顺序返回所有 bundle
+func (r *Relay) Bundles() []*Bundle {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	bundles := make([]*Bundle, 0, len(r.order))
+	for _, hash := range r.order {
+		bundles = append(bundles, r.bundles[hash])
+	}
+	return bundles
+}
+
+func (r *Relay) sendBundle(signer common.Address, params json.RawMessage) (any, error) {
+	var args sendBundleArgs
+	if err := decodeParam(params, &args); err != nil {
+		return nil, err
+	}
+	if len(args.Txs) == 0 {
+		return nil, errors.New("bundle has no transactions")
+	}
+	if args.BlockNumber == nil {
+		return nil, errors.New("missing blockNumber")
+	}
+	txs, err := decodeTxs(args.Txs)
+	if err != nil {
+		return nil, err
+	}
+	head, err := r.backend.Client().BlockNumber(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	target := args.BlockNumber.ToInt().Uint64()
+	if target <= head {
+		return nil, fmt.Errorf("blockNumber %d is not in the future (head %d)", target, head)
+	}
+
+	b := &Bundle{
+		Hash:              bundleHash(txs),
+		Signer:            signer,
+		Txs:               txs,
+		BlockNumber:       target,
+		MinTimestamp:      args.MinTimestamp,
+		MaxTimestamp:      args.MaxTimestamp,
+		RevertingTxHashes: args.RevertingTxHashes,
+		Status:            BundlePending,
+		ReceivedAt:        time.Now(),
+	}
+	if args.ReplacementUUID != zeroUUID {
+		b.ReplacementUUID = args.ReplacementUUID
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if b.ReplacementUUID != "" {
+		//同一签名者、同一 UUID 的旧 bundle 被替换
+		for _, old := range r.bundles {
+			if old.Signer == signer && old.ReplacementUUID == b.ReplacementUUID && old.Status == BundlePending {
+				old.Status = BundleCancelled
+				old.Error = "replaced by " + b.Hash.Hex()
+			}
+		}
+	}
+	if _, ok := r.bundles[b.Hash]; !ok {
+		r.order = append(r.order, b.Hash)
+	}
+	r.bundles[b.Hash] = b
+	r.user(signer).bundles++
+	return map[string]common.Hash{"bundleHash": b.Hash}, nil
+}
+
+func (r *Relay) cancelBundle(signer common.Address, params json.RawMessage) (any, error) {
+	var args cancelBundleArgs
+	if err := decodeParam(params, &args); err != nil {
+		return nil, err
+	}
+	if args.ReplacementUUID == "" || args.ReplacementUUID == zeroUUID {
+		return nil, errors.New("missing replacementUuid")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, b := range r.bundles {
+		if b.Signer == signer && b.ReplacementUUID == args.ReplacementUUID && b.Status == BundlePending {
+			b.Status = BundleCancelled
+			b.Error = "cancelled"
+		}
+	}
+	return nil, nil
+}
+
+// Commit 出一个新块：先把目标区块为下一块、模拟通过的 bundle、私密交易和 mev_sendBundle 交易按接收顺序放进交易池，
+// 再让模拟链出块，最后根据收据确认 bundle 是否上链，返回新块 hash。
+// 时间戳范围按新块的时间戳检查，与模拟链一致取当前时间，且至少比父块晚 1 秒。
+//
+// 块内交易顺序由模拟链的矿工按手续费排序决定，不保证与 bundle 内顺序一致；
+// bundle 的交易没有按顺序连续打包，或不允许回滚的交易在链上失败时，bundle 标记为失败。
+// 读取链状态出错时不出块，直接返回错误。
+func (r *Relay) Commit() (common.Hash, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ctx := context.Background()
+	client := r.backend.Client()
+	head, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("head: %w", err)
+	}
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("chain id: %w", err)
+	}
+	var (
+		target    = head.Number.Uint64() + 1
+		now       = time.Now()
+		timestamp = max(uint64(now.Unix()), head.Time+1)
+		signer    = types.LatestSignerForChainID(chainID)
+		accepted  types.Transactions
+		included  []*Bundle
+	)
+	for _, hash := range r.order {
+		b := r.bundles[hash]
+		if b.Status != BundlePending {
+			continue
+		}
+		if b.BlockNumber < target {
+			b.Status = BundleExpired
+			continue
+		}
+		if b.BlockNumber > target {
+			continue
+		}
+		if (b.MinTimestamp != 0 && timestamp < b.MinTimestamp) || (b.MaxTimestamp != 0 && timestamp > b.MaxTimestamp) {
+			b.Status = BundleFailed
+			b.Error = "timestamp out of range"
+			continue
+		}
+		//在已接受的 bundle 之后顺序模拟，避免互相冲突
+		res, err := r.simulate(ctx, "latest", new(big.Int).SetUint64(target), timestamp, append(slices.Clone(accepted), b.Txs...))
+		b.SimulatedAt = now
+		if err != nil {
+			b.Status = BundleFailed
+			b.Error = err.Error()
+			continue
+		}
+		res.Results = res.Results[len(accepted):]
+		res.BundleHash = b.Hash
+		res.sum()
+		b.Simulation = res
+		r.user(b.Signer).record(res)
+		if reason := b.revertReason(); reason != "" {
+			b.Status = BundleFailed
+			b.Error = reason
+			continue
+		}
+		if err := sendTxs(ctx, client, signer, b.Txs); err != nil {
+			b.Status = BundleFailed
+			b.Error = err.Error()
+			continue
+		}
+		accepted = append(accepted, b.Txs...)
+		included = append(included, b)
+	}
+
+	//私密交易排在 bundle 之后，直到 MaxBlockNumber 前每个块都尝试打包
+	var includedTxs []*PrivateTx
+	for _, hash := range r.privateOrder {
+		p := r.private[hash]
+		if p.Status != BundlePending {
+			continue
+		}
+		if p.MaxBlockNumber < target {
+			p.Status = BundleExpired
+			continue
+		}
+		res, err := r.simulate(ctx, "latest", new(big.Int).SetUint64(target), timestamp, append(slices.Clone(accepted), p.Tx))
+		if err != nil || res.Results[len(accepted)].Error != "" {
+			continue
+		}
+		if err := sendTxs(ctx, client, signer, types.Transactions{p.Tx}); err != nil {
+			continue
+		}
+		accepted = append(accepted, p.Tx)
+		includedTxs = append(includedTxs, p)
+	}
+
+	//mev_sendBundle：引用交易在交易池中时，我方交易跟在其后模拟并打包
+	var (
+		includedShares []*ShareBundle
+		shareRefs      = make(map[*ShareBundle]types.Transactions)
+	)
+	for _, hash := range r.shareOrder {
+		b := r.shares[hash]
+		if b.Status != BundlePending || target < b.Block {
+			continue
+		}
+		if b.MaxBlock < target {
+			b.Status = BundleExpired
+			continue
+		}
+		refs, ok := r.shareTxs(ctx, b)
+		if !ok {
+			continue
+		}
+		base := append(slices.Clone(accepted), refs...)
+		res, err := r.simulate(ctx, "latest", new(big.Int).SetUint64(target), timestamp, append(base, b.Txs...))
+		if err != nil || slices.ContainsFunc(res.Results[len(base):], func(tx CallBundleTx) bool { return tx.Error != "" }) {
+			continue
+		}
+		if err := sendTxs(ctx, client, signer, b.Txs); err != nil {
+			continue
+		}
+		accepted = append(base, b.Txs...)
+		includedShares = append(includedShares, b)
+		shareRefs[b] = refs
+	}
+
+	blockHash := r.backend.Commit()
+	for _, b := range includedShares {
+		//引用交易在前、我方交易在后，引用交易本身允许失败
+		refs := shareRefs[b]
+		ok, reason := checkReceipts(ctx, client, blockHash, append(slices.Clone(refs), b.Txs...), txHashes(refs))
+		switch {
+		case !ok:
+			b.Status = BundlePending
+		case reason != "":
+			b.Status = BundleFailed
+			b.Error = reason
+		default:
+			b.Status = BundleIncluded
+			b.IncludedAt = time.Now()
+		}
+	}
+	for _, p := range includedTxs {
+		ok, reason := checkReceipts(ctx, client, blockHash, types.Transactions{p.Tx}, nil)
+		switch {
+		case !ok:
+		case reason != "":
+			p.Status = BundleFailed
+			p.Error = reason
+		default:
+			p.Status = BundleIncluded
+			p.IncludedAt = time.Now()
+		}
+	}
+	for _, b := range included {
+		ok, reason := checkReceipts(ctx, client, blockHash, b.Txs, b.RevertingTxHashes)
+		switch {
+		case !ok:
+			b.Status = BundleFailed
+			b.Error = fmt.Sprintf("not included in block %d: %s", target, reason)
+		case reason != "":
+			b.Status = BundleFailed
+			b.Error = reason
+		default:
+			b.Status = BundleIncluded
+			b.IncludedAt = time.Now()
+		}
+	}
+	return blockHash, nil
+}
+
+// checkReceipts 按收据检查 txs 是否按顺序连续地打包在 blockHash 中，且 reverting 之外的交易都执行成功。
+// included 为 false 表示有交易不在这个块里；reason 不为空表示已上链但不满足 bundle 的要求
+func checkReceipts(ctx context.Context, client simulated.Client, blockHash common.Hash, txs types.Transactions, reverting []common.Hash) (included bool, reason string) {
+	var prev uint
+	for i, tx := range txs {
+		receipt, err := client.TransactionReceipt(ctx, tx.Hash())
+		if err != nil {
+			return false, fmt.Sprintf("tx %s: %v", tx.Hash().Hex(), err)
+		}
+		if receipt.BlockHash != blockHash {
+			return false, fmt.Sprintf("tx %s in block %s", tx.Hash().Hex(), receipt.BlockHash.Hex())
+		}
+		if i > 0 && receipt.TransactionIndex != prev+1 {
+			reason = fmt.Sprintf("tx %s at index %d, expected %d after previous bundle tx", tx.Hash().Hex(), receipt.TransactionIndex, prev+1)
+		}
+		if reason == "" && receipt.Status != types.ReceiptStatusSuccessful && !slices.Contains(reverting, tx.Hash()) {
+			reason = fmt.Sprintf("tx %s reverted on chain", tx.Hash().Hex())
+		}
+		prev = receipt.TransactionIndex
+	}
+	return true, reason
+}
+
+func txHashes(txs types.Transactions) []common.Hash {
+	hashes := make([]common.Hash, len(txs))
+	for i, tx := range txs {
+		hashes[i] = tx.Hash()
+	}
+	return hashes
+}
+
+// revertReason 返回第一个不允许回滚却失败的交易原因
+func (b *Bundle) revertReason() string {
+	for _, res := range b.Simulation.Results {
+		if res.Error == "" || slices.Contains(b.RevertingTxHashes, res.TxHash) {
+			continue
+		}
+		if res.Revert != "" {
+			return fmt.Sprintf("tx %s reverted: %s", res.TxHash.Hex(), res.Revert)
+		}
+		return fmt.Sprintf("tx %s failed: %s", res.TxHash.Hex(), res.Error)
+	}
+	return ""
+}
+
+// sendTxs 先按交易池的 pending 状态校验全部交易的 nonce 和余额，都通过后才放入交易池，
+// 避免 bundle 只有前几笔进入交易池、被单独打包
+func sendTxs(ctx context.Context, client simulated.Client, signer types.Signer, txs types.Transactions) error {
+	nonces := make(map[common.Address]uint64)
+	balances := make(map[common.Address]*big.Int)
+	for _, tx := range txs {
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			return fmt.Errorf("tx %s: invalid sender: %w", tx.Hash().Hex(), err)
+		}
+		if _, ok := nonces[from]; !ok {
+			if nonces[from], err = client.PendingNonceAt(ctx, from); err != nil {
+				return err
+			}
+			if balances[from], err = client.PendingBalanceAt(ctx, from); err != nil {
+				return err
+			}
+		}
+		if tx.Nonce() != nonces[from] {
+			return fmt.Errorf("tx %s: nonce %d, expected %d", tx.Hash().Hex(), tx.Nonce(), nonces[from])
+		}
+		if balances[from].Cmp(tx.Cost()) < 0 {
+			return fmt.Errorf("tx %s: insufficient funds for gas * price + value", tx.Hash().Hex())
+		}
+		nonces[from]++
+		balances[from] = new(big.Int).Sub(balances[from], tx.Cost())
+	}
+	for _, tx := range txs {
+		if err := client.SendTransaction(ctx, tx); err != nil {
+			return fmt.Errorf("send tx %s: %w", tx.Hash().Hex(), err)
+		}
+	}
+	return nil
+}
+
+func decodeTxs(raw []hexutil.Bytes) (types.Transactions, error) {
+	txs := make(types.Transactions, len(raw))
+	for i, b := range raw {
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(b); err != nil {
+			return nil, fmt.Errorf("invalid transaction %d: %w", i, err)
+		}
+		txs[i] = tx
+	}
+	return txs, nil
+}
+
+// bundleHash 与 Flashbots 一致：所有交易 hash 拼接后取 keccak256
+func bundleHash(txs types.Transactions) common.Hash {
+	hashes := make([]byte, 0, len(txs)*common.HashLength)
+	for _, tx := range txs {
+		hashes = append(hashes, tx.Hash().Bytes()...)
+	}
+	return crypto.Keccak256Hash(hashes)
+}
@@ -0,0 +1,95 @@
+package mockrelay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// PrivateTx relay 收到的私密交易，每次出块都会尝试打包，直到 MaxBlockNumber
+type PrivateTx struct {
+	Tx             *types.Transaction
+	Signer         common.Address
+	MaxBlockNumber uint64
+	Status         BundleStatus
+	Error          string
+	ReceivedAt     time.Time
+	IncludedAt     time.Time
+}
+
+type sendPrivateTxArgs struct {
+	Tx             hexutil.Bytes `json:"tx"`
+	MaxBlockNumber *hexutil.Big  `json:"maxBlockNumber"`
+}
+
+type cancelPrivateTxArgs struct {
+	TxHash common.Hash `json:"txHash"`
+}
+
+// defaultPrivateBlocks 未指定 maxBlockNumber 时与 Flashbots 一致，默认 25 个块
+const defaultPrivateBlocks = 25
+
+// PrivateTx 按交易 hash 查询私密交易
+func (r *Relay) PrivateTx(hash common.Hash) (*PrivateTx, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.private[hash]
+	return p, ok
+}
+
+func (r *Relay) sendPrivateTx(signer common.Address, params json.RawMessage) (any, error) {
+	var args sendPrivateTxArgs
+	if err := decodeParam(params, &args); err != nil {
+		return nil, err
+	}
+	txs, err := decodeTxs([]hexutil.Bytes{args.Tx})
+	if err != nil {
+		return nil, err
+	}
+	head, err := r.backend.Client().BlockNumber(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	maxBlock := head + defaultPrivateBlocks
+	if args.MaxBlockNumber != nil {
+		maxBlock = args.MaxBlockNumber.ToInt().Uint64()
+	}
+	if maxBlock <= head {
+		return nil, errors.New("maxBlockNumber is not in the future")
+	}
+	p := &PrivateTx{
+		Tx:             txs[0],
+		Signer:         signer,
+		MaxBlockNumber: maxBlock,
+		Status:         BundlePending,
+		ReceivedAt:     time.Now(),
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.private[p.Tx.Hash()]; !ok {
+		r.privateOrder = append(r.privateOrder, p.Tx.Hash())
+	}
+	r.private[p.Tx.Hash()] = p
+	return p.Tx.Hash(), nil
+}
+
+func (r *Relay) cancelPrivateTx(signer common.Address, params json.RawMessage) (any, error) {
+	var args cancelPrivateTxArgs
+	if err := decodeParam(params, &args); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.private[args.TxHash]
+	if !ok || p.Signer != signer || p.Status != BundlePending {
+		return false, nil
+	}
+	p.Status = BundleCancelled
+	p.Error = "cancelled"
+	return true, nil
+}
@@ -0,0 +1,244 @@
+package mockrelay
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Backend 模拟链后端，*simulated.Backend 满足该接口
+type Backend interface {
+	Client() simulated.Client
+	Commit() common.Hash
+}
+
+// Relay 进程内的 Flashbots relay 模拟服务，实现 http.Handler，可直接交给 httptest.NewServer 使用
+//
+// 支持 eth_sendBundle、eth_callBundle、eth_cancelBundle、eth_sendPrivateTransaction、
+// eth_cancelPrivateTransaction、mev_sendBundle 以及 flashbots_getUserStats(V2)、flashbots_getBundleStats(V2)。bundle 通过 eth_simulateV1 在模拟链上执行，
+// 调用 Relay.Commit 出块时把目标区块为下一块的 bundle 打包进链。
+type Relay struct {
+	backend Backend
+	rpc     *rpc.Client
+	signers map[common.Address]bool //允许的签名地址，为空表示任意合法签名
+
+	mu      sync.Mutex
+	bundles map[common.Hash]*Bundle
+	order   []common.Hash //按接收顺序记录 bundle
+	private map[common.Hash]*PrivateTx
+	shares  map[common.Hash]*ShareBundle
+	//私密交易和 mev_sendBundle 的接收顺序，出块时按此顺序打包
+	privateOrder []common.Hash
+	shareOrder   []common.Hash
+	users        map[common.Address]*userStats
+}
+
+// New 创建 relay，signers 为空时接受任意能通过校验的签名
+func New(backend Backend, signers ...common.Address) *Relay {
+	r := &Relay{
+		backend: backend,
+		signers: make(map[common.Address]bool),
+		bundles: make(map[common.Hash]*Bundle),
+		private: make(map[common.Hash]*PrivateTx),
+		shares:  make(map[common.Hash]*ShareBundle),
+		users:   make(map[common.Address]*userStats),
+	}
+	r.rpc = rpcClient(backend.Client())
+	for _, signer := range signers {
+		r.signers[signer] = true
+	}
+	return r
+}
+
+type jsonrpcRequest struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+type jsonrpcResponse struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *jsonrpcError   `json:"error,omitempty"`
+}
+
+type jsonrpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *jsonrpcError) Error() string { return e.Message }
+
+var (
+	errMethodNotFound = &jsonrpcError{Code: -32601, Message: "method not found"}
+	errInvalidParams  = &jsonrpcError{Code: -32602, Message: "invalid params"}
+)
+
+// ServeHTTP 处理 JSON-RPC 请求（含批量请求），所有请求都必须携带合法的 X-Flashbots-Signature
+func (r *Relay) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	signer, err := r.verifySignature(req.Header.Get("X-Flashbots-Signature"), body)
+	if err != nil {
+		writeJSON(w, http.StatusForbidden, jsonrpcResponse{
+			Version: "2.0",
+			ID:      json.RawMessage("null"),
+			Error:   &jsonrpcError{Code: -32600, Message: err.Error()},
+		})
+		return
+	}
+
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var reqs []jsonrpcRequest
+		if err := json.Unmarshal(body, &reqs); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resps := make([]jsonrpcResponse, len(reqs))
+		for i, rpcReq := range reqs {
+			resps[i] = r.dispatch(signer, rpcReq)
+		}
+		writeJSON(w, http.StatusOK, resps)
+		return
+	}
+	var rpcReq jsonrpcRequest
+	if err := json.Unmarshal(body, &rpcReq); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, r.dispatch(signer, rpcReq))
+}
+
+func (r *Relay) dispatch(signer common.Address, req jsonrpcRequest) jsonrpcResponse {
+	resp := jsonrpcResponse{Version: "2.0", ID: req.ID}
+	var (
+		result any
+		err    error
+	)
+	switch req.Method {
+	case "eth_sendBundle":
+		result, err = r.sendBundle(signer, req.Params)
+	case "eth_callBundle":
+		result, err = r.callBundle(signer, req.Params)
+	case "eth_cancelBundle":
+		result, err = r.cancelBundle(signer, req.Params)
+	case "eth_sendPrivateTransaction":
+		result, err = r.sendPrivateTx(signer, req.Params)
+	case "eth_cancelPrivateTransaction":
+		result, err = r.cancelPrivateTx(signer, req.Params)
+	case "mev_sendBundle":
+		result, err = r.mevSendBundle(signer, req.Params)
+	case "flashbots_getUserStats":
+		result, err = r.userStats(signer, false)
+	case "flashbots_getUserStatsV2":
+		result, err = r.userStats(signer, true)
+	case "flashbots_getBundleStats":
+		result, err = r.bundleStats(signer, req.Params, false)
+	case "flashbots_getBundleStatsV2":
+		result, err = r.bundleStats(signer, req.Params, true)
+	default:
+		err = errMethodNotFound
+	}
+	if err != nil {
+		var rpcErr *jsonrpcError
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &jsonrpcError{Code: -32000, Message: err.Error()}
+		}
+		resp.Error = rpcErr
+		return resp
+	}
+	if result == nil {
+		result = json.RawMessage("null")
+	}
+	resp.Result = result
+	return resp
+}
+
+// verifySignature 校验 X-Flashbots-Signature 头，格式为 "地址:签名"，
+// 签名内容为 personal_sign(hex(keccak256(body)))，返回签名地址
+func (r *Relay) verifySignature(header string, body []byte) (common.Address, error) {
+	addrHex, sigHex, ok := strings.Cut(header, ":")
+	if !ok || !common.IsHexAddress(addrHex) {
+		return common.Address{}, errors.New("missing or malformed X-Flashbots-Signature header")
+	}
+	sig, err := hexutil.Decode(sigHex)
+	if err != nil || len(sig) != crypto.SignatureLength {
+		return common.Address{}, errors.New("invalid signature encoding")
+	}
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	hash := accounts.TextHash([]byte(hexutil.Encode(crypto.Keccak256(body))))
+	pub, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("signature recovery failed: %w", err)
+	}
+	signer := common.HexToAddress(addrHex)
+	if crypto.PubkeyToAddress(*pub) != signer {
+		return common.Address{}, errors.New("signer address does not match signature")
+	}
+	if len(r.signers) > 0 && !r.signers[signer] {
+		return common.Address{}, fmt.Errorf("signer %s is not allowed", signer.Hex())
+	}
+	return signer, nil
+}
+
+// rpcClient 取出后端的底层 rpc 连接用于 eth_simulateV1。
+// simulated.Backend 返回的 client 用同名字段屏蔽了 ethclient.Client.Client 方法，只能通过反射读取
+func rpcClient(c simulated.Client) *rpc.Client {
+	if c, ok := c.(interface{ Client() *rpc.Client }); ok {
+		return c.Client()
+	}
+	v := reflect.Indirect(reflect.ValueOf(c))
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	if f := v.FieldByName("Client"); f.IsValid() && f.CanInterface() {
+		if ec, ok := f.Interface().(*ethclient.Client); ok && ec != nil {
+			return ec.Client()
+		}
+	}
+	return nil
+}
+
+// decodeParam 解析只有一个对象参数的 params
+func decodeParam(params json.RawMessage, v any) error {
+	var args []json.RawMessage
+	if err := json.Unmarshal(params, &args); err != nil || len(args) != 1 {
+		return errInvalidParams
+	}
+	if err := json.Unmarshal(args[0], v); err != nil {
+		return &jsonrpcError{Code: -32602, Message: "invalid params: " + err.Error()}
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
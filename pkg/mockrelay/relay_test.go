@@ -0,0 +1,253 @@
+package mockrelay
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/lmittmann/flashbots"
+	"github.com/lmittmann/w3"
+)
+
+var recipient = common.HexToAddress("0xeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee")
+
+type testEnv struct {
+	sim    *simulated.Backend
+	relay  *Relay
+	client *w3.Client
+	key    *ecdsa.PrivateKey
+	other  *ecdsa.PrivateKey
+	signer types.Signer
+}
+
+// newTestEnv 启动模拟链和 relay，relay 通过 httptest 对外提供服务
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	key, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	authKey, _ := crypto.GenerateKey()
+	balance := new(big.Int).Mul(big.NewInt(100), big.NewInt(params.Ether))
+	sim := simulated.NewBackend(types.GenesisAlloc{
+		crypto.PubkeyToAddress(key.PublicKey):   {Balance: balance},
+		crypto.PubkeyToAddress(other.PublicKey): {Balance: balance},
+	})
+	t.Cleanup(func() { sim.Close() })
+
+	relay := New(sim, crypto.PubkeyToAddress(authKey.PublicKey))
+	srv := httptest.NewServer(relay)
+	t.Cleanup(srv.Close)
+	client, err := flashbots.Dial(srv.URL, authKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	chainID, err := sim.Client().ChainID(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testEnv{sim: sim, relay: relay, client: client, key: key, other: other, signer: types.LatestSignerForChainID(chainID)}
+}
+
+// transfer 签名一笔向 recipient 转账的交易
+func (e *testEnv) transfer(t *testing.T, nonce uint64, value *big.Int) *types.Transaction {
+	t.Helper()
+	return e.transferFrom(t, e.key, nonce, value, big.NewInt(params.GWei))
+}
+
+func (e *testEnv) transferFrom(t *testing.T, key *ecdsa.PrivateKey, nonce uint64, value, tip *big.Int) *types.Transaction {
+	t.Helper()
+	tx, err := types.SignNewTx(key, e.signer, &types.DynamicFeeTx{
+		Nonce:     nonce,
+		GasTipCap: tip,
+		GasFeeCap: big.NewInt(100 * params.GWei),
+		Gas:       21000,
+		To:        &recipient,
+		Value:     value,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func (e *testEnv) next(t *testing.T) *big.Int {
+	t.Helper()
+	head, err := e.sim.Client().BlockNumber(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return new(big.Int).SetUint64(head + 1)
+}
+
+func (e *testEnv) commit(t *testing.T) common.Hash {
+	t.Helper()
+	hash, err := e.relay.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func (e *testEnv) sendBundle(t *testing.T, req *flashbots.SendBundleRequest) common.Hash {
+	t.Helper()
+	var hash common.Hash
+	if err := e.client.Call(flashbots.SendBundle(req).Returns(&hash)); err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func (e *testEnv) bundle(t *testing.T, hash common.Hash) *Bundle {
+	t.Helper()
+	b, ok := e.relay.Bundle(hash)
+	if !ok {
+		t.Fatalf("bundle %s not found", hash.Hex())
+	}
+	return b
+}
+
+func TestBundleIncluded(t *testing.T) {
+	e := newTestEnv(t)
+	txs := types.Transactions{e.transfer(t, 0, big.NewInt(1)), e.transfer(t, 1, big.NewInt(2))}
+	hash := e.sendBundle(t, &flashbots.SendBundleRequest{Transactions: txs, BlockNumber: e.next(t)})
+
+	blockHash := e.commit(t)
+	if b := e.bundle(t, hash); b.Status != BundleIncluded {
+		t.Fatalf("status = %s (%s), want included", b.Status, b.Error)
+	}
+	for _, tx := range txs {
+		receipt, err := e.sim.Client().TransactionReceipt(context.Background(), tx.Hash())
+		if err != nil || receipt.BlockHash != blockHash {
+			t.Errorf("tx %s not in block %s: %v", tx.Hash().Hex(), blockHash.Hex(), err)
+		}
+	}
+}
+
+func TestBundleAtomic(t *testing.T) {
+	e := newTestEnv(t)
+	// 第二笔 nonce 不连续，整个 bundle 都不能进入交易池
+	first := e.transfer(t, 0, big.NewInt(1))
+	hash := e.sendBundle(t, &flashbots.SendBundleRequest{
+		Transactions: types.Transactions{first, e.transfer(t, 2, big.NewInt(1))},
+		BlockNumber:  e.next(t),
+	})
+
+	e.commit(t)
+	if b := e.bundle(t, hash); b.Status != BundleFailed {
+		t.Fatalf("status = %s, want failed", b.Status)
+	}
+	if _, err := e.sim.Client().TransactionReceipt(context.Background(), first.Hash()); err == nil {
+		t.Error("first tx of failed bundle was included")
+	}
+	if n, _ := e.sim.Client().PendingNonceAt(context.Background(), crypto.PubkeyToAddress(e.key.PublicKey)); n != 0 {
+		t.Errorf("pending nonce = %d, want 0", n)
+	}
+}
+
+func TestBundleOrder(t *testing.T) {
+	e := newTestEnv(t)
+	// 矿工按小费排序，后一笔小费更高时会被排在前面，bundle 顺序被打乱
+	hash := e.sendBundle(t, &flashbots.SendBundleRequest{
+		Transactions: types.Transactions{
+			e.transferFrom(t, e.key, 0, big.NewInt(1), big.NewInt(params.GWei)),
+			e.transferFrom(t, e.other, 0, big.NewInt(1), big.NewInt(10*params.GWei)),
+		},
+		BlockNumber: e.next(t),
+	})
+
+	e.commit(t)
+	if b := e.bundle(t, hash); b.Status != BundleFailed || !strings.Contains(b.Error, "index") {
+		t.Fatalf("status = %s (%s), want failed for out-of-order txs", b.Status, b.Error)
+	}
+}
+
+func TestBundleTimestamp(t *testing.T) {
+	e := newTestEnv(t)
+	e.sim.Commit() // 创世块时间戳为 0，先出一块
+	head, err := e.sim.Client().HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 新块时间戳至少为父块 +1，只能晚于父块的 bundle 才能上链
+	early := e.sendBundle(t, &flashbots.SendBundleRequest{
+		Transactions: types.Transactions{e.transfer(t, 0, big.NewInt(1))},
+		BlockNumber:  e.next(t),
+		MaxTimestamp: head.Time,
+	})
+	late := e.sendBundle(t, &flashbots.SendBundleRequest{
+		Transactions: types.Transactions{e.transfer(t, 0, big.NewInt(2))},
+		BlockNumber:  e.next(t),
+		MinTimestamp: head.Time + 3600,
+	})
+	ok := e.sendBundle(t, &flashbots.SendBundleRequest{
+		Transactions: types.Transactions{e.transfer(t, 0, big.NewInt(3))},
+		BlockNumber:  e.next(t),
+		MinTimestamp: head.Time + 1,
+	})
+
+	e.commit(t)
+	for _, tt := range []struct {
+		name string
+		hash common.Hash
+		want BundleStatus
+	}{
+		{"maxTimestamp before block", early, BundleFailed},
+		{"minTimestamp after block", late, BundleFailed},
+		{"minTimestamp at block", ok, BundleIncluded},
+	} {
+		if b := e.bundle(t, tt.hash); b.Status != tt.want {
+			t.Errorf("%s: status = %s (%s), want %s", tt.name, b.Status, b.Error, tt.want)
+		}
+	}
+}
+
+func TestPrivateTxOrder(t *testing.T) {
+	e := newTestEnv(t)
+	// 同一账户连续 nonce 的私密交易必须按接收顺序放入交易池才能在同一块内上链
+	var txs types.Transactions
+	for nonce := range uint64(8) {
+		tx := e.transfer(t, nonce, big.NewInt(1))
+		var hash common.Hash
+		if err := e.client.Call(flashbots.SendPrivateTx(&flashbots.SendPrivateTxRequest{Tx: tx}).Returns(&hash)); err != nil {
+			t.Fatal(err)
+		}
+		txs = append(txs, tx)
+	}
+
+	e.commit(t)
+	for _, tx := range txs {
+		if p, _ := e.relay.PrivateTx(tx.Hash()); p.Status != BundleIncluded {
+			t.Errorf("tx nonce %d: status = %s (%s), want included", tx.Nonce(), p.Status, p.Error)
+		}
+	}
+}
+
+func TestInvalidSignature(t *testing.T) {
+	e := newTestEnv(t)
+	srv := httptest.NewServer(e.relay)
+	defer srv.Close()
+
+	body := []byte(`{"jsonrpc":"2.0","id":1,"method":"flashbots_getUserStats","params":["0x1"]}`)
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Flashbots-Signature", recipient.Hex()+":0x"+common.Bytes2Hex(make([]byte, crypto.SignatureLength)))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("status = %d, want 403", resp.StatusCode)
+	}
+}
@@ -0,0 +1,106 @@
+package mockrelay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ShareBundle relay 收到的 mev_sendBundle，Refs 引用的交易需要已在模拟链交易池中
+type ShareBundle struct {
+	Hash       common.Hash
+	Signer     common.Address
+	Refs       []common.Hash
+	Txs        types.Transactions
+	Block      uint64
+	MaxBlock   uint64
+	Status     BundleStatus
+	Error      string
+	ReceivedAt time.Time
+	IncludedAt time.Time
+}
+
+type mevSendBundleArgs struct {
+	Version   string `json:"version"`
+	Inclusion struct {
+		Block    hexutil.Uint64 `json:"block"`
+		MaxBlock hexutil.Uint64 `json:"maxBlock"`
+	} `json:"inclusion"`
+	Body []struct {
+		Hash *common.Hash  `json:"hash"`
+		Tx   hexutil.Bytes `json:"tx"`
+	} `json:"body"`
+}
+
+// ShareBundle 按 hash 查询 mev_sendBundle 提交的 bundle
+func (r *Relay) ShareBundle(hash common.Hash) (*ShareBundle, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b, ok := r.shares[hash]
+	return b, ok
+}
+
+func (r *Relay) mevSendBundle(signer common.Address, params json.RawMessage) (any, error) {
+	var args mevSendBundleArgs
+	if err := decodeParam(params, &args); err != nil {
+		return nil, err
+	}
+	if len(args.Body) == 0 {
+		return nil, errors.New("bundle has no body")
+	}
+	b := &ShareBundle{
+		Signer:     signer,
+		Block:      uint64(args.Inclusion.Block),
+		MaxBlock:   uint64(args.Inclusion.MaxBlock),
+		Status:     BundlePending,
+		ReceivedAt: time.Now(),
+	}
+	if b.MaxBlock < b.Block {
+		b.MaxBlock = b.Block
+	}
+	var hashes []byte
+	for _, body := range args.Body {
+		switch {
+		case body.Hash != nil:
+			b.Refs = append(b.Refs, *body.Hash)
+			hashes = append(hashes, body.Hash.Bytes()...)
+		case len(body.Tx) > 0:
+			txs, err := decodeTxs([]hexutil.Bytes{body.Tx})
+			if err != nil {
+				return nil, err
+			}
+			b.Txs = append(b.Txs, txs[0])
+			hashes = append(hashes, txs[0].Hash().Bytes()...)
+		default:
+			return nil, errors.New("body element needs hash or tx")
+		}
+	}
+	b.Hash = crypto.Keccak256Hash(hashes)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.shares[b.Hash]; !ok {
+		r.shareOrder = append(r.shareOrder, b.Hash)
+	}
+	r.shares[b.Hash] = b
+	return map[string]common.Hash{"bundleHash": b.Hash}, nil
+}
+
+// shareTxs 取出引用交易，只要有一笔不在交易池中就返回 false
+func (r *Relay) shareTxs(ctx context.Context, b *ShareBundle) (types.Transactions, bool) {
+	refs := make(types.Transactions, 0, len(b.Refs))
+	for _, hash := range b.Refs {
+		tx, pending, err := r.backend.Client().TransactionByHash(ctx, hash)
+		if err != nil || !pending {
+			return nil, false
+		}
+		refs = append(refs, tx)
+	}
+	return refs, true
+}
@@ -0,0 +1,219 @@
+package mockrelay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// CallBundleResponse eth_callBundle 的返回，字段与 Flashbots relay 保持一致
+type CallBundleResponse struct {
+	BundleGasPrice    string         `json:"bundleGasPrice"`
+	BundleHash        common.Hash    `json:"bundleHash"`
+	CoinbaseDiff      string         `json:"coinbaseDiff"`
+	EthSentToCoinbase string         `json:"ethSentToCoinbase"`
+	GasFees           string         `json:"gasFees"`
+	Results           []CallBundleTx `json:"results"`
+	StateBlockNumber  uint64         `json:"stateBlockNumber"`
+	TotalGasUsed      uint64         `json:"totalGasUsed"`
+}
+
+// CallBundleTx bundle 中单笔交易的模拟结果
+type CallBundleTx struct {
+	CoinbaseDiff      string          `json:"coinbaseDiff"`
+	EthSentToCoinbase string          `json:"ethSentToCoinbase"`
+	FromAddress       common.Address  `json:"fromAddress"`
+	GasFees           string          `json:"gasFees"`
+	GasPrice          string          `json:"gasPrice"`
+	GasUsed           uint64          `json:"gasUsed"`
+	ToAddress         *common.Address `json:"toAddress"`
+	TxHash            common.Hash     `json:"txHash"`
+	Value             hexutil.Bytes   `json:"value"`
+	Error             string          `json:"error,omitempty"`
+	Revert            string          `json:"revert,omitempty"`
+}
+
+type callBundleArgs struct {
+	Txs              []hexutil.Bytes `json:"txs"`
+	BlockNumber      *hexutil.Big    `json:"blockNumber"`
+	StateBlockNumber string          `json:"stateBlockNumber"`
+	Timestamp        uint64          `json:"timestamp,omitempty"`
+}
+
+func (r *Relay) callBundle(signer common.Address, params json.RawMessage) (any, error) {
+	var args callBundleArgs
+	if err := decodeParam(params, &args); err != nil {
+		return nil, err
+	}
+	if len(args.Txs) == 0 {
+		return nil, errors.New("bundle has no transactions")
+	}
+	txs, err := decodeTxs(args.Txs)
+	if err != nil {
+		return nil, err
+	}
+	stateBlock := args.StateBlockNumber
+	if stateBlock == "" {
+		stateBlock = "latest"
+	}
+	var number *big.Int
+	if args.BlockNumber != nil {
+		number = args.BlockNumber.ToInt()
+	}
+	res, err := r.simulate(context.Background(), stateBlock, number, args.Timestamp, txs)
+	if err != nil {
+		return nil, err
+	}
+	res.BundleHash = bundleHash(txs)
+	res.sum()
+
+	r.mu.Lock()
+	r.user(signer).record(res)
+	r.mu.Unlock()
+	return res, nil
+}
+
+type simCallResult struct {
+	ReturnData hexutil.Bytes  `json:"returnData"`
+	GasUsed    hexutil.Uint64 `json:"gasUsed"`
+	Status     hexutil.Uint64 `json:"status"`
+	Error      *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type simBlockResult struct {
+	Number  hexutil.Uint64  `json:"number"`
+	BaseFee *hexutil.Big    `json:"baseFeePerGas"`
+	Calls   []simCallResult `json:"calls"`
+}
+
+// simulate 用 eth_simulateV1 在 stateBlock 状态之上顺序执行交易，开启 nonce、余额、base fee 校验
+func (r *Relay) simulate(ctx context.Context, stateBlock string, number *big.Int, timestamp uint64, txs types.Transactions) (*CallBundleResponse, error) {
+	if r.rpc == nil {
+		return nil, errors.New("backend does not expose an rpc client")
+	}
+	chainID, err := r.backend.Client().ChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	signer := types.LatestSignerForChainID(chainID)
+	calls := make([]map[string]any, len(txs))
+	froms := make([]common.Address, len(txs))
+	for i, tx := range txs {
+		if froms[i], err = types.Sender(signer, tx); err != nil {
+			return nil, fmt.Errorf("tx %d: invalid sender: %w", i, err)
+		}
+		calls[i] = callArgs(froms[i], tx)
+	}
+	overrides := make(map[string]any)
+	if number != nil {
+		overrides["number"] = (*hexutil.Big)(number)
+	}
+	if timestamp != 0 {
+		overrides["time"] = hexutil.Uint64(timestamp)
+	}
+	opts := map[string]any{
+		"blockStateCalls": []map[string]any{{"blockOverrides": overrides, "calls": calls}},
+		"validation":      true,
+	}
+
+	var blocks []simBlockResult
+	if err := r.rpc.CallContext(ctx, &blocks, "eth_simulateV1", opts, stateBlock); err != nil {
+		return nil, err
+	}
+	if len(blocks) == 0 {
+		return nil, errors.New("empty simulation result")
+	}
+	//目标区块在 state 之后多个块时，simulateV1 会补齐中间的空块，交易在最后一块
+	block := blocks[len(blocks)-1]
+	if len(block.Calls) != len(txs) {
+		return nil, fmt.Errorf("simulation returned %d results for %d txs", len(block.Calls), len(txs))
+	}
+	res := &CallBundleResponse{StateBlockNumber: uint64(blocks[0].Number) - 1}
+	baseFee := new(big.Int)
+	if block.BaseFee != nil {
+		baseFee = block.BaseFee.ToInt()
+	}
+	for i, call := range block.Calls {
+		tx := txs[i]
+		tip, err := tx.EffectiveGasTip(baseFee)
+		if err != nil {
+			tip = new(big.Int)
+		}
+		fee := new(big.Int).Mul(tip, new(big.Int).SetUint64(uint64(call.GasUsed)))
+		result := CallBundleTx{
+			CoinbaseDiff:      fee.String(),
+			EthSentToCoinbase: "0",
+			FromAddress:       froms[i],
+			GasFees:           fee.String(),
+			GasPrice:          tip.String(),
+			GasUsed:           uint64(call.GasUsed),
+			ToAddress:         tx.To(),
+			TxHash:            tx.Hash(),
+			Value:             call.ReturnData,
+		}
+		if uint64(call.Status) == types.ReceiptStatusFailed {
+			result.Error = "execution reverted"
+			if call.Error != nil && call.Error.Message != "" {
+				result.Error = call.Error.Message
+			}
+			if reason, err := abi.UnpackRevert(call.ReturnData); err == nil {
+				result.Revert = reason
+			}
+		}
+		res.Results = append(res.Results, result)
+	}
+	return res, nil
+}
+
+// sum 汇总 Results 得到 bundle 级别的 gas 和 coinbase 收益，直接转给 coinbase 的 ETH 不做追踪
+func (res *CallBundleResponse) sum() {
+	var (
+		totalFees = new(big.Int)
+		totalGas  uint64
+	)
+	for _, tx := range res.Results {
+		fee, _ := new(big.Int).SetString(tx.GasFees, 10)
+		if fee != nil {
+			totalFees.Add(totalFees, fee)
+		}
+		totalGas += tx.GasUsed
+	}
+	res.TotalGasUsed = totalGas
+	res.GasFees = totalFees.String()
+	res.CoinbaseDiff = totalFees.String()
+	res.EthSentToCoinbase = "0"
+	res.BundleGasPrice = "0"
+	if totalGas > 0 {
+		res.BundleGasPrice = new(big.Int).Div(totalFees, new(big.Int).SetUint64(totalGas)).String()
+	}
+}
+
+// callArgs 把已签名交易还原成 eth_simulateV1 的调用参数
+func callArgs(from common.Address, tx *types.Transaction) map[string]any {
+	args := map[string]any{
+		"from":  from,
+		"to":    tx.To(),
+		"gas":   hexutil.Uint64(tx.Gas()),
+		"value": (*hexutil.Big)(tx.Value()),
+		"input": hexutil.Bytes(tx.Data()),
+		"nonce": hexutil.Uint64(tx.Nonce()),
+	}
+	if tx.Type() == types.LegacyTxType {
+		args["gasPrice"] = (*hexutil.Big)(tx.GasPrice())
+	} else {
+		args["maxFeePerGas"] = (*hexutil.Big)(tx.GasFeeCap())
+		args["maxPriorityFeePerGas"] = (*hexutil.Big)(tx.GasTipCap())
+		args["accessList"] = tx.AccessList()
+	}
+	return args
+}
@@ -0,0 +1,118 @@
+package mockrelay
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// userStats 按签名地址统计，模拟环境不区分时间窗口
+type userStats struct {
+	bundles      int
+	gasSimulated *big.Int
+	payments     *big.Int
+}
+
+func (r *Relay) user(signer common.Address) *userStats {
+	u, ok := r.users[signer]
+	if !ok {
+		u = &userStats{gasSimulated: new(big.Int), payments: new(big.Int)}
+		r.users[signer] = u
+	}
+	return u
+}
+
+func (u *userStats) record(res *CallBundleResponse) {
+	u.gasSimulated.Add(u.gasSimulated, new(big.Int).SetUint64(res.TotalGasUsed))
+	if diff, ok := new(big.Int).SetString(res.CoinbaseDiff, 10); ok {
+		u.payments.Add(u.payments, diff)
+	}
+}
+
+func (r *Relay) userStats(signer common.Address, v2 bool) (any, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u := r.user(signer)
+	gas, pay := u.gasSimulated.String(), u.payments.String()
+	if v2 {
+		return map[string]any{
+			"isHighPriority":           false,
+			"allTimeValidatorPayments": pay,
+			"allTimeGasSimulated":      gas,
+			"last7dValidatorPayments":  pay,
+			"last7dGasSimulated":       gas,
+			"last1dValidatorPayments":  pay,
+			"last1dGasSimulated":       gas,
+		}, nil
+	}
+	return map[string]any{
+		"is_high_priority":        false,
+		"all_time_miner_payments": pay,
+		"all_time_gas_simulated":  gas,
+		"last_7d_miner_payments":  pay,
+		"last_7d_gas_simulated":   gas,
+		"last_1d_miner_payments":  pay,
+		"last_1d_gas_simulated":   gas,
+	}, nil
+}
+
+type bundleStatsArgs struct {
+	BundleHash  common.Hash  `json:"bundleHash"`
+	BlockNumber *hexutil.Big `json:"blockNumber"`
+}
+
+type builderTimestamp struct {
+	Pubkey    string    `json:"pubkey"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// mockBuilder 模拟 relay 只有一个 builder
+const mockBuilder = "0xmockbuilder"
+
+func (r *Relay) bundleStats(signer common.Address, params json.RawMessage, v2 bool) (any, error) {
+	var args bundleStatsArgs
+	if err := decodeParam(params, &args); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b, ok := r.bundles[args.BundleHash]
+	if !ok || b.Signer != signer {
+		return nil, errors.New("bundle not found")
+	}
+	simulated := !b.SimulatedAt.IsZero()
+	if v2 {
+		stats := map[string]any{
+			"isHighPriority":         false,
+			"isSimulated":            simulated,
+			"receivedAt":             b.ReceivedAt,
+			"consideredByBuildersAt": []builderTimestamp{},
+			"sealedByBuildersAt":     []builderTimestamp{},
+		}
+		if simulated {
+			stats["simulatedAt"] = b.SimulatedAt
+			stats["consideredByBuildersAt"] = []builderTimestamp{{mockBuilder, b.SimulatedAt}}
+		}
+		if b.Status == BundleIncluded {
+			stats["sealedByBuildersAt"] = []builderTimestamp{{mockBuilder, b.IncludedAt}}
+		}
+		return stats, nil
+	}
+	stats := map[string]any{
+		"isSimulated":    simulated,
+		"isSentToMiners": b.Status == BundleIncluded,
+		"isHighPriority": false,
+		"submittedAt":    b.ReceivedAt,
+	}
+	if simulated {
+		stats["simulatedAt"] = b.SimulatedAt
+	}
+	if b.Status == BundleIncluded {
+		stats["sentToMinersAt"] = b.IncludedAt
+	}
+	return stats, nil
+}
@@ -0,0 +1,245 @@
+package multicall
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/lmittmann/w3"
+)
+
+// Address Multicall3 在绝大多数 EVM 链上的部署地址
+var Address = common.HexToAddress("0xcA11bde05977b3631167028862bE2a173976CA11")
+
+// ErrReverted 调用失败
+var ErrReverted = errors.New("call reverted")
+
+var (
+	aggregate3 = w3.MustNewFunc(
+		"aggregate3((address target, bool allowFailure, bytes callData)[] calls)",
+		"(bool success, bytes returnData)[] returnData",
+	)
+	getEthBalance = w3.MustNewFunc("getEthBalance(address addr)", "uint256 balance")
+)
+
+type call3 struct {
+	Target       common.Address
+	AllowFailure bool
+	CallData     []byte
+}
+
+type result3 struct {
+	Success    bool
+	ReturnData []byte
+}
+
+// RPC 需要的方法，*rpc.Client 满足
+type RPC interface {
+	CallContext(ctx context.Context, result any, method string, args ...any) error
+	BatchCallContext(ctx context.Context, b []rpc.BatchElem) error
+}
+
+// Call 一次只读调用，执行后 Output、Err 被填充，设置了 Returns 时按 Func 解码到其中
+type Call struct {
+	Target       common.Address
+	Data         []byte
+	AllowFailure bool // 为 false 时这次调用失败会让整批返回错误
+
+	Func    *w3.Func
+	returns []any
+
+	Output []byte
+	Err    error // 回滚（ErrReverted，带原因）或解码失败
+}
+
+// NewCall 按 w3.Func 编码调用，参数编码失败时 Err 被设置、执行时跳过
+func NewCall(target common.Address, fn *w3.Func, args ...any) *Call {
+	c := &Call{Target: target, Func: fn}
+	c.Data, c.Err = fn.EncodeArgs(args...)
+	return c
+}
+
+// Returns 设置解码目标（指针），顺序与函数返回值一致
+func (c *Call) Returns(returns ...any) *Call {
+	c.returns = returns
+	return c
+}
+
+// Optional 允许这次调用失败
+func (c *Call) Optional() *Call {
+	c.AllowFailure = true
+	return c
+}
+
+// Caller 把调用合并成 Multicall3 aggregate3，链上没有 Multicall3 时改用 JSON-RPC 批量 eth_call
+type Caller struct {
+	client RPC
+	// Address Multicall3 合约地址
+	Address common.Address
+	// Block 为 nil 时调用最新区块
+	Block *big.Int
+	// BatchSize 一次 aggregate3 的调用数，过大时会超过节点的 eth_call gas 上限
+	BatchSize int
+	// RPCBatchSize 一次 JSON-RPC 批量请求的请求数
+	RPCBatchSize int
+
+	mu       sync.Mutex
+	probed   bool //只缓存成功的检查，节点错误时下次重新检查
+	deployed bool
+}
+
+func New(client RPC) *Caller {
+	return &Caller{client: client, Address: Address, BatchSize: 500, RPCBatchSize: 100}
+}
+
+// Deployed Multicall3 是否已部署，检查成功后不再重复检查
+func (c *Caller) Deployed(ctx context.Context) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.probed {
+		return c.deployed, nil
+	}
+	var code hexutil.Bytes
+	if err := c.client.CallContext(ctx, &code, "eth_getCode", c.Address, blockArg(c.Block)); err != nil {
+		return false, err
+	}
+	c.probed, c.deployed = true, len(code) > 0
+	return c.deployed, nil
+}
+
+// Do 执行调用：每 BatchSize 个合并成一次 aggregate3，没有 Multicall3 时每 RPCBatchSize 个合并成一次批量请求。
+// 节点错误和 AllowFailure 为 false 的调用失败时返回错误，其余失败记录在各自的 Err 中
+func (c *Caller) Do(ctx context.Context, calls ...*Call) error {
+	pending := make([]*Call, 0, len(calls))
+	for _, call := range calls {
+		if call.Err == nil {
+			pending = append(pending, call)
+		} else if !call.AllowFailure {
+			return fmt.Errorf("call %s: %w", call.Target.Hex(), call.Err)
+		}
+	}
+	deployed, err := c.Deployed(ctx)
+	if err != nil {
+		return fmt.Errorf("check multicall3: %w", err)
+	}
+	if deployed {
+		for _, chunk := range chunks(pending, c.BatchSize) {
+			if err := c.aggregate(ctx, chunk); err != nil {
+				return err
+			}
+		}
+	} else {
+		for _, chunk := range chunks(pending, c.RPCBatchSize) {
+			if err := c.batch(ctx, chunk); err != nil {
+				return err
+			}
+		}
+	}
+	for _, call := range pending {
+		if call.Err == nil && call.Func != nil && len(call.returns) > 0 {
+			if err := call.Func.DecodeReturns(call.Output, call.returns...); err != nil {
+				call.Err = fmt.Errorf("decode %s: %w", call.Func.Signature, err)
+			}
+		}
+		if call.Err != nil && !call.AllowFailure {
+			return fmt.Errorf("call %s: %w", call.Target.Hex(), call.Err)
+		}
+	}
+	return nil
+}
+
+func (c *Caller) aggregate(ctx context.Context, calls []*Call) error {
+	args := make([]call3, len(calls))
+	for i, call := range calls {
+		args[i] = call3{Target: call.Target, AllowFailure: call.AllowFailure, CallData: call.Data}
+	}
+	data, err := aggregate3.EncodeArgs(args)
+	if err != nil {
+		return err
+	}
+	var out hexutil.Bytes
+	msg := map[string]any{"to": c.Address, "data": hexutil.Bytes(data)}
+	if err := c.client.CallContext(ctx, &out, "eth_call", msg, blockArg(c.Block)); err != nil {
+		return fmt.Errorf("aggregate3 of %d calls: %w", len(calls), err)
+	}
+	var results []result3
+	if err := aggregate3.DecodeReturns(out, &results); err != nil {
+		return fmt.Errorf("aggregate3: %w", err)
+	}
+	if len(results) != len(calls) {
+		return fmt.Errorf("aggregate3: %d results for %d calls", len(results), len(calls))
+	}
+	for i, r := range results {
+		calls[i].Output = r.ReturnData
+		if !r.Success {
+			calls[i].Err = revertError(r.ReturnData)
+		}
+	}
+	return nil
+}
+
+func (c *Caller) batch(ctx context.Context, calls []*Call) error {
+	elems := make([]rpc.BatchElem, len(calls))
+	outs := make([]hexutil.Bytes, len(calls))
+	for i, call := range calls {
+		elems[i] = rpc.BatchElem{
+			Method: "eth_call",
+			Args:   []any{map[string]any{"to": call.Target, "data": hexutil.Bytes(call.Data)}, blockArg(c.Block)},
+			Result: &outs[i],
+		}
+	}
+	if err := c.client.BatchCallContext(ctx, elems); err != nil {
+		return fmt.Errorf("batch of %d calls: %w", len(calls), err)
+	}
+	for i, e := range elems {
+		calls[i].Output = outs[i]
+		if e.Error != nil {
+			var data rpc.DataError
+			if errors.As(e.Error, &data) {
+				if s, ok := data.ErrorData().(string); ok {
+					calls[i].Output = common.FromHex(s)
+				}
+			}
+			calls[i].Err = fmt.Errorf("%w: %v", ErrReverted, e.Error)
+		}
+	}
+	return nil
+}
+
+// revertError 解析 Error(string) 回滚原因
+func revertError(data []byte) error {
+	if reason, err := abi.UnpackRevert(data); err == nil {
+		return fmt.Errorf("%w: %s", ErrReverted, reason)
+	}
+	if len(data) > 0 {
+		return fmt.Errorf("%w: %x", ErrReverted, data)
+	}
+	return ErrReverted
+}
+
+func blockArg(block *big.Int) string {
+	if block == nil {
+		return "latest"
+	}
+	return hexutil.EncodeBig(block)
+}
+
+// chunks 按 size 切分，size 不大于 0 时不切分
+func chunks[T any](s []T, size int) [][]T {
+	if size <= 0 {
+		size = max(len(s), 1)
+	}
+	var out [][]T
+	for len(s) > 0 {
+		n := min(size, len(s))
+		out = append(out, s[:n])
+		s = s[n:]
+	}
+	return out
+}
@@ -0,0 +1,152 @@
+package multicall
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/lmittmann/w3"
+)
+
+var (
+	funcName        = w3.MustNewFunc("name()", "string")
+	funcSymbol      = w3.MustNewFunc("symbol()", "string")
+	funcDecimals    = w3.MustNewFunc("decimals()", "uint8")
+	funcTotalSupply = w3.MustNewFunc("totalSupply()", "uint256")
+	funcBalanceOf   = w3.MustNewFunc("balanceOf(address)", "uint256")
+)
+
+// Token ERC20 元数据，某一项读取失败时为零值，第一个错误记录在 Err
+type Token struct {
+	Address     common.Address
+	Name        string
+	Symbol      string
+	Decimals    uint8
+	TotalSupply *big.Int
+	Err         error
+}
+
+// Tokens 一次读取多个代币的 name、symbol、decimals、totalSupply；name/symbol 为 bytes32 的老代币（如 MKR）也能解析
+func (c *Caller) Tokens(ctx context.Context, tokens ...common.Address) ([]*Token, error) {
+	out := make([]*Token, len(tokens))
+	var calls []*Call
+	for i, addr := range tokens {
+		t := &Token{Address: addr, TotalSupply: new(big.Int)}
+		out[i] = t
+		calls = append(calls,
+			NewCall(addr, funcName).Optional(),
+			NewCall(addr, funcSymbol).Optional(),
+			NewCall(addr, funcDecimals).Returns(&t.Decimals).Optional(),
+			NewCall(addr, funcTotalSupply).Returns(t.TotalSupply).Optional(),
+		)
+	}
+	if err := c.Do(ctx, calls...); err != nil {
+		return nil, err
+	}
+	for i, t := range out {
+		group := calls[i*4 : i*4+4]
+		t.Name, t.Symbol = text(group[0]), text(group[1])
+		for _, call := range group {
+			if call.Err != nil && t.Err == nil {
+				t.Err = call.Err
+			}
+		}
+	}
+	return out, nil
+}
+
+// text 按 string 解码，不行时按 bytes32 去掉末尾的零
+func text(call *Call) string {
+	if call.Err != nil {
+		return ""
+	}
+	var s string
+	if err := call.Func.DecodeReturns(call.Output, &s); err == nil {
+		return s
+	}
+	if len(call.Output) == 32 {
+		call.Err = nil
+		return string(bytes.TrimRight(call.Output, "\x00"))
+	}
+	call.Err = fmt.Errorf("decode %s: unexpected output %x", call.Func.Signature, call.Output)
+	return ""
+}
+
+// Balances 一批地址的代币余额；token 为零地址时查 ETH 余额（Multicall3 的 getEthBalance，没有时批量 eth_getBalance）
+func (c *Caller) Balances(ctx context.Context, token common.Address, holders ...common.Address) ([]*big.Int, error) {
+	balances := make([]*big.Int, len(holders))
+	for i := range balances {
+		balances[i] = new(big.Int)
+	}
+	if token == (common.Address{}) {
+		deployed, err := c.Deployed(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("check multicall3: %w", err)
+		}
+		if !deployed {
+			return balances, c.ethBalances(ctx, holders, balances)
+		}
+	}
+	calls := make([]*Call, len(holders))
+	for i, h := range holders {
+		if token == (common.Address{}) {
+			calls[i] = NewCall(c.Address, getEthBalance, h).Returns(balances[i])
+		} else {
+			calls[i] = NewCall(token, funcBalanceOf, h).Returns(balances[i])
+		}
+	}
+	return balances, c.Do(ctx, calls...)
+}
+
+func (c *Caller) ethBalances(ctx context.Context, holders []common.Address, balances []*big.Int) error {
+	offset := 0
+	for _, chunk := range chunks(holders, c.RPCBatchSize) {
+		elems := make([]rpc.BatchElem, len(chunk))
+		results := make([]hexutil.Big, len(chunk))
+		for i, h := range chunk {
+			elems[i] = rpc.BatchElem{Method: "eth_getBalance", Args: []any{h, blockArg(c.Block)}, Result: &results[i]}
+		}
+		if err := c.batchElems(ctx, elems); err != nil {
+			return err
+		}
+		for i := range chunk {
+			balances[offset+i].Set(results[i].ToInt())
+		}
+		offset += len(chunk)
+	}
+	return nil
+}
+
+// Storage 用 JSON-RPC 批量请求读取一个合约的多个存储槽
+func (c *Caller) Storage(ctx context.Context, account common.Address, slots []common.Hash) ([]common.Hash, error) {
+	values := make([]common.Hash, 0, len(slots))
+	for _, chunk := range chunks(slots, c.RPCBatchSize) {
+		elems := make([]rpc.BatchElem, len(chunk))
+		results := make([]common.Hash, len(chunk))
+		for i, s := range chunk {
+			elems[i] = rpc.BatchElem{Method: "eth_getStorageAt", Args: []any{account, s, blockArg(c.Block)}, Result: &results[i]}
+		}
+		if err := c.batchElems(ctx, elems); err != nil {
+			return nil, err
+		}
+		values = append(values, results...)
+	}
+	return values, nil
+}
+
+// batchElems 执行批量请求，任何一个请求出错都返回错误
+func (c *Caller) batchElems(ctx context.Context, elems []rpc.BatchElem) error {
+	if err := c.client.BatchCallContext(ctx, elems); err != nil {
+		return err
+	}
+	for _, e := range elems {
+		if e.Error != nil {
+			return fmt.Errorf("%s %v: %w", e.Method, e.Args[0], e.Error)
+		}
+	}
+	return nil
+}
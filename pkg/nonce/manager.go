@@ -0,0 +1,252 @@
+package nonce
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"slices"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/lmittmann/w3"
+	"github.com/lmittmann/w3/module/eth"
+)
+
+// pending eth_getTransactionCount 的 pending 块参数
+var pending = big.NewInt(-1)
+
+// Manager 按账户在本地分配 nonce，同一进程内并发的 bundle、私密交易共用一个 Manager 才不会拿到相同 nonce
+type Manager struct {
+	client *w3.Client
+
+	mu       sync.Mutex
+	accounts map[common.Address]*account
+}
+
+type account struct {
+	synced    bool
+	confirmed uint64                  // 链上 latest nonce
+	pending   uint64                  // 节点交易池 pending nonce，包含不经过 Manager 发出的交易
+	next      uint64                  // 下一个新分配的 nonce
+	reserved  map[uint64]*Reservation // 已分配未确认的 nonce
+	free      []uint64                // 释放后等待复用的 nonce，升序
+	head      uint64                  // 最近一次同步时的区块高度
+}
+
+func NewManager(client *w3.Client) *Manager {
+	return &Manager{client: client, accounts: make(map[common.Address]*account)}
+}
+
+// Reserve 为 addr 分配 n 个连续 nonce，优先复用已释放的 nonce 以免留下空洞。
+// maxBlock 为使用这些 nonce 的 bundle 的最后目标区块，同步到更高区块仍未上链时自动释放，0 表示不过期
+func (m *Manager) Reserve(ctx context.Context, addr common.Address, n int, maxBlock uint64) (*Reservation, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("nonce: invalid count %d", n)
+	}
+	m.mu.Lock()
+	acc := m.account(addr)
+	synced := acc.synced
+	m.mu.Unlock()
+	if !synced {
+		if err := m.Sync(ctx, addr, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	start := acc.take(n)
+	r := &Reservation{Account: addr, MaxBlock: maxBlock, m: m}
+	for i := 0; i < n; i++ {
+		r.Nonces = append(r.Nonces, start+uint64(i))
+		acc.reserved[start+uint64(i)] = r
+	}
+	return r, nil
+}
+
+// take 找到 n 个连续可用 nonce 的起点：最小的已释放 nonce 开始的连续段足够长时复用，否则从 next 继续分配
+func (acc *account) take(n int) uint64 {
+	if len(acc.free) > 0 {
+		start, run := acc.free[0], 1
+		for run < len(acc.free) && acc.free[run] == start+uint64(run) {
+			run++
+		}
+		//空闲段紧挨着 next 时可以向上延伸
+		if run >= n || start+uint64(run) == acc.next {
+			used := min(run, n)
+			acc.free = acc.free[used:]
+			if end := start + uint64(n); end > acc.next {
+				acc.next = end
+			}
+			return start
+		}
+	}
+	start := acc.next
+	acc.next += uint64(n)
+	return start
+}
+
+// Sync 从节点刷新 addr 的 latest/pending nonce：已上链的分配视为完成，
+// head 超过 MaxBlock 的分配自动释放，节点 pending nonce 更高时（外部发出交易）跳过被占用的 nonce
+func (m *Manager) Sync(ctx context.Context, addr common.Address, head uint64) error {
+	var confirmed, pendingNonce uint64
+	if err := m.client.CallCtx(ctx,
+		eth.Nonce(addr, nil).Returns(&confirmed),
+		eth.Nonce(addr, pending).Returns(&pendingNonce),
+	); err != nil {
+		return fmt.Errorf("nonce: sync %s: %w", addr.Hex(), err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	acc := m.account(addr)
+	acc.synced = true
+	acc.confirmed, acc.pending = confirmed, max(pendingNonce, confirmed)
+	if head > acc.head {
+		acc.head = head
+	}
+
+	for nonce, r := range acc.reserved {
+		switch {
+		case nonce < confirmed:
+			delete(acc.reserved, nonce)
+		case r.MaxBlock != 0 && acc.head > r.MaxBlock:
+			delete(acc.reserved, nonce)
+			acc.free = append(acc.free, nonce)
+		}
+	}
+	//外部交易占用的 nonce 不再复用
+	acc.free = slices.DeleteFunc(acc.free, func(nonce uint64) bool { return nonce < acc.pending })
+	if acc.next < acc.pending {
+		acc.next = acc.pending
+	}
+	acc.compact()
+	return nil
+}
+
+// SyncAll 同步所有已使用过的账户，新区块到来时调用
+func (m *Manager) SyncAll(ctx context.Context, head uint64) error {
+	for addr := range m.addrs() {
+		if err := m.Sync(ctx, addr, head); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run 每收到一个新区块头就 SyncAll，同步失败只记录日志，下一个区块重试，直到 ctx 取消或 heads 关闭
+func (m *Manager) Run(ctx context.Context, heads <-chan *types.Header) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case head, ok := <-heads:
+			if !ok {
+				return nil
+			}
+			if err := m.SyncAll(ctx, head.Number.Uint64()); err != nil {
+				log.Printf("❌ 区块 %d 同步 nonce 失败: %v", head.Number, err)
+				continue
+			}
+			for addr := range m.addrs() {
+				if gaps := m.Gaps(addr); len(gaps) > 0 {
+					log.Printf("⚠️ %s 存在 nonce 空洞: %v", addr.Hex(), gaps)
+				}
+			}
+		}
+	}
+}
+
+// Gaps 返回 addr 的 nonce 空洞：链上 pending nonce 与最高已分配 nonce 之间既未分配也未发出的 nonce，
+// 存在空洞时更高 nonce 的交易永远无法上链
+func (m *Manager) Gaps(addr common.Address) []uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	acc, ok := m.accounts[addr]
+	if !ok {
+		return nil
+	}
+	var top uint64
+	for nonce := range acc.reserved {
+		top = max(top, nonce+1)
+	}
+	var gaps []uint64
+	for nonce := acc.pending; nonce < top; nonce++ {
+		if _, ok := acc.reserved[nonce]; !ok {
+			gaps = append(gaps, nonce)
+		}
+	}
+	return gaps
+}
+
+// Next 返回 addr 下一个会分配的 nonce，不做预留
+func (m *Manager) Next(addr common.Address) (uint64, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	acc, ok := m.accounts[addr]
+	if !ok || !acc.synced {
+		return 0, false
+	}
+	if len(acc.free) > 0 {
+		return acc.free[0], true
+	}
+	return acc.next, true
+}
+
+func (m *Manager) addrs() map[common.Address]struct{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	addrs := make(map[common.Address]struct{}, len(m.accounts))
+	for addr := range m.accounts {
+		addrs[addr] = struct{}{}
+	}
+	return addrs
+}
+
+func (m *Manager) account(addr common.Address) *account {
+	acc, ok := m.accounts[addr]
+	if !ok {
+		acc = &account{reserved: make(map[uint64]*Reservation)}
+		m.accounts[addr] = acc
+	}
+	return acc
+}
+
+// compact 空闲 nonce 排序去重，紧挨 next 的空闲段直接收回
+func (acc *account) compact() {
+	slices.Sort(acc.free)
+	acc.free = slices.Compact(acc.free)
+	for len(acc.free) > 0 && acc.free[len(acc.free)-1] == acc.next-1 {
+		acc.free = acc.free[:len(acc.free)-1]
+		acc.next--
+	}
+}
+
+// Reservation 一次分配的连续 nonce
+type Reservation struct {
+	Account  common.Address
+	Nonces   []uint64
+	MaxBlock uint64
+
+	m *Manager
+}
+
+// First 第一个 nonce
+func (r *Reservation) First() uint64 { return r.Nonces[0] }
+
+// Release bundle 过期、被撤回或签名前放弃时归还 nonce，可重复调用；
+// 已被同步为上链或已被其他分配复用的 nonce 不受影响
+func (r *Reservation) Release() {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	acc := r.m.account(r.Account)
+	for _, nonce := range r.Nonces {
+		if acc.reserved[nonce] == r {
+			delete(acc.reserved, nonce)
+			acc.free = append(acc.free, nonce)
+		}
+	}
+	acc.compact()
+}
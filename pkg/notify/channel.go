@@ -0,0 +1,149 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Channel 一个通知渠道
+type Channel interface {
+	Name() string
+	Send(ctx context.Context, m *Message) error
+}
+
+// httpClient 所有渠道共用，超时避免通知卡住监听循环
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// postJSON 发送 JSON 请求，非 2xx 视为失败
+func postJSON(ctx context.Context, method, url string, headers map[string]string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s %s: %s: %s", method, redact(url), resp.Status, strings.TrimSpace(string(data)))
+	}
+	return nil
+}
+
+// redact 去掉 URL 路径，webhook 和 bot token 都在路径里，不能出现在日志中
+func redact(url string) string {
+	if i := strings.Index(url, "://"); i >= 0 {
+		if j := strings.Index(url[i+3:], "/"); j >= 0 {
+			return url[:i+3+j] + "/***"
+		}
+	}
+	return url
+}
+
+// truncate 按字符截断到平台的长度上限
+func truncate(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max-1]) + "…"
+}
+
+// Telegram 机器人发送到指定聊天
+type Telegram struct {
+	Token   string
+	ChatID  string
+	BaseURL string // 默认 https://api.telegram.org，测试时指向本地替身
+}
+
+func (t *Telegram) Name() string { return "telegram" }
+
+func (t *Telegram) Send(ctx context.Context, m *Message) error {
+	base := t.BaseURL
+	if base == "" {
+		base = "https://api.telegram.org"
+	}
+	body, err := json.Marshal(map[string]any{
+		"chat_id":                  t.ChatID,
+		"text":                     truncate(m.Text, 4096),
+		"disable_web_page_preview": true,
+	})
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, http.MethodPost, fmt.Sprintf("%s/bot%s/sendMessage", strings.TrimRight(base, "/"), t.Token), nil, body)
+}
+
+// Slack incoming webhook
+type Slack struct {
+	URL string
+}
+
+func (s *Slack) Name() string { return "slack" }
+
+func (s *Slack) Send(ctx context.Context, m *Message) error {
+	body, err := json.Marshal(map[string]string{"text": m.Text})
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, http.MethodPost, s.URL, nil, body)
+}
+
+// Discord incoming webhook
+type Discord struct {
+	URL string
+}
+
+func (d *Discord) Name() string { return "discord" }
+
+func (d *Discord) Send(ctx context.Context, m *Message) error {
+	body, err := json.Marshal(map[string]string{"content": truncate(m.Text, 2000)})
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, http.MethodPost, d.URL, nil, body)
+}
+
+// Webhook 通用 webhook，Template 以 Message 为数据渲染请求体，为空时发送 Message 的 JSON
+type Webhook struct {
+	URL      string
+	Method   string // 默认 POST
+	Headers  map[string]string
+	Template *template.Template
+}
+
+func (w *Webhook) Name() string { return "webhook" }
+
+func (w *Webhook) Send(ctx context.Context, m *Message) error {
+	method := w.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	var body []byte
+	if w.Template == nil {
+		var err error
+		if body, err = json.Marshal(m); err != nil {
+			return err
+		}
+	} else {
+		var buf bytes.Buffer
+		if err := w.Template.Execute(&buf, m); err != nil {
+			return fmt.Errorf("webhook template: %w", err)
+		}
+		body = buf.Bytes()
+	}
+	return postJSON(ctx, method, w.URL, w.Headers, body)
+}
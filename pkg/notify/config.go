@@ -0,0 +1,113 @@
+package notify
+
+import (
+	"fmt"
+	"os"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config 通知配置，config.yaml 的 notify 节点或单独的 notify.yaml
+//
+//	notify:
+//	  dedupWindow: 10m
+//	  templates:
+//	    NewIDOContract: "新 IDO 池子 {{.Address}} tx {{.TxHash}}"
+//	  channels:
+//	    - {type: telegram, token: "123:abc", chatId: "-100123", rate: {every: 3s, burst: 5}}
+//	    - {type: slack, url: "https://hooks.slack.com/services/..."}
+//	    - {type: discord, url: "https://discord.com/api/webhooks/...", events: [whale]}
+//	    - {type: webhook, url: "https://example.com/hook", headers: {Authorization: "Bearer x"}, template: '{"text": {{json .Text}}}'}
+type Config struct {
+	DedupWindow time.Duration     `mapstructure:"dedupWindow" yaml:"dedupWindow"`
+	Templates   map[string]string `mapstructure:"templates" yaml:"templates"`
+	Channels    []ChannelConfig   `mapstructure:"channels" yaml:"channels"`
+}
+
+// ChannelConfig 单个渠道
+type ChannelConfig struct {
+	Type     string            `mapstructure:"type" yaml:"type"` // telegram | slack | discord | webhook
+	Token    string            `mapstructure:"token" yaml:"token"`
+	ChatID   string            `mapstructure:"chatId" yaml:"chatId"`
+	BaseURL  string            `mapstructure:"baseUrl" yaml:"baseUrl"`
+	URL      string            `mapstructure:"url" yaml:"url"`
+	Method   string            `mapstructure:"method" yaml:"method"`
+	Headers  map[string]string `mapstructure:"headers" yaml:"headers"`
+	Template string            `mapstructure:"template" yaml:"template"` // webhook 请求体模板，数据为 Message
+	Events   []string          `mapstructure:"events" yaml:"events"`     // 只接收这些事件，为空时全部接收
+	Rate     struct {
+		Every time.Duration `mapstructure:"every" yaml:"every"`
+		Burst int           `mapstructure:"burst" yaml:"burst"`
+	} `mapstructure:"rate" yaml:"rate"`
+}
+
+// Load 读取单独的通知配置文件
+func Load(path string) (*Notifier, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Config
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	return c.Notifier()
+}
+
+// Notifier 按配置创建通知器，未配置限速的渠道默认每 3 秒一条、最多连发 5 条
+func (c Config) Notifier() (*Notifier, error) {
+	n := New()
+	if c.DedupWindow > 0 {
+		n.DedupWindow = c.DedupWindow
+	}
+	for event, text := range c.Templates {
+		if err := n.Template(event, text); err != nil {
+			return nil, err
+		}
+	}
+	for i, cc := range c.Channels {
+		ch, err := cc.channel()
+		if err != nil {
+			return nil, fmt.Errorf("channel %d: %w", i, err)
+		}
+		every, burst := cc.Rate.Every, cc.Rate.Burst
+		if every == 0 {
+			every, burst = 3*time.Second, 5
+		}
+		n.Add(ch, cc.Events, every, burst)
+	}
+	return n, nil
+}
+
+func (c ChannelConfig) channel() (Channel, error) {
+	switch c.Type {
+	case "telegram":
+		if c.Token == "" || c.ChatID == "" {
+			return nil, fmt.Errorf("telegram: token and chatId required")
+		}
+		return &Telegram{Token: c.Token, ChatID: c.ChatID, BaseURL: c.BaseURL}, nil
+	case "slack", "discord", "webhook":
+		if c.URL == "" {
+			return nil, fmt.Errorf("%s: url required", c.Type)
+		}
+	default:
+		return nil, fmt.Errorf("unknown type %q", c.Type)
+	}
+	switch c.Type {
+	case "slack":
+		return &Slack{URL: c.URL}, nil
+	case "discord":
+		return &Discord{URL: c.URL}, nil
+	}
+	w := &Webhook{URL: c.URL, Method: c.Method, Headers: c.Headers}
+	if c.Template != "" {
+		t, err := template.New("webhook").Funcs(Funcs).Parse(c.Template)
+		if err != nil {
+			return nil, fmt.Errorf("webhook template: %w", err)
+		}
+		w.Template = t
+	}
+	return w, nil
+}
@@ -0,0 +1,57 @@
+package notify
+
+import (
+	"io"
+	"net/http"
+	"sync"
+)
+
+// Request Inbox 收到的请求
+type Request struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   []byte
+}
+
+// Inbox 本地通知接收替身，实现 http.Handler，可直接交给 httptest.NewServer 使用；
+// Telegram 的 BaseURL 和各 webhook 的 URL 都可以指向它
+type Inbox struct {
+	mu       sync.Mutex
+	requests []Request
+	status   int
+}
+
+func NewInbox() *Inbox {
+	return &Inbox{status: http.StatusOK}
+}
+
+// SetStatus 设置响应状态码，用于模拟渠道故障
+func (b *Inbox) SetStatus(code int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.status = code
+}
+
+// Requests 已收到的请求
+func (b *Inbox) Requests() []Request {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Request(nil), b.requests...)
+}
+
+func (b *Inbox) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	b.mu.Lock()
+	b.requests = append(b.requests, Request{Method: r.Method, Path: r.URL.Path, Header: r.Header.Clone(), Body: body})
+	status := b.status
+	b.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if status == http.StatusOK {
+		io.WriteString(w, `{"ok":true}`)
+	} else {
+		io.WriteString(w, `{"ok":false}`)
+	}
+}
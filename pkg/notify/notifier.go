@@ -0,0 +1,228 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Message 一条待发送的通知，Text 已按事件模板渲染
+type Message struct {
+	Event string    `json:"event"`
+	Key   string    `json:"key,omitempty"`
+	Text  string    `json:"text"`
+	Data  any       `json:"data,omitempty"`
+	Time  time.Time `json:"time"`
+}
+
+// Notifier 把事件按模板渲染后发送到各渠道，同一 Key 在 DedupWindow 内只发一次，每个渠道单独限速
+type Notifier struct {
+	templates   map[string]*template.Template // 小写事件名 → 模板
+	routes      []*route
+	DedupWindow time.Duration
+
+	mu   sync.Mutex
+	sent map[string]time.Time
+}
+
+// route 渠道及其事件过滤和限速
+type route struct {
+	channel Channel
+	events  map[string]bool // 为空时接收全部事件
+	limiter *limiter
+}
+
+// Funcs 模板可用的函数
+var Funcs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+func New() *Notifier {
+	return &Notifier{
+		templates:   make(map[string]*template.Template),
+		DedupWindow: 10 * time.Minute,
+		sent:        make(map[string]time.Time),
+	}
+}
+
+// Template 设置事件的消息模板，模板数据为 Notify 传入的 data；事件名 default 的模板用于未配置的事件
+func (n *Notifier) Template(event, text string) error {
+	t, err := template.New(event).Funcs(Funcs).Parse(text)
+	if err != nil {
+		return fmt.Errorf("template %s: %w", event, err)
+	}
+	n.templates[strings.ToLower(event)] = t
+	return nil
+}
+
+// Add 添加渠道，events 为空时接收全部事件；every 为 0 时不限速，否则每 every 恢复一次额度，最多攒 burst 次
+func (n *Notifier) Add(c Channel, events []string, every time.Duration, burst int) {
+	r := &route{channel: c}
+	if len(events) > 0 {
+		r.events = make(map[string]bool)
+		for _, e := range events {
+			r.events[strings.ToLower(e)] = true
+		}
+	}
+	if every > 0 {
+		r.limiter = newLimiter(every, burst)
+	}
+	n.routes = append(n.routes, r)
+}
+
+// Channels 已配置的渠道数
+func (n *Notifier) Channels() int {
+	return len(n.routes)
+}
+
+// Notify 渲染并同步发送，key 为空时不去重，至少一个渠道发送成功才计入去重；返回各渠道错误的合集。n 为 nil 时什么也不做，便于通知可选
+func (n *Notifier) Notify(ctx context.Context, event, key string, data any) error {
+	if n == nil {
+		return nil
+	}
+	now := time.Now()
+	dedup := event + "|" + key
+	if key != "" && n.duplicate(dedup, now) {
+		return nil
+	}
+	var sent int
+	defer func() {
+		//没有任何渠道发送成功时撤销去重记录，下次同一 key 还会发送
+		if key != "" && sent == 0 {
+			n.forget(dedup, now)
+		}
+	}()
+	text, err := n.render(event, data)
+	if err != nil {
+		return err
+	}
+	m := &Message{Event: event, Key: key, Text: text, Data: data, Time: now}
+
+	var errs []error
+	for _, r := range n.routes {
+		if r.events != nil && !r.events[strings.ToLower(event)] {
+			continue
+		}
+		msg := m
+		if r.limiter != nil {
+			ok, dropped := r.limiter.allow(now)
+			if !ok {
+				continue
+			}
+			if dropped > 0 {
+				copied := *m
+				copied.Text = fmt.Sprintf("%s\n(限速期间丢弃了 %d 条通知)", m.Text, dropped)
+				msg = &copied
+			}
+		}
+		if err := r.channel.Send(ctx, msg); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.channel.Name(), err))
+			continue
+		}
+		sent++
+	}
+	return errors.Join(errs...)
+}
+
+// Go 异步发送，失败只记日志，用于不能被网络请求阻塞的监听循环
+func (n *Notifier) Go(event, key string, data any) {
+	if n == nil {
+		return
+	}
+	go func() {
+		if err := n.Notify(context.Background(), event, key, data); err != nil {
+			log.Printf("⚠️ 发送通知失败: %v", err)
+		}
+	}()
+}
+
+// duplicate 窗口内已发送过返回 true，否则先占住 key，避免并发的同一通知重复发送；发送失败时由 forget 撤销
+func (n *Notifier) duplicate(key string, now time.Time) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if at, ok := n.sent[key]; ok && now.Sub(at) < n.DedupWindow {
+		return true
+	}
+	if len(n.sent) > 10000 {
+		for k, at := range n.sent {
+			if now.Sub(at) >= n.DedupWindow {
+				delete(n.sent, k)
+			}
+		}
+	}
+	n.sent[key] = now
+	return false
+}
+
+// forget 撤销 duplicate 在 now 时记下的 key，之后已被新的发送覆盖的不动
+func (n *Notifier) forget(key string, now time.Time) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if at, ok := n.sent[key]; ok && at.Equal(now) {
+		delete(n.sent, key)
+	}
+}
+
+// render 依次使用事件模板、default 模板，都没有时输出事件名和 data 的 JSON
+func (n *Notifier) render(event string, data any) (string, error) {
+	t, ok := n.templates[strings.ToLower(event)]
+	if !ok {
+		t, ok = n.templates["default"]
+	}
+	if !ok {
+		body, err := json.MarshalIndent(data, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s\n%s", event, body), nil
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("template %s: %w", event, err)
+	}
+	return buf.String(), nil
+}
+
+// limiter 令牌桶，记录被丢弃的条数，下一条放行的通知会带上
+type limiter struct {
+	mu      sync.Mutex
+	every   time.Duration
+	burst   float64
+	tokens  float64
+	last    time.Time
+	dropped int
+}
+
+func newLimiter(every time.Duration, burst int) *limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &limiter{every: every, burst: float64(burst), tokens: float64(burst)}
+}
+
+func (l *limiter) allow(now time.Time) (bool, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.last.IsZero() {
+		l.tokens = min(l.burst, l.tokens+float64(now.Sub(l.last))/float64(l.every))
+	}
+	l.last = now
+	if l.tokens < 1 {
+		l.dropped++
+		return false, 0
+	}
+	l.tokens--
+	dropped := l.dropped
+	l.dropped = 0
+	return true, dropped
+}
@@ -0,0 +1,218 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type whale struct {
+	Token string
+	Value string
+}
+
+// newTestServer Inbox 替身，所有渠道都指向它，按路径区分
+func newTestServer(t *testing.T) (*Inbox, string) {
+	t.Helper()
+	inbox := NewInbox()
+	ts := httptest.NewServer(inbox)
+	t.Cleanup(ts.Close)
+	return inbox, ts.URL
+}
+
+// byPath 按请求路径分组的请求体
+func byPath(t *testing.T, inbox *Inbox) map[string]map[string]any {
+	t.Helper()
+	bodies := make(map[string]map[string]any)
+	for _, r := range inbox.Requests() {
+		var body map[string]any
+		if err := json.Unmarshal(r.Body, &body); err != nil {
+			t.Fatalf("%s: invalid JSON %q: %v", r.Path, r.Body, err)
+		}
+		bodies[r.Path] = body
+	}
+	return bodies
+}
+
+func TestChannels(t *testing.T) {
+	inbox, url := newTestServer(t)
+	c := Config{
+		Templates: map[string]string{"whale": "{{.Token}} 大额转账 {{.Value}}"},
+		Channels: []ChannelConfig{
+			{Type: "telegram", Token: "123:abc", ChatID: "-100123", BaseURL: url},
+			{Type: "slack", URL: url + "/slack"},
+			{Type: "discord", URL: url + "/discord", Events: []string{"Whale"}},
+			{Type: "webhook", URL: url + "/hook", Method: http.MethodPut, Headers: map[string]string{"Authorization": "Bearer x"},
+				Template: `{"event": {{json .Event}}, "text": {{json .Text}}}`},
+		},
+	}
+	n, err := c.Notifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify(context.Background(), "whale", "", whale{Token: "USDT", Value: "250000"}); err != nil {
+		t.Fatal(err)
+	}
+	const text = "USDT 大额转账 250000"
+	bodies := byPath(t, inbox)
+	for path, want := range map[string]map[string]any{
+		"/bot123:abc/sendMessage": {"chat_id": "-100123", "text": text, "disable_web_page_preview": true},
+		"/slack":                  {"text": text},
+		"/discord":                {"content": text},
+		"/hook":                   {"event": "whale", "text": text},
+	} {
+		got, ok := bodies[path]
+		if !ok {
+			t.Errorf("%s: no request", path)
+			continue
+		}
+		for k, v := range want {
+			if got[k] != v {
+				t.Errorf("%s: %s = %v, want %v", path, k, got[k], v)
+			}
+		}
+	}
+	for _, r := range inbox.Requests() {
+		if r.Path == "/hook" && (r.Method != http.MethodPut || r.Header.Get("Authorization") != "Bearer x") {
+			t.Errorf("webhook: method %s, Authorization %q", r.Method, r.Header.Get("Authorization"))
+		}
+	}
+
+	// discord 只接收 whale；没有模板的事件输出事件名和 data 的 JSON
+	before := len(inbox.Requests())
+	if err := n.Notify(context.Background(), "deploy", "", map[string]string{"address": "0x01"}); err != nil {
+		t.Fatal(err)
+	}
+	requests := inbox.Requests()[before:]
+	if len(requests) != 3 {
+		t.Fatalf("deploy: %d requests, want 3", len(requests))
+	}
+	for _, r := range requests {
+		if r.Path == "/discord" {
+			t.Error("deploy sent to discord")
+		}
+		if r.Path == "/slack" && !strings.Contains(string(r.Body), `deploy\n{\n  \"address\": \"0x01\"\n}`) {
+			t.Errorf("slack default template: %s", r.Body)
+		}
+	}
+}
+
+func TestChannelError(t *testing.T) {
+	inbox, url := newTestServer(t)
+	inbox.SetStatus(http.StatusInternalServerError)
+	n := New()
+	n.Add(&Telegram{Token: "123:secret", ChatID: "1", BaseURL: url}, nil, 0, 0)
+	err := n.Notify(context.Background(), "whale", "", whale{})
+	if err == nil || !strings.Contains(err.Error(), "500") {
+		t.Fatalf("err = %v, want HTTP 500", err)
+	}
+	if strings.Contains(err.Error(), "secret") {
+		t.Errorf("bot token leaked in error: %v", err)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	inbox, url := newTestServer(t)
+	n := New()
+	n.Add(&Discord{URL: url + "/discord"}, nil, 0, 0)
+	if err := n.Template("long", strings.Repeat("鲸", 3000)); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify(context.Background(), "long", "", nil); err != nil {
+		t.Fatal(err)
+	}
+	content := byPath(t, inbox)["/discord"]["content"].(string)
+	if r := []rune(content); len(r) != 2000 || r[len(r)-1] != '…' {
+		t.Errorf("discord content: %d runes, want 2000 ending with …", len(r))
+	}
+}
+
+func TestDedup(t *testing.T) {
+	inbox, url := newTestServer(t)
+	n := New()
+	n.Add(&Slack{URL: url}, nil, 0, 0)
+	ctx := context.Background()
+
+	// 发送失败不计入去重，恢复后同一 key 还会发送
+	inbox.SetStatus(http.StatusBadGateway)
+	if err := n.Notify(ctx, "deploy", "0x01", nil); err == nil {
+		t.Fatal("want error from failing channel")
+	}
+	inbox.SetStatus(http.StatusOK)
+	for range 3 {
+		if err := n.Notify(ctx, "deploy", "0x01", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := len(inbox.Requests()); got != 2 {
+		t.Errorf("requests = %d, want 2 (failed + first success)", got)
+	}
+
+	// 不同事件、不同 key 或不去重的通知都会发送
+	for _, tt := range []struct{ event, key string }{{"deploy", "0x02"}, {"whale", "0x01"}, {"deploy", ""}, {"deploy", ""}} {
+		if err := n.Notify(ctx, tt.event, tt.key, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := len(inbox.Requests()); got != 6 {
+		t.Errorf("requests = %d, want 6", got)
+	}
+
+	// 窗口过后重新发送
+	n.DedupWindow = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	if err := n.Notify(ctx, "deploy", "0x01", nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(inbox.Requests()); got != 7 {
+		t.Errorf("requests after window = %d, want 7", got)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	inbox, url := newTestServer(t)
+	n := New()
+	n.Add(&Slack{URL: url + "/limited"}, nil, time.Hour, 2)
+	n.Add(&Discord{URL: url + "/unlimited"}, nil, 0, 0)
+	for range 5 {
+		if err := n.Notify(context.Background(), "whale", "", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	count := make(map[string]int)
+	for _, r := range inbox.Requests() {
+		count[r.Path]++
+	}
+	if count["/limited"] != 2 || count["/unlimited"] != 5 {
+		t.Errorf("requests = %v, want 2 limited and 5 unlimited", count)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	l := newLimiter(time.Second, 1)
+	start := time.Unix(1700000000, 0)
+	for _, tt := range []struct {
+		after   time.Duration
+		ok      bool
+		dropped int
+	}{
+		{0, true, 0},
+		{100 * time.Millisecond, false, 0},
+		{500 * time.Millisecond, false, 0},
+		// 恢复一次额度，放行的这条带上被丢弃的条数
+		{1100 * time.Millisecond, true, 2},
+		{1200 * time.Millisecond, false, 0},
+		// 额度最多攒 burst 次
+		{10 * time.Second, true, 1},
+		{10*time.Second + time.Millisecond, false, 0},
+	} {
+		ok, dropped := l.allow(start.Add(tt.after))
+		if ok != tt.ok || dropped != tt.dropped {
+			t.Errorf("+%s: allow = %v, %d, want %v, %d", tt.after, ok, dropped, tt.ok, tt.dropped)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package poll
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// Backend 订阅模式和轮询模式共同的接口，*ethclient.Client 和 *Client 都实现了它
+type Backend interface {
+	ethereum.LogFilterer
+	ethereum.BlockNumberReader
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error)
+	Close()
+}
+
+// DefaultInterval 默认轮询间隔
+const DefaultInterval = 3 * time.Second
+
+// Client 用轮询模拟订阅，供只有 HTTP 的 RPC 使用：日志优先用 eth_newFilter + eth_getFilterChanges，
+// 节点不支持或过滤器失效时退回按区块范围 eth_getLogs；新区块按区块号逐个拉取。其余方法与 ethclient 相同
+type Client struct {
+	*ethclient.Client
+	Interval  time.Duration
+	Range     uint64 // eth_getLogs 单次最大区块跨度
+	MaxErrors int    // 连续失败多少次后结束订阅
+}
+
+func New(c *ethclient.Client, interval time.Duration) *Client {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &Client{Client: c, Interval: interval, Range: 2000, MaxErrors: 5}
+}
+
+// Dial http(s) 地址返回轮询客户端，ws/ipc 返回原生订阅的 ethclient
+func Dial(rawurl string, interval time.Duration) (Backend, error) {
+	c, err := ethclient.Dial(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(rawurl, "http://") || strings.HasPrefix(rawurl, "https://") {
+		return New(c, interval), nil
+	}
+	return c, nil
+}
+
+// SubscribeFilterLogs 与 ethclient 语义相同：只推送订阅之后的新日志，忽略 q 的区块范围
+func (c *Client) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	head, err := c.BlockNumber(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p := &logPoller{client: c, query: q, ch: ch, next: head + 1}
+	// 创建失败说明节点不支持过滤器，直接用 eth_getLogs
+	if err := p.newFilter(ctx); err != nil {
+		log.Printf("⚠️ eth_newFilter 不可用，改用 eth_getLogs 轮询: %v", err)
+	}
+	return event.NewSubscription(p.run), nil
+}
+
+// SubscribeNewHead 按区块号逐个推送新区块头，落后太多时只补最近 Range 个
+func (c *Client) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	head, err := c.BlockNumber(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		ticker := time.NewTicker(c.Interval)
+		defer ticker.Stop()
+		last, failures := head, 0
+		for {
+			select {
+			case <-quit:
+				return nil
+			case <-ticker.C:
+			}
+			latest, err := c.BlockNumber(context.Background())
+			if err == nil && latest > last {
+				last = max(last, latest-min(latest, c.Range))
+				for n := last + 1; n <= latest && err == nil; n++ {
+					var h *types.Header
+					if h, err = c.HeaderByNumber(context.Background(), new(big.Int).SetUint64(n)); err == nil {
+						select {
+						case ch <- h:
+							last = n
+						case <-quit:
+							return nil
+						}
+					}
+				}
+			}
+			if failures, err = c.failure(failures, err); err != nil {
+				return err
+			}
+		}
+	}), nil
+}
+
+// failure 统计连续失败，超过 MaxErrors 返回错误结束订阅，否则只记日志
+func (c *Client) failure(failures int, err error) (int, error) {
+	if err == nil {
+		return 0, nil
+	}
+	failures++
+	if failures >= c.MaxErrors {
+		return failures, fmt.Errorf("poll: %d consecutive errors: %w", failures, err)
+	}
+	log.Printf("⚠️ 轮询失败(%d/%d): %v", failures, c.MaxErrors, err)
+	return failures, nil
+}
+
+// logPoller 一个日志订阅的轮询状态，filterID 为空时按区块范围拉取
+type logPoller struct {
+	client   *Client
+	query    ethereum.FilterQuery
+	ch       chan<- types.Log
+	filterID string
+	next     uint64 // 下一个需要拉取的区块，过滤器模式下用于退回 eth_getLogs 时衔接
+}
+
+func (p *logPoller) newFilter(ctx context.Context) error {
+	return p.client.Client.Client().CallContext(ctx, &p.filterID, "eth_newFilter", filterArg(p.query))
+}
+
+func (p *logPoller) run(quit <-chan struct{}) error {
+	defer func() {
+		if p.filterID != "" {
+			var ok bool
+			p.client.Client.Client().CallContext(context.Background(), &ok, "eth_uninstallFilter", p.filterID)
+		}
+	}()
+	ticker := time.NewTicker(p.client.Interval)
+	defer ticker.Stop()
+	failures := 0
+	for {
+		select {
+		case <-quit:
+			return nil
+		case <-ticker.C:
+		}
+		var logs []types.Log
+		var err error
+		if p.filterID != "" {
+			logs, err = p.changes()
+		} else {
+			logs, err = p.logs()
+		}
+		if err == nil {
+			for _, l := range logs {
+				select {
+				case p.ch <- l:
+				case <-quit:
+					return nil
+				}
+			}
+		}
+		if failures, err = p.client.failure(failures, err); err != nil {
+			return err
+		}
+	}
+}
+
+// changes 取过滤器的增量日志；过滤器被节点回收或出错时退回 eth_getLogs，从上次覆盖到的区块继续
+func (p *logPoller) changes() ([]types.Log, error) {
+	ctx := context.Background()
+	head, err := p.client.BlockNumber(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var logs []types.Log
+	if err := p.client.Client.Client().CallContext(ctx, &logs, "eth_getFilterChanges", p.filterID); err != nil {
+		log.Printf("⚠️ eth_getFilterChanges 失败，改用 eth_getLogs 从区块 %d 继续: %v", p.next, err)
+		p.filterID = ""
+		return p.logs()
+	}
+	// 调用前的 head 及之前的日志都已经包含在本次结果里
+	p.next = max(p.next, head+1)
+	return logs, nil
+}
+
+// logs 按区块范围拉取 [next, head]，单次最多 Range 个区块
+func (p *logPoller) logs() ([]types.Log, error) {
+	ctx := context.Background()
+	head, err := p.client.BlockNumber(ctx)
+	if err != nil || head < p.next {
+		return nil, err
+	}
+	to := min(head, p.next+p.client.Range-1)
+	q := p.query
+	q.BlockHash = nil
+	q.FromBlock = new(big.Int).SetUint64(p.next)
+	q.ToBlock = new(big.Int).SetUint64(to)
+	logs, err := p.client.FilterLogs(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	p.next = to + 1
+	return logs, nil
+}
+
+// filterArg eth_newFilter 的参数，只关心订阅之后的新日志
+func filterArg(q ethereum.FilterQuery) map[string]any {
+	arg := map[string]any{"fromBlock": "latest"}
+	if len(q.Addresses) > 0 {
+		arg["address"] = q.Addresses
+	}
+	if len(q.Topics) > 0 {
+		topics := make([]any, len(q.Topics))
+		for i, t := range q.Topics {
+			if len(t) > 0 {
+				topics[i] = t
+			}
+		}
+		arg["topics"] = topics
+	}
+	return arg
+}
+
+var (
+	_ Backend = (*Client)(nil)
+	_ Backend = (*ethclient.Client)(nil)
+)
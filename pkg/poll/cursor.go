@@ -0,0 +1,46 @@
+package poll
+
+import (
+	"math"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Cursor 日志流中已处理到的位置（区块号、日志序号）。重新订阅后从 Block 开始补齐，
+// 订阅推送和补齐结果中位于游标处或之前的日志都已处理过，直接丢掉
+type Cursor struct {
+	Block uint64
+	Index uint
+}
+
+// BlockCursor 整个区块都已处理过的位置
+func BlockCursor(block uint64) Cursor {
+	return Cursor{Block: block, Index: math.MaxUint}
+}
+
+// IsZero 还没有处理过任何日志
+func (c Cursor) IsZero() bool {
+	return c == Cursor{}
+}
+
+// Covers 日志位于游标处或之前
+func (c Cursor) Covers(l types.Log) bool {
+	return l.BlockNumber < c.Block || (l.BlockNumber == c.Block && l.Index <= c.Index)
+}
+
+// Next 处理完 l 之后的游标：新日志把游标前移；被重组移除的日志把游标退回它之前，新链上同一位置的日志不会被当作已处理
+func (c Cursor) Next(l types.Log) Cursor {
+	switch {
+	case !l.Removed && !c.Covers(l):
+		return Cursor{Block: l.BlockNumber, Index: l.Index}
+	case l.Removed && c.Covers(l):
+		if l.Index > 0 {
+			return Cursor{Block: l.BlockNumber, Index: l.Index - 1}
+		}
+		if l.BlockNumber == 0 {
+			return Cursor{}
+		}
+		return BlockCursor(l.BlockNumber - 1)
+	}
+	return c
+}
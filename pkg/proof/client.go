@@ -0,0 +1,237 @@
+package proof
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// ErrMismatch 节点返回的值与证明不符
+var ErrMismatch = errors.New("proof mismatch")
+
+// Prover 提供 eth_getProof，*gethclient.Client 满足
+type Prover interface {
+	GetProof(ctx context.Context, account common.Address, keys []string, blockNumber *big.Int) (*gethclient.AccountResult, error)
+}
+
+// Headers 提供区块头，*ethclient.Client 满足；它决定了状态根是否可信
+type Headers interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error)
+}
+
+// Account 校验过的账户状态
+type Account struct {
+	Address     common.Address
+	Nonce       uint64
+	Balance     *big.Int
+	CodeHash    common.Hash
+	StorageRoot common.Hash
+	Storage     map[common.Hash]common.Hash
+	Header      *types.Header // 用来校验的区块头
+}
+
+// Client 用区块头的状态根校验 eth_getProof 返回的账户和存储，不信任提供证明的节点。
+// 满足 storage.Client，可以直接给 storage.Reader 使用
+type Client struct {
+	prover  Prover
+	headers Headers
+	// BlockHash 不为零时只使用这个区块：按哈希取区块头并在本地重新计算哈希，区块头来源也不需要可信
+	BlockHash common.Hash
+
+	mu     sync.Mutex
+	byHash map[common.Hash]*types.Header
+}
+
+func NewClient(prover Prover, headers Headers) *Client {
+	return &Client{prover: prover, headers: headers, byHash: make(map[common.Hash]*types.Header)}
+}
+
+// Header 校验使用的区块头：设置了 BlockHash 时为该区块（block 必须为 nil 或与之一致），否则按区块号取，nil 为最新区块
+func (c *Client) Header(ctx context.Context, block *big.Int) (*types.Header, error) {
+	if c.BlockHash == (common.Hash{}) {
+		h, err := c.headers.HeaderByNumber(ctx, block)
+		if err != nil {
+			return nil, fmt.Errorf("header %v: %w", block, err)
+		}
+		return h, nil
+	}
+	c.mu.Lock()
+	h, ok := c.byHash[c.BlockHash]
+	c.mu.Unlock()
+	if !ok {
+		var err error
+		if h, err = c.headers.HeaderByHash(ctx, c.BlockHash); err != nil {
+			return nil, fmt.Errorf("header %s: %w", c.BlockHash.Hex(), err)
+		}
+		if h.Hash() != c.BlockHash {
+			return nil, fmt.Errorf("header %s: hash is %s: %w", c.BlockHash.Hex(), h.Hash().Hex(), ErrMismatch)
+		}
+		c.mu.Lock()
+		c.byHash[c.BlockHash] = h
+		c.mu.Unlock()
+	}
+	if block != nil && block.Cmp(h.Number) != 0 {
+		return nil, fmt.Errorf("block %s is not pinned block %s", block, h.Number)
+	}
+	return h, nil
+}
+
+// Account 取账户及 slots 的值和证明，并对照区块头的状态根校验
+func (c *Client) Account(ctx context.Context, address common.Address, block *big.Int, slots ...common.Hash) (*Account, error) {
+	header, err := c.Header(ctx, block)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, len(slots))
+	for i, s := range slots {
+		keys[i] = s.Hex()
+	}
+	res, err := c.prover.GetProof(ctx, address, keys, header.Number)
+	if err != nil {
+		return nil, fmt.Errorf("eth_getProof %s: %w", address.Hex(), err)
+	}
+	acc, err := Verify(header.Root, address, slots, res)
+	if err != nil {
+		return nil, fmt.Errorf("%s at block %s: %w", address.Hex(), header.Number, err)
+	}
+	acc.Header = header
+	return acc, nil
+}
+
+// StorageAt 校验过的存储槽
+func (c *Client) StorageAt(ctx context.Context, account common.Address, key common.Hash, block *big.Int) ([]byte, error) {
+	acc, err := c.Account(ctx, account, block, key)
+	if err != nil {
+		return nil, err
+	}
+	v := acc.Storage[key]
+	return v[:], nil
+}
+
+// BalanceAt 校验过的余额
+func (c *Client) BalanceAt(ctx context.Context, account common.Address, block *big.Int) (*big.Int, error) {
+	acc, err := c.Account(ctx, account, block)
+	if err != nil {
+		return nil, err
+	}
+	return acc.Balance, nil
+}
+
+// NonceAt 校验过的 nonce
+func (c *Client) NonceAt(ctx context.Context, account common.Address, block *big.Int) (uint64, error) {
+	acc, err := c.Account(ctx, account, block)
+	if err != nil {
+		return 0, err
+	}
+	return acc.Nonce, nil
+}
+
+// Verify 对照状态根 root 校验 eth_getProof 的结果：账户证明给出的账户必须与返回的 nonce、余额、codeHash、
+// storageHash 一致（不存在的账户必须全部为空），每个槽的证明必须与返回的值一致，以证明为准返回账户
+func Verify(root common.Hash, address common.Address, slots []common.Hash, res *gethclient.AccountResult) (*Account, error) {
+	value, err := trie.VerifyProof(root, crypto.Keccak256(address[:]), proofDB(res.AccountProof))
+	if err != nil {
+		return nil, fmt.Errorf("account proof: %w", err)
+	}
+	acc := &Account{Address: address, Balance: new(big.Int), CodeHash: types.EmptyCodeHash, StorageRoot: types.EmptyRootHash}
+	if value != nil {
+		var state types.StateAccount
+		if err := rlp.DecodeBytes(value, &state); err != nil {
+			return nil, fmt.Errorf("account proof: %w", err)
+		}
+		acc.Nonce, acc.Balance = state.Nonce, state.Balance.ToBig()
+		acc.CodeHash, acc.StorageRoot = common.BytesToHash(state.CodeHash), state.Root
+	}
+	reported := res.Balance
+	if reported == nil {
+		reported = new(big.Int)
+	}
+	switch {
+	case res.Nonce != acc.Nonce:
+		return nil, fmt.Errorf("nonce %d, proof %d: %w", res.Nonce, acc.Nonce, ErrMismatch)
+	case reported.Cmp(acc.Balance) != 0:
+		return nil, fmt.Errorf("balance %s, proof %s: %w", reported, acc.Balance, ErrMismatch)
+	case !emptyOr(res.CodeHash, acc.CodeHash, types.EmptyCodeHash):
+		return nil, fmt.Errorf("codeHash %s, proof %s: %w", res.CodeHash.Hex(), acc.CodeHash.Hex(), ErrMismatch)
+	case !emptyOr(res.StorageHash, acc.StorageRoot, types.EmptyRootHash):
+		return nil, fmt.Errorf("storageHash %s, proof %s: %w", res.StorageHash.Hex(), acc.StorageRoot.Hex(), ErrMismatch)
+	}
+
+	if len(res.StorageProof) != len(slots) {
+		return nil, fmt.Errorf("%d storage proofs for %d slots: %w", len(res.StorageProof), len(slots), ErrMismatch)
+	}
+	acc.Storage = make(map[common.Hash]common.Hash, len(slots))
+	for i, s := range slots {
+		sp := res.StorageProof[i]
+		if key, err := hexutil.DecodeBig(trimKey(sp.Key)); err != nil || common.BigToHash(key) != s {
+			return nil, fmt.Errorf("storage proof %d is for key %s, want %s: %w", i, sp.Key, s.Hex(), ErrMismatch)
+		}
+		v, err := verifySlot(acc.StorageRoot, s, sp.Proof)
+		if err != nil {
+			return nil, fmt.Errorf("slot %s: %w", s.Hex(), err)
+		}
+		if sp.Value == nil || common.BigToHash(sp.Value) != v {
+			return nil, fmt.Errorf("slot %s value %v, proof %s: %w", s.Hex(), sp.Value, v.Big(), ErrMismatch)
+		}
+		acc.Storage[s] = v
+	}
+	return acc, nil
+}
+
+// verifySlot 存储证明给出的槽值，槽不存在时为零
+func verifySlot(root, slot common.Hash, proof []string) (common.Hash, error) {
+	if root == types.EmptyRootHash {
+		return common.Hash{}, nil
+	}
+	value, err := trie.VerifyProof(root, crypto.Keccak256(slot[:]), proofDB(proof))
+	if err != nil || value == nil {
+		return common.Hash{}, err
+	}
+	var content []byte
+	if err := rlp.DecodeBytes(value, &content); err != nil {
+		return common.Hash{}, err
+	}
+	if len(content) > 32 {
+		return common.Hash{}, fmt.Errorf("storage value of %d bytes", len(content))
+	}
+	return common.BytesToHash(content), nil
+}
+
+// proofDB 以节点哈希为键的证明节点集合
+func proofDB(proof []string) *memorydb.Database {
+	db := memorydb.New()
+	for _, node := range proof {
+		b := common.FromHex(node)
+		db.Put(crypto.Keccak256(b), b)
+	}
+	return db
+}
+
+// emptyOr 节点对不存在的账户可能返回零哈希而不是空哈希
+func emptyOr(reported, proved, empty common.Hash) bool {
+	return reported == proved || (reported == common.Hash{} && proved == empty)
+}
+
+// trimKey 有的节点返回的键是去掉前导零的 quantity，有的是 32 字节，统一成 DecodeBig 能接受的形式
+func trimKey(key string) string {
+	digits := key
+	if len(digits) >= 2 && digits[0] == '0' && (digits[1] == 'x' || digits[1] == 'X') {
+		digits = digits[2:]
+	}
+	for len(digits) > 1 && digits[0] == '0' {
+		digits = digits[1:]
+	}
+	return "0x" + digits
+}
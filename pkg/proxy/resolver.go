@@ -0,0 +1,201 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Kind 代理类型
+type Kind string
+
+const (
+	None     Kind = ""
+	EIP1967  Kind = "EIP-1967"        // 实现地址在 bytes32(uint256(keccak256("eip1967.proxy.implementation")) - 1)
+	Beacon   Kind = "EIP-1967 beacon" // beacon 地址在 eip1967.proxy.beacon 槽，实现地址由 beacon.implementation() 返回
+	EIP1822  Kind = "EIP-1822"        // UUPS，实现地址在 keccak256("PROXIABLE")
+	EIP1167  Kind = "EIP-1167"        // 最小代理，实现地址写在字节码里
+	Zeppelin Kind = "OpenZeppelin legacy"
+)
+
+var (
+	ImplementationSlot = common.HexToHash("0x360894a13ba1a3210667c828492db98dca3e2076cc3735a920a3ca505d382bbc")
+	BeaconSlot         = common.HexToHash("0xa3f0ad74e5423aebfd80d3ef4346578335a9a72aeaee59ff6cb3582b35133d50")
+	AdminSlot          = common.HexToHash("0xb53127684a568b3173ae13b9f8a6016e243e63b6e8ee1178d6a717850b5d6103")
+	ProxiableSlot      = crypto.Keccak256Hash([]byte("PROXIABLE"))
+	ZeppelinSlot       = crypto.Keccak256Hash([]byte("org.zeppelinos.proxy.implementation"))
+
+	// implementation() 的选择器
+	implementationSelector = crypto.Keccak256([]byte("implementation()"))[:4]
+
+	// 最小代理字节码，地址前后的固定部分；第二种是使用 PUSH0 的版本（EIP-7511）
+	minimalProxies = [][2][]byte{
+		{common.FromHex("0x363d3d373d3d3d363d73"), common.FromHex("0x5af43d82803e903d91602b57fd5bf3")},
+		{common.FromHex("0x365f5f375f5f365f73"), common.FromHex("0x5af43d5f5f3e5f3d91602a57fd5bf3")},
+	}
+)
+
+// maxDepth 实现合约本身也是代理时最多跟随的层数
+const maxDepth = 5
+
+// Client 需要的 RPC 方法，*ethclient.Client 满足
+type Client interface {
+	CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error)
+	StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error)
+	CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+}
+
+// Proxy 解析结果，Kind 为 None 表示不是代理
+type Proxy struct {
+	Address        common.Address
+	Kind           Kind
+	Implementation common.Address
+	Beacon         common.Address // Beacon 代理的 beacon 合约
+	Admin          common.Address // EIP-1967 admin 槽，未设置时为零地址
+	Next           *Proxy         // 实现合约本身也是代理
+}
+
+// Target 最终的实现合约，不是代理时为合约本身
+func (p *Proxy) Target() common.Address {
+	for p.Next != nil {
+		p = p.Next
+	}
+	if p.Kind == None {
+		return p.Address
+	}
+	return p.Implementation
+}
+
+func (p *Proxy) String() string {
+	if p.Kind == None {
+		return fmt.Sprintf("%s 不是代理合约", p.Address.Hex())
+	}
+	var sb strings.Builder
+	for hop := p; hop != nil && hop.Kind != None; hop = hop.Next {
+		fmt.Fprintf(&sb, "%s %s 代理 → 实现合约 %s", hop.Address.Hex(), hop.Kind, hop.Implementation.Hex())
+		if hop.Beacon != (common.Address{}) {
+			fmt.Fprintf(&sb, "，beacon %s", hop.Beacon.Hex())
+		}
+		if hop.Admin != (common.Address{}) {
+			fmt.Fprintf(&sb, "，admin %s", hop.Admin.Hex())
+		}
+		sb.WriteString("\n")
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// Resolver 识别代理合约并解析实现合约地址
+type Resolver struct {
+	client Client
+	Block  *big.Int // 为 nil 时按最新区块
+}
+
+func NewResolver(client Client) *Resolver {
+	return &Resolver{client: client}
+}
+
+// Resolve 依次检查最小代理字节码、EIP-1967 实现槽、beacon 槽、EIP-1822 槽和旧版 OpenZeppelin 槽，
+// 实现合约本身也是代理时继续解析
+func (r *Resolver) Resolve(ctx context.Context, address common.Address) (*Proxy, error) {
+	return r.resolve(ctx, address, 0)
+}
+
+func (r *Resolver) resolve(ctx context.Context, address common.Address, depth int) (*Proxy, error) {
+	code, err := r.client.CodeAt(ctx, address, r.Block)
+	if err != nil {
+		return nil, fmt.Errorf("code of %s: %w", address.Hex(), err)
+	}
+	if len(code) == 0 {
+		return nil, fmt.Errorf("%s has no code", address.Hex())
+	}
+	p := &Proxy{Address: address}
+	if impl, ok := minimalProxy(code); ok {
+		p.Kind, p.Implementation = EIP1167, impl
+	} else if err := r.slots(ctx, p); err != nil {
+		return nil, err
+	}
+	if p.Kind == None || depth+1 >= maxDepth || p.Implementation == address {
+		return p, nil
+	}
+	next, err := r.resolve(ctx, p.Implementation, depth+1)
+	if err != nil {
+		return nil, fmt.Errorf("implementation %s: %w", p.Implementation.Hex(), err)
+	}
+	if next.Kind != None {
+		p.Next = next
+	}
+	return p, nil
+}
+
+// slots 按存储槽识别代理
+func (r *Resolver) slots(ctx context.Context, p *Proxy) error {
+	impl, err := r.address(ctx, p.Address, ImplementationSlot)
+	if err != nil {
+		return err
+	}
+	if impl != (common.Address{}) {
+		p.Kind, p.Implementation = EIP1967, impl
+		p.Admin, err = r.address(ctx, p.Address, AdminSlot)
+		return err
+	}
+	beacon, err := r.address(ctx, p.Address, BeaconSlot)
+	if err != nil {
+		return err
+	}
+	if beacon != (common.Address{}) {
+		out, err := r.client.CallContract(ctx, ethereum.CallMsg{To: &beacon, Data: implementationSelector}, r.Block)
+		if err != nil {
+			return fmt.Errorf("beacon %s implementation(): %w", beacon.Hex(), err)
+		}
+		if len(out) < 32 {
+			return fmt.Errorf("beacon %s implementation(): unexpected output %x", beacon.Hex(), out)
+		}
+		p.Kind, p.Beacon, p.Implementation = Beacon, beacon, common.BytesToAddress(out[:32])
+		p.Admin, err = r.address(ctx, p.Address, AdminSlot)
+		return err
+	}
+	for _, s := range []struct {
+		slot common.Hash
+		kind Kind
+	}{{ProxiableSlot, EIP1822}, {ZeppelinSlot, Zeppelin}} {
+		impl, err := r.address(ctx, p.Address, s.slot)
+		if err != nil {
+			return err
+		}
+		if impl != (common.Address{}) {
+			p.Kind, p.Implementation = s.kind, impl
+			return nil
+		}
+	}
+	return nil
+}
+
+// address 读取槽中的地址，槽的高 12 字节不为零时不是地址
+func (r *Resolver) address(ctx context.Context, account common.Address, slot common.Hash) (common.Address, error) {
+	b, err := r.client.StorageAt(ctx, account, slot, r.Block)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("read slot %s of %s: %w", slot.Hex(), account.Hex(), err)
+	}
+	word := common.BytesToHash(b)
+	if !bytes.Equal(word[:12], make([]byte, 12)) {
+		return common.Address{}, nil
+	}
+	return common.BytesToAddress(word[12:]), nil
+}
+
+// minimalProxy 匹配 EIP-1167 字节码，返回写死的实现地址
+func minimalProxy(code []byte) (common.Address, bool) {
+	for _, p := range minimalProxies {
+		prefix, suffix := p[0], p[1]
+		if len(code) == len(prefix)+20+len(suffix) && bytes.HasPrefix(code, prefix) && bytes.HasSuffix(code, suffix) {
+			return common.BytesToAddress(code[len(prefix) : len(prefix)+20]), true
+		}
+	}
+	return common.Address{}, false
+}
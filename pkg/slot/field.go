@@ -0,0 +1,96 @@
+package slot
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Field 槽内的一个值：Offset 为从低位开始的字节偏移，Size 为字节数
+type Field struct {
+	Slot   common.Hash
+	Offset int
+	Size   int
+}
+
+// Bits 在 256 位槽字中的位区间 [lo, hi)，从最低位算起
+func (f Field) Bits() (lo, hi int) {
+	return f.Offset * 8, (f.Offset + f.Size) * 8
+}
+
+// Mask 槽字中该字段所在位的掩码
+func (f Field) Mask() *big.Int {
+	lo, hi := f.Bits()
+	mask := new(big.Int).Lsh(big.NewInt(1), uint(hi-lo))
+	mask.Sub(mask, big.NewInt(1))
+	return mask.Lsh(mask, uint(lo))
+}
+
+// Extract 从槽字中截出字段的原始字节（大端）
+func (f Field) Extract(word common.Hash) []byte {
+	return common.CopyBytes(word[32-f.Offset-f.Size : 32-f.Offset])
+}
+
+// Insert 把 value（大端，不超过 Size 字节）写入槽字中的字段，其他位不变
+func (f Field) Insert(word common.Hash, value []byte) (common.Hash, error) {
+	if !f.valid() {
+		return word, fmt.Errorf("invalid field: %d bytes at offset %d", f.Size, f.Offset)
+	}
+	if len(value) > f.Size {
+		return word, fmt.Errorf("value of %d bytes does not fit in %d", len(value), f.Size)
+	}
+	copy(word[32-f.Offset-f.Size:32-f.Offset], common.LeftPadBytes(value, f.Size))
+	return word, nil
+}
+
+func (f Field) valid() bool {
+	return f.Size > 0 && f.Offset >= 0 && f.Offset+f.Size <= 32
+}
+
+func (f Field) String() string {
+	lo, hi := f.Bits()
+	return fmt.Sprintf("%s bits [%d, %d)", f.Slot.Hex(), lo, hi)
+}
+
+// Member 结构体成员或状态变量的大小；Whole 为结构体、数组、mapping、bytes/string 等总是独占整槽的类型
+type Member struct {
+	Size  int
+	Whole bool
+}
+
+// Position 成员相对结构体起始槽的位置
+type Position struct {
+	Slot   uint64
+	Offset int
+}
+
+// Pack 按 Solidity 的规则为依次声明的成员分配位置：值类型从低位开始紧密排列，放不下时换到下一个槽；
+// 独占整槽的类型从新槽开始，它之后的成员也从新槽开始。返回各成员位置和总槽数
+func Pack(members []Member) ([]Position, uint64) {
+	positions := make([]Position, len(members))
+	var slot uint64
+	offset := 0
+	for i, m := range members {
+		if m.Whole || offset+m.Size > 32 {
+			if offset > 0 {
+				slot, offset = slot+1, 0
+			}
+		}
+		positions[i] = Position{Slot: slot, Offset: offset}
+		if m.Whole {
+			slot += uint64(Slots(m.Size))
+			continue
+		}
+		offset += m.Size
+	}
+	if offset > 0 {
+		slot++
+	}
+	return positions, slot
+}
+
+// At 成员在结构体 base 之后的字段
+func (p Position) At(base common.Hash, size int) Field {
+	return Field{Slot: Add(base, p.Slot), Offset: p.Offset, Size: size}
+}
@@ -0,0 +1,97 @@
+package slot
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestPack(t *testing.T) {
+	// struct { uint128 a; uint64 b; uint64 c; address d; bool e; uint256[] f; uint8 g; mapping(..) h; uint16 i; }
+	members := []Member{
+		{Size: 16},
+		{Size: 8},
+		{Size: 8},
+		{Size: 20},
+		{Size: 1},
+		{Size: 32, Whole: true},
+		{Size: 1},
+		{Size: 32, Whole: true},
+		{Size: 2},
+	}
+	want := []Position{
+		{Slot: 0, Offset: 0},
+		{Slot: 0, Offset: 16},
+		{Slot: 0, Offset: 24},
+		{Slot: 1, Offset: 0},
+		{Slot: 1, Offset: 20},
+		{Slot: 2, Offset: 0},
+		{Slot: 3, Offset: 0},
+		{Slot: 4, Offset: 0},
+		{Slot: 5, Offset: 0},
+	}
+	got, slots := Pack(members)
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("member %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if slots != 6 {
+		t.Errorf("slots = %d, want 6", slots)
+	}
+
+	// 多槽的结构体成员占 Slots(size) 个槽
+	got, slots = Pack([]Member{{Size: 1}, {Size: 96, Whole: true}, {Size: 1}})
+	if got[1] != (Position{Slot: 1}) || got[2] != (Position{Slot: 4}) || slots != 5 {
+		t.Errorf("struct member: got %+v, %d slots", got, slots)
+	}
+}
+
+func TestFieldBits(t *testing.T) {
+	f := Field{Slot: Of(1), Offset: 20, Size: 1}
+	if lo, hi := f.Bits(); lo != 160 || hi != 168 {
+		t.Errorf("Bits = [%d, %d), want [160, 168)", lo, hi)
+	}
+	if got := common.BigToHash(f.Mask()); got != common.HexToHash("0x0000000000000000000000ff0000000000000000000000000000000000000000") {
+		t.Errorf("Mask = %s", got.Hex())
+	}
+}
+
+func TestFieldExtractInsert(t *testing.T) {
+	// 槽 1：低 20 字节为 address，之后 1 字节 bool，再之后 8 字节 uint64
+	word := common.HexToHash("0x000000000000000000000001dac17f958d2ee523a2206206994597c13d831ec7")
+	addr := Field{Slot: Of(1), Offset: 0, Size: 20}
+	flag := Field{Slot: Of(1), Offset: 20, Size: 1}
+	num := Field{Slot: Of(1), Offset: 21, Size: 8}
+
+	if got := addr.Extract(word); !bytes.Equal(got, common.FromHex("0xdac17f958d2ee523a2206206994597c13d831ec7")) {
+		t.Errorf("address = %x", got)
+	}
+	if got := flag.Extract(word); !bytes.Equal(got, []byte{1}) {
+		t.Errorf("bool = %x", got)
+	}
+
+	word, err := num.Insert(word, []byte{0x12, 0x34})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := common.HexToHash("0x000000000000000000123401dac17f958d2ee523a2206206994597c13d831ec7")
+	if word != want {
+		t.Errorf("Insert uint64 = %s, want %s", word.Hex(), want.Hex())
+	}
+	if got := num.Extract(word); !bytes.Equal(got, common.FromHex("0x0000000000001234")) {
+		t.Errorf("uint64 = %x", got)
+	}
+	// 写入 0 只清掉该字段
+	if word, err = flag.Insert(word, nil); err != nil || word != common.HexToHash("0x000000000000000000123400dac17f958d2ee523a2206206994597c13d831ec7") {
+		t.Errorf("Insert bool = %s, %v", word.Hex(), err)
+	}
+
+	if _, err := flag.Insert(word, []byte{1, 2}); err == nil {
+		t.Error("Insert 2 bytes into 1-byte field: want error")
+	}
+	if _, err := (Field{Offset: 30, Size: 4}).Insert(word, []byte{1}); err == nil {
+		t.Error("Insert into field past slot end: want error")
+	}
+}
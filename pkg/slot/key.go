@@ -0,0 +1,166 @@
+package slot
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+)
+
+// EncodeKey 按 Solidity 键类型编码 mapping 的键：值类型补齐到 32 字节（bytesN 右补零、其余左补），
+// string、bytes 直接用原始内容。typ 可以是 address、address payable、contract X、interface X、
+// bool、uintN、intN、enum X、bytesN、string、bytes；v 可以是字符串（数字、0x 十六进制、带或不带引号的文本）
+// 或对应的 Go 值（common.Address、*big.Int、整数、bool、[]byte、common.Hash）
+func EncodeKey(typ string, v any) ([]byte, error) {
+	typ = strings.TrimSpace(typ)
+	switch {
+	case typ == "string":
+		switch x := v.(type) {
+		case string:
+			return []byte(unquote(x)), nil
+		case []byte:
+			return x, nil
+		}
+	case typ == "bytes":
+		return toBytes(v)
+	case typ == "address" || typ == "address payable" || strings.HasPrefix(typ, "contract ") || strings.HasPrefix(typ, "interface "):
+		switch x := v.(type) {
+		case common.Address:
+			return common.LeftPadBytes(x.Bytes(), 32), nil
+		case string:
+			if s := unquote(x); common.IsHexAddress(s) {
+				return common.LeftPadBytes(common.HexToAddress(s).Bytes(), 32), nil
+			}
+		}
+		return nil, fmt.Errorf("invalid address key %v", v)
+	case typ == "bool":
+		b, ok := v.(bool)
+		if s, isString := v.(string); isString && (s == "true" || s == "false") {
+			b, ok = s == "true", true
+		}
+		if !ok {
+			return nil, fmt.Errorf("invalid bool key %v", v)
+		}
+		if b {
+			return common.LeftPadBytes([]byte{1}, 32), nil
+		}
+		return make([]byte, 32), nil
+	case strings.HasPrefix(typ, "bytes"):
+		size, err := strconv.Atoi(typ[5:])
+		if err != nil || size < 1 || size > 32 {
+			return nil, fmt.Errorf("invalid type %s", typ)
+		}
+		b, err := toBytes(v)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) > size {
+			return nil, fmt.Errorf("%s key %v too long", typ, v)
+		}
+		return common.RightPadBytes(b, 32), nil
+	case strings.HasPrefix(typ, "uint"), strings.HasPrefix(typ, "int"), strings.HasPrefix(typ, "enum "):
+		bits, signed, err := intType(typ)
+		if err != nil {
+			return nil, err
+		}
+		n, err := toBig(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s key %v: %w", typ, v, err)
+		}
+		if !fits(n, bits, signed) {
+			return nil, fmt.Errorf("key %s overflows %s", n, typ)
+		}
+		return math.U256Bytes(new(big.Int).Set(n)), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %s", typ)
+	}
+	return nil, fmt.Errorf("invalid %s key %v", typ, v)
+}
+
+// MappingKey 编码键并计算 mapping 值的槽位
+func MappingKey(slot common.Hash, typ string, key any) (common.Hash, error) {
+	b, err := EncodeKey(typ, key)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return Mapping(slot, b), nil
+}
+
+// intType 整数类型的位宽，enum 按 uint8
+func intType(typ string) (int, bool, error) {
+	if strings.HasPrefix(typ, "enum ") {
+		return 8, false, nil
+	}
+	signed := strings.HasPrefix(typ, "int")
+	digits := strings.TrimPrefix(strings.TrimPrefix(typ, "u"), "int")
+	if digits == "" {
+		return 256, signed, nil
+	}
+	bits, err := strconv.Atoi(digits)
+	if err != nil || bits < 8 || bits > 256 || bits%8 != 0 {
+		return 0, false, fmt.Errorf("invalid type %s", typ)
+	}
+	return bits, signed, nil
+}
+
+func fits(n *big.Int, bits int, signed bool) bool {
+	if !signed {
+		return n.Sign() >= 0 && n.BitLen() <= bits
+	}
+	limit := new(big.Int).Lsh(big.NewInt(1), uint(bits-1))
+	return n.Cmp(limit) < 0 && n.Cmp(new(big.Int).Neg(limit)) >= 0
+}
+
+func toBig(v any) (*big.Int, error) {
+	switch x := v.(type) {
+	case *big.Int:
+		return x, nil
+	case int:
+		return big.NewInt(int64(x)), nil
+	case int64:
+		return big.NewInt(x), nil
+	case uint64:
+		return new(big.Int).SetUint64(x), nil
+	case uint8:
+		return big.NewInt(int64(x)), nil
+	case string:
+		s := unquote(x)
+		neg := strings.HasPrefix(s, "-")
+		n, ok := math.ParseBig256(strings.TrimPrefix(s, "-"))
+		if !ok {
+			return nil, fmt.Errorf("invalid number %q", x)
+		}
+		if neg {
+			n.Neg(n)
+		}
+		return n, nil
+	}
+	return nil, fmt.Errorf("unsupported value %T", v)
+}
+
+func toBytes(v any) ([]byte, error) {
+	switch x := v.(type) {
+	case []byte:
+		return x, nil
+	case common.Hash:
+		return x.Bytes(), nil
+	case string:
+		return hexutil.Decode(unquote(x))
+	}
+	return nil, fmt.Errorf("unsupported value %T", v)
+}
+
+// unquote 去掉两边的引号
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		if u, err := strconv.Unquote(`"` + s[1:len(s)-1] + `"`); err == nil {
+			return u
+		}
+		return s[1 : len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,94 @@
+package slot
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestEncodeKey(t *testing.T) {
+	usdt := "0xdAC17F958D2ee523a2206206994597C13D831ec7"
+	tests := []struct {
+		typ  string
+		key  any
+		slot uint64
+		enc  string // 编码后的键
+		want string // keccak256(key . slot)
+	}{
+		{"uint256", 0, 0, "0000000000000000000000000000000000000000000000000000000000000000", "0xad3228b676f7d3cd4284a5443f17f1962b36e491b30a40b2405849e597ba5fb5"},
+		{"uint256", "1", 0, "0000000000000000000000000000000000000000000000000000000000000001", "0xada5013122d395ba3c54772283fb069b10426056ef8ca54750cb9bb552a59e7d"},
+		{"address", usdt, 2, "000000000000000000000000dac17f958d2ee523a2206206994597c13d831ec7", "0xf4f699a1ef35e64890828cc562d137cceeab28af8455567ef14b6ec385c8e441"},
+		{"address", common.HexToAddress(usdt), 2, "000000000000000000000000dac17f958d2ee523a2206206994597c13d831ec7", "0xf4f699a1ef35e64890828cc562d137cceeab28af8455567ef14b6ec385c8e441"},
+		{"contract IERC20", usdt, 2, "000000000000000000000000dac17f958d2ee523a2206206994597c13d831ec7", "0xf4f699a1ef35e64890828cc562d137cceeab28af8455567ef14b6ec385c8e441"},
+		{"bool", true, 3, "0000000000000000000000000000000000000000000000000000000000000001", "0xa15bc60c955c405d20d9149c709e2460f1c2d9a497496a7f46004d1772c3054c"},
+		{"bool", "true", 3, "0000000000000000000000000000000000000000000000000000000000000001", "0xa15bc60c955c405d20d9149c709e2460f1c2d9a497496a7f46004d1772c3054c"},
+		{"int8", -1, 4, "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", "0xd8c80a9840ed58f33f2186a8fbc29ecd8c3610d196f1da047301bd51988eb95c"},
+		{"int256", "-2", 4, "fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffe", "0x6d72300b4b1bfb30c4887d2f7e84a399175ad400b870651c2cc74eb77a10bc22"},
+		{"bytes4", "0xa9059cbb", 5, "a9059cbb00000000000000000000000000000000000000000000000000000000", "0xc380f7d569119f698e22840bb93ec0f170b05f88909772744653f081be577628"},
+		{"string", "hello", 6, "68656c6c6f", "0x45c1ec9be2e3e9d2314c1c37743b6f310b4e13361ba57441e76ef688f086ae25"},
+		{"string", `"hello"`, 6, "68656c6c6f", "0x45c1ec9be2e3e9d2314c1c37743b6f310b4e13361ba57441e76ef688f086ae25"},
+		{"bytes", "0x1234", 7, "1234", "0xcbbdf75ea8792fa4f5bdd336cf3c32022fb7c4738c40d93cd9472372caaf0475"},
+	}
+	for _, tt := range tests {
+		enc, err := EncodeKey(tt.typ, tt.key)
+		if err != nil {
+			t.Errorf("EncodeKey(%s, %v): %v", tt.typ, tt.key, err)
+			continue
+		}
+		if !bytes.Equal(enc, common.FromHex(tt.enc)) {
+			t.Errorf("EncodeKey(%s, %v) = %x, want %s", tt.typ, tt.key, enc, tt.enc)
+		}
+		got, err := MappingKey(Of(tt.slot), tt.typ, tt.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != common.HexToHash(tt.want) {
+			t.Errorf("MappingKey(%d, %s, %v) = %s, want %s", tt.slot, tt.typ, tt.key, got.Hex(), tt.want)
+		}
+	}
+}
+
+func TestEncodeKeyInvalid(t *testing.T) {
+	tests := []struct {
+		typ string
+		key any
+	}{
+		{"uint8", 256},
+		{"uint256", -1},
+		{"int8", 128},
+		{"int8", -129},
+		{"bytes2", "0x123456"},
+		{"bytes33", "0x12"},
+		{"address", "0x1234"},
+		{"bool", "yes"},
+		{"uint7", 1},
+		{"mapping", 1},
+	}
+	for _, tt := range tests {
+		if enc, err := EncodeKey(tt.typ, tt.key); err == nil {
+			t.Errorf("EncodeKey(%s, %v) = %x, want error", tt.typ, tt.key, enc)
+		}
+	}
+	if _, err := EncodeKey("int256", new(big.Int).Lsh(big.NewInt(1), 255)); err == nil {
+		t.Error("EncodeKey(int256, 2^255): want overflow error")
+	}
+}
+
+func TestNested(t *testing.T) {
+	// mapping(address => mapping(uint256 => uint256)) 位于槽 1
+	owner, _ := EncodeKey("address", "0xdAC17F958D2ee523a2206206994597C13D831ec7")
+	id, _ := EncodeKey("uint256", 5)
+	got := Nested(Of(1), owner, id)
+	if want := common.HexToHash("0x6cf0447159cc626c8151240c721b7a12613c22ee2b5a52a8fd1cab895df7a89f"); got != want {
+		t.Errorf("Nested = %s, want %s", got.Hex(), want.Hex())
+	}
+	if inner := crypto.Keccak256Hash(id, crypto.Keccak256(owner, Of(1).Bytes())); got != inner {
+		t.Errorf("Nested = %s, want keccak256(id . keccak256(owner . 1)) %s", got.Hex(), inner.Hex())
+	}
+	if got := Nested(Of(7)); got != Of(7) {
+		t.Errorf("Nested without keys = %s, want slot itself", got.Hex())
+	}
+}
@@ -0,0 +1,64 @@
+package slot
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Of 十进制槽号转换为槽位
+func Of(n uint64) common.Hash {
+	return common.BigToHash(new(big.Int).SetUint64(n))
+}
+
+// Add 槽位加 n，超过 2^256 时回绕
+func Add(slot common.Hash, n uint64) common.Hash {
+	return AddBig(slot, new(big.Int).SetUint64(n))
+}
+
+// AddBig 槽位加任意大小的偏移，超过 2^256 时回绕
+func AddBig(slot common.Hash, n *big.Int) common.Hash {
+	sum := new(big.Int).Add(slot.Big(), n)
+	return common.BytesToHash(math.U256Bytes(sum))
+}
+
+// Mapping mapping 的值位于 keccak256(key . slot)，key 为 EncodeKey 编码后的键
+func Mapping(slot common.Hash, key []byte) common.Hash {
+	return crypto.Keccak256Hash(key, slot.Bytes())
+}
+
+// Nested 嵌套 mapping，keys 从外到内
+func Nested(slot common.Hash, keys ...[]byte) common.Hash {
+	for _, key := range keys {
+		slot = Mapping(slot, key)
+	}
+	return slot
+}
+
+// ArrayBase 动态数组的长度在 slot，元素（以及长 bytes/string 的内容）从 keccak256(slot) 开始连续存放
+func ArrayBase(slot common.Hash) common.Hash {
+	return crypto.Keccak256Hash(slot.Bytes())
+}
+
+// Element 数组从 base 开始第 index 个元素的位置：不足 32 字节的元素按 32/size 个一槽紧密排列，
+// 否则每个元素占 ceil(size/32) 个槽、从槽首开始。定长数组的 base 就是变量的槽
+func Element(base common.Hash, index uint64, size int) (common.Hash, int) {
+	if size < 32 {
+		perSlot := uint64(32 / size)
+		return Add(base, index/perSlot), int(index%perSlot) * size
+	}
+	slots := new(big.Int).SetUint64(uint64(Slots(size)))
+	return AddBig(base, slots.Mul(slots, new(big.Int).SetUint64(index))), 0
+}
+
+// DynamicElement 动态数组 slot 的第 index 个元素
+func DynamicElement(slot common.Hash, index uint64, size int) (common.Hash, int) {
+	return Element(ArrayBase(slot), index, size)
+}
+
+// Slots size 字节占用的槽数
+func Slots(size int) int {
+	return (size + 31) / 32
+}
@@ -0,0 +1,57 @@
+package slot
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestArrayBase(t *testing.T) {
+	tests := map[uint64]string{
+		0: "0x290decd9548b62a8d60345a988386fc84ba6bc95484008f6362f93160ef3e563",
+		1: "0xb10e2d527612073b26eecdfd717e6a320cf44b4afac2b0732d9fcbe2b7fa0cf6",
+	}
+	for n, want := range tests {
+		if got := ArrayBase(Of(n)); got != common.HexToHash(want) {
+			t.Errorf("ArrayBase(%d) = %s, want %s", n, got.Hex(), want)
+		}
+	}
+}
+
+func TestElement(t *testing.T) {
+	base := ArrayBase(Of(0))
+	tests := []struct {
+		name   string
+		index  uint64
+		size   int
+		slot   uint64 // 相对 base
+		offset int
+	}{
+		{"uint8 first", 0, 1, 0, 0},
+		{"uint8 last in slot", 31, 1, 0, 31},
+		{"uint8 next slot", 32, 1, 1, 0},
+		{"uint64", 5, 8, 1, 8},
+		{"address", 1, 20, 1, 0}, // 20 字节一槽只放一个
+		{"uint128", 3, 16, 1, 16},
+		{"uint256", 7, 32, 7, 0},
+		{"64-byte struct", 0, 64, 0, 0},
+		{"64-byte struct", 3, 64, 6, 0},
+		{"33-byte struct", 2, 33, 4, 0},
+	}
+	for _, tt := range tests {
+		slot, offset := Element(base, tt.index, tt.size)
+		if want := Add(base, tt.slot); slot != want || offset != tt.offset {
+			t.Errorf("%s [%d]: got %s offset %d, want base+%d offset %d", tt.name, tt.index, slot.Hex(), offset, tt.slot, tt.offset)
+		}
+	}
+	if slot, offset := DynamicElement(Of(0), 33, 1); slot != Add(base, 1) || offset != 1 {
+		t.Errorf("DynamicElement(0, 33, 1) = %s, %d", slot.Hex(), offset)
+	}
+}
+
+func TestAddWraps(t *testing.T) {
+	last := common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")
+	if got := Add(last, 2); got != Of(1) {
+		t.Errorf("Add(2^256-1, 2) = %s, want 1", got.Hex())
+	}
+}
@@ -0,0 +1,251 @@
+package storage
+
+import (
+	"bytes"
+	"chainget/pkg/slot"
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Change 一个槽在前后两个状态的值
+type Change struct {
+	Slot   common.Hash `json:"slot"`
+	Before common.Hash `json:"before"`
+	After  common.Hash `json:"after"`
+}
+
+// FieldChange 按布局解码的变化，一个槽内紧密排列的多个变量分别列出
+type FieldChange struct {
+	Path   string      `json:"path"`
+	Type   string      `json:"type"`
+	Slot   common.Hash `json:"slot"`
+	Offset int         `json:"offset,omitempty"`
+	Before any         `json:"before"`
+	After  any         `json:"after"`
+}
+
+func (c *FieldChange) String() string {
+	s := fmt.Sprintf("%s (%s): %v → %v  [slot %s", c.Path, c.Type, c.Before, c.After, c.Slot.Hex())
+	if c.Offset > 0 {
+		s += fmt.Sprintf(" offset %d", c.Offset)
+	}
+	return s + "]"
+}
+
+// Field 布局中占据槽内固定位置的值：值类型变量、结构体成员、数组元素，以及动态数组的长度和 bytes/string 的头部槽
+type Field struct {
+	slot.Field
+	Path string
+	Type *TypeInfo
+}
+
+// Tracer 只需要 CallContext，*rpc.Client 满足
+type Tracer interface {
+	CallContext(ctx context.Context, result any, method string, args ...any) error
+}
+
+// TxDiff 交易改动的存储槽，按合约分组
+type TxDiff struct {
+	Changes  map[common.Address][]Change
+	Accounts []common.Address // 交易涉及的所有账户（发送方、调用到的合约、余额变化的账户），可作为 mapping 的候选键
+}
+
+// TraceDiff 用 debug_traceTransaction 的 prestateTracer diffMode 取交易改动的存储槽，
+// post 中省略了被清零的槽
+func TraceDiff(ctx context.Context, tracer Tracer, tx common.Hash) (*TxDiff, error) {
+	type account struct {
+		Storage map[common.Hash]common.Hash `json:"storage"`
+	}
+	var result struct {
+		Pre  map[common.Address]account `json:"pre"`
+		Post map[common.Address]account `json:"post"`
+	}
+	config := map[string]any{"tracer": "prestateTracer", "tracerConfig": map[string]any{"diffMode": true}}
+	if err := tracer.CallContext(ctx, &result, "debug_traceTransaction", tx, config); err != nil {
+		return nil, err
+	}
+	diff := &TxDiff{Changes: make(map[common.Address][]Change)}
+	for address := range result.Post {
+		if _, ok := result.Pre[address]; !ok {
+			diff.Accounts = append(diff.Accounts, address)
+		}
+	}
+	for address, pre := range result.Pre {
+		diff.Accounts = append(diff.Accounts, address)
+		post := result.Post[address]
+		seen := make(map[common.Hash]bool)
+		for key := range pre.Storage {
+			seen[key] = true
+		}
+		for key := range post.Storage {
+			seen[key] = true
+		}
+		var changes []Change
+		for key := range seen {
+			if c := (Change{Slot: key, Before: pre.Storage[key], After: post.Storage[key]}); c.Before != c.After {
+				changes = append(changes, c)
+			}
+		}
+		if len(changes) > 0 {
+			diff.Changes[address] = sortChanges(changes)
+		}
+	}
+	return diff, nil
+}
+
+// DiffSlots 逐个读取槽在 before、after 两个区块的值，返回有变化的槽
+func DiffSlots(ctx context.Context, client Client, address common.Address, slots []common.Hash, before, after *big.Int) ([]Change, error) {
+	var changes []Change
+	seen := make(map[common.Hash]bool)
+	for _, at := range slots {
+		if seen[at] {
+			continue
+		}
+		seen[at] = true
+		a, err := client.StorageAt(ctx, address, at, before)
+		if err != nil {
+			return nil, fmt.Errorf("read slot %s at %v: %w", at.Hex(), before, err)
+		}
+		b, err := client.StorageAt(ctx, address, at, after)
+		if err != nil {
+			return nil, fmt.Errorf("read slot %s at %v: %w", at.Hex(), after, err)
+		}
+		if c := (Change{Slot: at, Before: common.BytesToHash(a), After: common.BytesToHash(b)}); c.Before != c.After {
+			changes = append(changes, c)
+		}
+	}
+	return sortChanges(changes), nil
+}
+
+// Fields 列出布局中所有状态变量占据的位置：结构体和定长数组展开（最多 maxItems 个元素），
+// mapping 用 keys 中能按键类型编码的值作为候选键展开，动态数组只列长度槽
+func (l *Layout) Fields(keys []string, maxItems int) []Field {
+	var fields []Field
+	for _, v := range l.Storage {
+		at, err := parseSlot(v.Slot)
+		if err != nil {
+			continue
+		}
+		l.expand(&fields, v.Label, common.BigToHash(at), v.Offset, l.Types[v.Type], keys, maxItems)
+	}
+	return fields
+}
+
+// Fields 定位路径并列出它占据的位置，路径中可以给出 mapping 键和动态数组下标
+func (r *Reader) Fields(ctx context.Context, path string, keys []string) ([]Field, error) {
+	loc, err := r.Locate(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	var fields []Field
+	r.Layout.expand(&fields, loc.Path, loc.Slot, loc.Offset, loc.Type, keys, r.MaxItems)
+	return fields, nil
+}
+
+func (l *Layout) expand(fields *[]Field, path string, at common.Hash, offset int, t *TypeInfo, keys []string, maxItems int) {
+	switch {
+	case t.Encoding == "mapping":
+		for _, k := range keys {
+			key, err := slot.EncodeKey(l.Types[t.Key].Label, k)
+			if err != nil {
+				continue
+			}
+			l.expand(fields, fmt.Sprintf("%s[%s]", path, k), slot.Mapping(at, key), 0, l.Types[t.Value], keys, maxItems)
+		}
+	case t.Encoding == "bytes":
+		*fields = append(*fields, Field{Field: slot.Field{Slot: at, Size: 32}, Path: path, Type: t})
+	case t.Encoding == "dynamic_array":
+		*fields = append(*fields, Field{Field: slot.Field{Slot: at, Size: 32}, Path: path + ".length", Type: t})
+	case t.isStruct():
+		for _, m := range t.Members {
+			mslot, err := parseSlot(m.Slot)
+			if err != nil {
+				continue
+			}
+			l.expand(fields, path+"."+m.Label, slot.AddBig(at, mslot), m.Offset, l.Types[m.Type], keys, maxItems)
+		}
+	case t.Base != "":
+		length, _ := t.Length()
+		elem := l.Types[t.Base]
+		for i := 0; i < min(length, maxItems); i++ {
+			eslot, eoffset := slot.Element(at, uint64(i), elem.Size())
+			l.expand(fields, fmt.Sprintf("%s[%d]", path, i), eslot, eoffset, elem, keys, maxItems)
+		}
+	default:
+		*fields = append(*fields, Field{Field: slot.Field{Slot: at, Offset: offset, Size: t.Size()}, Path: path, Type: t})
+	}
+}
+
+// Slots 字段所在的槽，去重
+func Slots(fields []Field) []common.Hash {
+	var slots []common.Hash
+	seen := make(map[common.Hash]bool)
+	for _, f := range fields {
+		if !seen[f.Slot] {
+			seen[f.Slot] = true
+			slots = append(slots, f.Slot)
+		}
+	}
+	return slots
+}
+
+// Decode 把槽的变化按字段解码，只输出值变了的字段，同一位置只取第一个字段；没有对应字段的槽原样放在 unknown 里
+func Decode(changes []Change, fields []Field) (decoded []*FieldChange, unknown []Change) {
+	bySlot := make(map[common.Hash][]Field)
+	seen := make(map[slot.Field]bool)
+	for _, f := range fields {
+		if f.Offset+f.Size <= 32 && !seen[f.Field] {
+			seen[f.Field] = true
+			bySlot[f.Slot] = append(bySlot[f.Slot], f)
+		}
+	}
+	for _, c := range changes {
+		fs, ok := bySlot[c.Slot]
+		if !ok {
+			unknown = append(unknown, c)
+			continue
+		}
+		for _, f := range fs {
+			before, after := f.Extract(c.Before), f.Extract(c.After)
+			if bytes.Equal(before, after) {
+				continue
+			}
+			decoded = append(decoded, &FieldChange{
+				Path: f.Path, Type: f.Type.Label, Slot: f.Slot, Offset: f.Offset,
+				Before: f.decode(before), After: f.decode(after),
+			})
+		}
+	}
+	return decoded, unknown
+}
+
+// decode 解码字段的原始字节；bytes/string 只解码短数据，长数据显示长度
+func (f Field) decode(b []byte) any {
+	switch f.Type.Encoding {
+	case "dynamic_array":
+		return new(big.Int).SetBytes(b)
+	case "bytes":
+		if b[31]&1 == 1 {
+			n := new(big.Int).SetBytes(b)
+			return fmt.Sprintf("<%s bytes>", n.Rsh(n, 1))
+		}
+		data := b[:min(int(b[31]/2), 31)]
+		if f.Type.Label == "string" {
+			return string(data)
+		}
+		return hexutil.Bytes(common.CopyBytes(data))
+	}
+	return decodeValue(f.Type.Label, b)
+}
+
+func sortChanges(changes []Change) []Change {
+	sort.Slice(changes, func(i, j int) bool {
+		return bytes.Compare(changes[i].Slot[:], changes[j].Slot[:]) < 0
+	})
+	return changes
+}
@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Layout solc 输出的 storageLayout（solc --storage-layout、forge inspect <合约> storageLayout）
+type Layout struct {
+	Storage []Variable           `json:"storage"`
+	Types   map[string]*TypeInfo `json:"types"`
+}
+
+// Variable 状态变量或结构体成员，Slot 为十进制字符串，Offset 为槽内从低位开始的字节偏移
+type Variable struct {
+	Label    string `json:"label"`
+	Slot     string `json:"slot"`
+	Offset   int    `json:"offset"`
+	Type     string `json:"type"`
+	Contract string `json:"contract,omitempty"`
+}
+
+// TypeInfo 类型描述，Encoding 为 inplace、mapping、dynamic_array 或 bytes
+type TypeInfo struct {
+	Encoding      string     `json:"encoding"`
+	Label         string     `json:"label"`
+	NumberOfBytes string     `json:"numberOfBytes"`
+	Key           string     `json:"key,omitempty"`     // mapping 的键类型
+	Value         string     `json:"value,omitempty"`   // mapping 的值类型
+	Base          string     `json:"base,omitempty"`    // 数组的元素类型
+	Members       []Variable `json:"members,omitempty"` // 结构体成员
+}
+
+// LoadLayout 读取 storageLayout JSON，也接受带 storageLayout 字段的编译产物（forge out/*.json）
+func LoadLayout(path string) (*Layout, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseLayout(data)
+}
+
+func ParseLayout(data []byte) (*Layout, error) {
+	var artifact struct {
+		StorageLayout *Layout `json:"storageLayout"`
+	}
+	if err := json.Unmarshal(data, &artifact); err == nil && artifact.StorageLayout != nil {
+		return artifact.StorageLayout, artifact.StorageLayout.validate()
+	}
+	var l Layout
+	if err := json.Unmarshal(data, &l); err != nil {
+		return nil, err
+	}
+	return &l, l.validate()
+}
+
+// validate 检查引用的类型都存在
+func (l *Layout) validate() error {
+	if len(l.Storage) == 0 {
+		return fmt.Errorf("storage layout: no variables")
+	}
+	check := func(label, id string) error {
+		if _, ok := l.Types[id]; !ok {
+			return fmt.Errorf("storage layout: %s: unknown type %s", label, id)
+		}
+		return nil
+	}
+	for _, v := range l.Storage {
+		if err := check(v.Label, v.Type); err != nil {
+			return err
+		}
+	}
+	for id, t := range l.Types {
+		for _, ref := range []string{t.Key, t.Value, t.Base} {
+			if ref != "" {
+				if err := check(id, ref); err != nil {
+					return err
+				}
+			}
+		}
+		for _, m := range t.Members {
+			if err := check(id+"."+m.Label, m.Type); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Variable 按名字查找状态变量
+func (l *Layout) Variable(label string) (*Variable, bool) {
+	for i := range l.Storage {
+		if l.Storage[i].Label == label {
+			return &l.Storage[i], true
+		}
+	}
+	return nil, false
+}
+
+// Size 类型占用的字节数
+func (t *TypeInfo) Size() int {
+	n, _ := strconv.Atoi(t.NumberOfBytes)
+	return n
+}
+
+// Length 定长数组的长度，从 label 的最后一个 [N] 取
+func (t *TypeInfo) Length() (int, bool) {
+	if t.Base == "" || t.Encoding != "inplace" || !strings.HasSuffix(t.Label, "]") {
+		return 0, false
+	}
+	i := strings.LastIndex(t.Label, "[")
+	n, err := strconv.Atoi(t.Label[i+1 : len(t.Label)-1])
+	return n, err == nil
+}
+
+func (t *TypeInfo) isStruct() bool { return t.Encoding == "inplace" && len(t.Members) > 0 }
+
+func parseSlot(s string) (*big.Int, error) {
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid slot %q", s)
+	}
+	return n, nil
+}
@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+)
+
+// step 路径中的一步：.member 或 [key]
+type step struct {
+	member string
+	key    string
+	index  bool
+}
+
+func (s step) String() string {
+	if s.index {
+		return "[" + s.key + "]"
+	}
+	return "." + s.member
+}
+
+// parsePath 解析 locks[3].amount、balances[0xabc…]、allowance[0xa][0xb]、names["foo"] 形式的变量路径
+func parsePath(path string) (string, []step, error) {
+	path = strings.TrimSpace(path)
+	end := strings.IndexAny(path, ".[")
+	if end < 0 {
+		end = len(path)
+	}
+	name := path[:end]
+	if name == "" {
+		return "", nil, fmt.Errorf("path %q: missing variable name", path)
+	}
+	var steps []step
+	for rest := path[end:]; rest != ""; {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			n := strings.IndexAny(rest, ".[")
+			if n < 0 {
+				n = len(rest)
+			}
+			if n == 0 {
+				return "", nil, fmt.Errorf("path %q: empty member name", path)
+			}
+			steps = append(steps, step{member: rest[:n]})
+			rest = rest[n:]
+		case '[':
+			n := closing(rest)
+			if n < 0 {
+				return "", nil, fmt.Errorf("path %q: missing ]", path)
+			}
+			key := strings.TrimSpace(rest[1:n])
+			if key == "" {
+				return "", nil, fmt.Errorf("path %q: empty key", path)
+			}
+			steps = append(steps, step{key: key, index: true})
+			rest = rest[n+1:]
+		default:
+			return "", nil, fmt.Errorf("path %q: unexpected %q", path, rest[0])
+		}
+	}
+	return name, steps, nil
+}
+
+// closing 匹配的 ]，跳过引号内的内容
+func closing(s string) int {
+	var quote byte
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ']':
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,280 @@
+package storage
+
+import (
+	"chainget/pkg/slot"
+	"context"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Client 只需要 eth_getStorageAt，*ethclient.Client 满足
+type Client interface {
+	StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error)
+}
+
+// Location 变量在存储中的位置
+type Location struct {
+	Path   string
+	Slot   common.Hash
+	Offset int // 槽内从低位开始的字节偏移
+	TypeID string
+	Type   *TypeInfo
+}
+
+// Value 读取并解码后的值，结构体和数组的元素在 Items 中
+type Value struct {
+	Path   string      `json:"path"`
+	Type   string      `json:"type"`
+	Slot   common.Hash `json:"slot"`
+	Offset int         `json:"offset,omitempty"`
+	Value  any         `json:"value,omitempty"` // *big.Int、bool、common.Address、hexutil.Bytes、string；动态数组为长度
+	Items  []*Value    `json:"items,omitempty"`
+}
+
+// Reader 按 storageLayout 读取合约状态变量
+type Reader struct {
+	client  Client
+	Address common.Address
+	Layout  *Layout
+	Block   *big.Int // 为 nil 时读最新区块
+	// MaxItems 读取整个动态数组或 bytes 时最多展开的元素数
+	MaxItems int
+}
+
+func NewReader(client Client, address common.Address, layout *Layout) *Reader {
+	return &Reader{client: client, Address: address, Layout: layout, MaxItems: 100}
+}
+
+// Locate 计算路径对应的槽位；动态数组的下标会先读长度检查越界
+func (r *Reader) Locate(ctx context.Context, path string) (*Location, error) {
+	name, steps, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	v, ok := r.Layout.Variable(name)
+	if !ok {
+		return nil, fmt.Errorf("no state variable %s", name)
+	}
+	start, err := parseSlot(v.Slot)
+	if err != nil {
+		return nil, err
+	}
+	loc := &Location{Path: name, Slot: common.BigToHash(start), Offset: v.Offset, TypeID: v.Type, Type: r.Layout.Types[v.Type]}
+	for _, s := range steps {
+		if err := r.step(ctx, loc, s); err != nil {
+			return nil, fmt.Errorf("%s%s: %w", loc.Path, s, err)
+		}
+		loc.Path += s.String()
+	}
+	return loc, nil
+}
+
+func (r *Reader) step(ctx context.Context, loc *Location, s step) error {
+	t := loc.Type
+	if !s.index {
+		if !t.isStruct() {
+			return fmt.Errorf("%s is not a struct", t.Label)
+		}
+		for _, m := range t.Members {
+			if m.Label == s.member {
+				offset, err := parseSlot(m.Slot)
+				if err != nil {
+					return err
+				}
+				loc.Slot = slot.AddBig(loc.Slot, offset)
+				loc.Offset, loc.TypeID, loc.Type = m.Offset, m.Type, r.Layout.Types[m.Type]
+				return nil
+			}
+		}
+		return fmt.Errorf("%s has no member %s", t.Label, s.member)
+	}
+	switch {
+	case t.Encoding == "mapping":
+		key, err := slot.EncodeKey(r.Layout.Types[t.Key].Label, s.key)
+		if err != nil {
+			return err
+		}
+		loc.Slot, loc.Offset, loc.TypeID, loc.Type = slot.Mapping(loc.Slot, key), 0, t.Value, r.Layout.Types[t.Value]
+		return nil
+	case t.Encoding == "dynamic_array" || t.Base != "":
+		index, err := strconv.ParseUint(s.key, 0, 64)
+		if err != nil {
+			return fmt.Errorf("invalid index %q", s.key)
+		}
+		base := loc.Slot
+		if t.Encoding == "dynamic_array" {
+			word, err := r.word(ctx, loc.Slot)
+			if err != nil {
+				return err
+			}
+			if length := word.Big(); length.Cmp(new(big.Int).SetUint64(index)) <= 0 {
+				return fmt.Errorf("index %d out of range, length %s", index, length)
+			}
+			base = slot.ArrayBase(loc.Slot)
+		} else if length, _ := t.Length(); index >= uint64(length) {
+			return fmt.Errorf("index %d out of range, length %d", index, length)
+		}
+		elem := r.Layout.Types[t.Base]
+		loc.Slot, loc.Offset = slot.Element(base, index, elem.Size())
+		loc.TypeID, loc.Type = t.Base, elem
+		return nil
+	}
+	return fmt.Errorf("%s can not be indexed", t.Label)
+}
+
+// Read 定位并读取变量，结构体、数组递归展开，mapping 需要在路径中给出键
+func (r *Reader) Read(ctx context.Context, path string) (*Value, error) {
+	loc, err := r.Locate(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return r.read(ctx, loc.Path, loc.Slot, loc.Offset, loc.Type)
+}
+
+func (r *Reader) read(ctx context.Context, path string, at common.Hash, offset int, t *TypeInfo) (*Value, error) {
+	v := &Value{Path: path, Type: t.Label, Slot: at, Offset: offset}
+	switch {
+	case t.Encoding == "mapping":
+		return nil, fmt.Errorf("%s: mapping needs a key", path)
+	case t.Encoding == "bytes":
+		data, err := r.bytes(ctx, at)
+		if err != nil {
+			return nil, err
+		}
+		if t.Label == "string" {
+			v.Value = string(data)
+		} else {
+			v.Value = hexutil.Bytes(data)
+		}
+	case t.isStruct():
+		for _, m := range t.Members {
+			mslot, err := parseSlot(m.Slot)
+			if err != nil {
+				return nil, err
+			}
+			item, err := r.read(ctx, path+"."+m.Label, slot.AddBig(at, mslot), m.Offset, r.Layout.Types[m.Type])
+			if err != nil {
+				return nil, err
+			}
+			v.Items = append(v.Items, item)
+		}
+	case t.Encoding == "dynamic_array" || t.Base != "":
+		base, length := at, 0
+		if t.Encoding == "dynamic_array" {
+			word, err := r.word(ctx, at)
+			if err != nil {
+				return nil, err
+			}
+			n := word.Big()
+			v.Value = n
+			length = int(min(n.Uint64(), uint64(r.MaxItems)))
+			base = slot.ArrayBase(at)
+		} else {
+			length, _ = t.Length()
+			length = min(length, r.MaxItems)
+		}
+		elem := r.Layout.Types[t.Base]
+		for i := 0; i < length; i++ {
+			eslot, eoffset := slot.Element(base, uint64(i), elem.Size())
+			item, err := r.read(ctx, fmt.Sprintf("%s[%d]", path, i), eslot, eoffset, elem)
+			if err != nil {
+				return nil, err
+			}
+			v.Items = append(v.Items, item)
+		}
+	default:
+		word, err := r.word(ctx, at)
+		if err != nil {
+			return nil, err
+		}
+		f := slot.Field{Slot: at, Offset: offset, Size: t.Size()}
+		if f.Offset+f.Size > 32 {
+			return nil, fmt.Errorf("%s: %d bytes at offset %d exceed the slot", path, f.Size, f.Offset)
+		}
+		v.Value = decodeValue(t.Label, f.Extract(word))
+	}
+	return v, nil
+}
+
+// bytes 读取 bytes/string：短数据（< 32 字节）与长度*2 一起存在槽内，长数据槽内为长度*2+1，内容从 keccak256(slot) 开始
+func (r *Reader) bytes(ctx context.Context, at common.Hash) ([]byte, error) {
+	word, err := r.word(ctx, at)
+	if err != nil {
+		return nil, err
+	}
+	if word[31]&1 == 0 {
+		n := int(word[31] / 2)
+		if n > 31 {
+			return nil, fmt.Errorf("invalid short bytes length %d", n)
+		}
+		return word[:n], nil
+	}
+	length := word.Big()
+	length.Rsh(length, 1)
+	if !length.IsInt64() || length.Int64() > int64(r.MaxItems)*32 {
+		return nil, fmt.Errorf("bytes too long: %s", length)
+	}
+	n := int(length.Int64())
+	base := slot.ArrayBase(at)
+	data := make([]byte, 0, n+31)
+	for i := uint64(0); len(data) < n; i++ {
+		chunk, err := r.word(ctx, slot.Add(base, i))
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, chunk[:]...)
+	}
+	return data[:n], nil
+}
+
+func (r *Reader) word(ctx context.Context, at common.Hash) (common.Hash, error) {
+	b, err := r.client.StorageAt(ctx, r.Address, at, r.Block)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("read slot %s: %w", at.Hex(), err)
+	}
+	return common.BytesToHash(b), nil
+}
+
+// decodeValue 解码槽内截出的值类型
+func decodeValue(label string, b []byte) any {
+	switch {
+	case label == "bool":
+		return b[len(b)-1] != 0
+	case label == "address" || label == "address payable" || strings.HasPrefix(label, "contract "):
+		return common.BytesToAddress(b)
+	case strings.HasPrefix(label, "int"):
+		n := new(big.Int).SetBytes(b)
+		if len(b) > 0 && b[0]&0x80 != 0 {
+			n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(len(b)*8)))
+		}
+		return n
+	case strings.HasPrefix(label, "uint"), strings.HasPrefix(label, "enum "):
+		return new(big.Int).SetBytes(b)
+	}
+	return hexutil.Bytes(common.CopyBytes(b))
+}
+
+// String 按行输出，结构体和数组逐个列出元素
+func (v *Value) String() string {
+	var sb strings.Builder
+	v.write(&sb)
+	return sb.String()
+}
+
+func (v *Value) write(sb *strings.Builder) {
+	if len(v.Items) == 0 || v.Value != nil {
+		fmt.Fprintf(sb, "%s (%s) = %v  [slot %s", v.Path, v.Type, v.Value, v.Slot.Hex())
+		if v.Offset > 0 {
+			fmt.Fprintf(sb, " offset %d", v.Offset)
+		}
+		sb.WriteString("]\n")
+	}
+	for _, item := range v.Items {
+		item.write(sb)
+	}
+}
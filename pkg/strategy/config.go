@@ -0,0 +1,269 @@
+package strategy
+
+import (
+	"chainget/pkg/expr"
+	"chainget/pkg/helper"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/lmittmann/w3"
+	"gopkg.in/yaml.v3"
+)
+
+// File 策略文件，选择器和事件既可以写十六进制，也可以写函数/事件签名
+//
+//	strategies:
+//	  - name: presale
+//	    trigger: {type: pending, address: "0x332C7b...", selector: "enablePresale()"}
+//	    actions:
+//	      - {to: "0x332C7b...", sig: "presale(uint256)", args: [1], value: "0.01", gas: 250000}
+//	    blocks: 3
+//	    dryRun: true
+type File struct {
+	Strategies []Config `yaml:"strategies"`
+}
+
+// Config 单个策略的配置
+type Config struct {
+	Name    string         `yaml:"name"`
+	Trigger TriggerConfig  `yaml:"trigger"`
+	Actions []ActionConfig `yaml:"actions"`
+	Blocks  uint64         `yaml:"blocks"` // 连续提交的区块数，默认 3
+	DryRun  *bool          `yaml:"dryRun"` // 只模拟不提交，默认 true
+	Once    bool           `yaml:"once"`   // pending、event 触发后不再重复执行；block、time 总是只执行一次
+	Policy  PolicyConfig   `yaml:"policy"`
+}
+
+// TriggerConfig 触发条件
+type TriggerConfig struct {
+	Type     string `yaml:"type"`     // pending | event | block | time
+	Address  string `yaml:"address"`  // pending: 交易接收地址；event: 日志合约地址
+	Selector string `yaml:"selector"` // pending: 方法选择器，为空时匹配任意调用
+	Event    string `yaml:"event"`    // event: 事件签名或 topic0
+	Block    uint64 `yaml:"block"`    // block: 目标区块
+	Time     string `yaml:"time"`     // time: RFC3339 或 unix 秒，出块时间达到后执行
+
+	// Where pending、event 的过滤表达式，见 pkg/expr，如 method == "presale" && tx.value > 1 ether；
+	// 方法和事件参数按 ABI 解码，pending 未配置 ABI 时用 selector 的函数签名
+	Where string         `yaml:"where"`
+	ABI   string         `yaml:"abi"`  // abis 目录下的文件名
+	Vars  map[string]any `yaml:"vars"` // 表达式中的常量
+}
+
+// ActionConfig 动作，参数格式同 helper.PackCall
+type ActionConfig struct {
+	To      string `yaml:"to"`
+	Sig     string `yaml:"sig"`
+	Args    []any  `yaml:"args"`
+	Value   string `yaml:"value"`   // ETH 数量
+	Gas     uint64 `yaml:"gas"`     // 为 0 时估算，依赖触发交易执行结果的调用需要指定
+	Account string `yaml:"account"` // 签名账户名，默认 default
+}
+
+// PolicyConfig 每个账户的花费上限，单位 ETH；调用目标和方法自动限制为 actions 中声明的
+type PolicyConfig struct {
+	MaxValue         string `yaml:"maxValue"`
+	MaxGasSpend      string `yaml:"maxGasSpend"`
+	MinBalanceChange string `yaml:"minBalanceChange"`
+}
+
+// Load 读取并解析策略文件
+func Load(path string) ([]*Strategy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse 解析 YAML 策略定义
+func Parse(data []byte) ([]*Strategy, error) {
+	var f File
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	strategies := make([]*Strategy, 0, len(f.Strategies))
+	names := make(map[string]bool)
+	for _, c := range f.Strategies {
+		s, err := c.Strategy()
+		if err != nil {
+			return nil, err
+		}
+		if names[s.Name] {
+			return nil, fmt.Errorf("strategy %s: duplicate name", s.Name)
+		}
+		names[s.Name] = true
+		strategies = append(strategies, s)
+	}
+	return strategies, nil
+}
+
+// Strategy 把配置转换为策略
+func (c Config) Strategy() (*Strategy, error) {
+	if c.Name == "" {
+		return nil, fmt.Errorf("strategy without name")
+	}
+	s := &Strategy{Name: c.Name, Blocks: c.Blocks, DryRun: true, Once: c.Once}
+	if s.Blocks == 0 {
+		s.Blocks = 3
+	}
+	if c.DryRun != nil {
+		s.DryRun = *c.DryRun
+	}
+	var err error
+	if s.Trigger, err = c.Trigger.trigger(); err != nil {
+		return nil, fmt.Errorf("strategy %s: trigger: %w", c.Name, err)
+	}
+	if len(c.Actions) == 0 {
+		return nil, fmt.Errorf("strategy %s: no actions", c.Name)
+	}
+	for i, a := range c.Actions {
+		action, err := a.action()
+		if err != nil {
+			return nil, fmt.Errorf("strategy %s: action %d: %w", c.Name, i, err)
+		}
+		s.Actions = append(s.Actions, action)
+	}
+	if s.Policy.MaxValue, err = parseLimit(c.Policy.MaxValue); err != nil {
+		return nil, fmt.Errorf("strategy %s: policy.maxValue: %w", c.Name, err)
+	}
+	if s.Policy.MaxGasSpend, err = parseLimit(c.Policy.MaxGasSpend); err != nil {
+		return nil, fmt.Errorf("strategy %s: policy.maxGasSpend: %w", c.Name, err)
+	}
+	if s.Policy.MinBalanceChange, err = parseLimit(c.Policy.MinBalanceChange); err != nil {
+		return nil, fmt.Errorf("strategy %s: policy.minBalanceChange: %w", c.Name, err)
+	}
+	return s, nil
+}
+
+// parseLimit 空字符串表示不限制
+func parseLimit(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	return helper.ParseEther(s)
+}
+
+func (c TriggerConfig) trigger() (Trigger, error) {
+	t := Trigger{Type: TriggerType(c.Type)}
+	switch t.Type {
+	case TriggerPending, TriggerEvent:
+		if !common.IsHexAddress(c.Address) {
+			return t, fmt.Errorf("invalid address %q", c.Address)
+		}
+		t.Address = common.HexToAddress(c.Address)
+		if t.Type == TriggerPending && c.Selector != "" {
+			selector, err := helper.ParseSelector(c.Selector)
+			if err != nil {
+				return t, err
+			}
+			t.Selector = &selector
+		}
+		if t.Type == TriggerEvent && c.Event != "" {
+			if strings.HasPrefix(c.Event, "0x") {
+				t.Topic = common.HexToHash(c.Event)
+			} else {
+				t.Topic = crypto.Keccak256Hash([]byte(c.Event))
+			}
+		}
+	case TriggerBlock:
+		if c.Block == 0 {
+			return t, fmt.Errorf("missing block")
+		}
+		t.Block = c.Block
+	case TriggerTime:
+		if c.Time == "" {
+			return t, fmt.Errorf("missing time")
+		}
+		if unix, err := strconv.ParseInt(c.Time, 10, 64); err == nil {
+			t.Time = time.Unix(unix, 0)
+		} else if t.Time, err = time.Parse(time.RFC3339, c.Time); err != nil {
+			return t, fmt.Errorf("invalid time %q", c.Time)
+		}
+	default:
+		return t, fmt.Errorf("unknown type %q", c.Type)
+	}
+	if c.Where != "" {
+		if err := c.where(&t); err != nil {
+			return t, fmt.Errorf("where: %w", err)
+		}
+	}
+	return t, nil
+}
+
+// where 按 ABI 中每个方法（事件）编译过滤表达式；无法解码的交易和日志按空名字匹配，只能引用 tx.*、log.* 等公共字段
+func (c TriggerConfig) where(t *Trigger) error {
+	if t.Type != TriggerPending && t.Type != TriggerEvent {
+		return fmt.Errorf("not supported by %s trigger", t.Type)
+	}
+	var contractAbi abi.ABI
+	if c.ABI != "" {
+		var err error
+		if contractAbi, err = abi.JSON(strings.NewReader(helper.ReadAbiJson(c.ABI))); err != nil {
+			return fmt.Errorf("abi: %w", err)
+		}
+	} else if t.Type == TriggerPending && c.Selector != "" && !strings.HasPrefix(c.Selector, "0x") {
+		fn, err := w3.NewFunc(c.Selector, "")
+		if err != nil {
+			return err
+		}
+		// w3 解析出的类型没有规范名，选择器和签名直接用 w3 算好的
+		name := c.Selector[:strings.Index(c.Selector, "(")]
+		m := abi.NewMethod(name, name, abi.Function, "", false, false, fn.Args, nil)
+		m.Sig, m.ID = fn.Signature, fn.Selector[:]
+		contractAbi.Methods = map[string]abi.Method{name: m}
+	}
+	env := expr.NewEnv()
+	if err := env.Consts(c.Vars); err != nil {
+		return err
+	}
+	envs := make(map[string]*expr.Env)
+	if t.Type == TriggerPending {
+		envs[""] = expr.TxEnv(env)
+		for name, m := range contractAbi.Methods {
+			envs[name] = expr.MethodEnv(env, m)
+		}
+	} else {
+		envs[""] = expr.EventEnv(env, abi.Event{})
+		for name, e := range contractAbi.Events {
+			envs[name] = expr.EventEnv(env, e)
+		}
+	}
+	rule, err := expr.CompileRule(c.Where, envs)
+	if err != nil {
+		return err
+	}
+	t.Where, t.ABI = rule, &contractAbi
+	return nil
+}
+
+func (c ActionConfig) action() (Action, error) {
+	a := Action{Sig: c.Sig, Args: c.Args, Gas: c.Gas, Account: c.Account, Value: new(big.Int)}
+	if !common.IsHexAddress(c.To) {
+		return a, fmt.Errorf("invalid to %q", c.To)
+	}
+	a.To = common.HexToAddress(c.To)
+	if a.Account == "" {
+		a.Account = DefaultAccount
+	}
+	var err error
+	if a.Data, err = helper.PackCall(c.Sig, c.Args...); err != nil {
+		return a, err
+	}
+	if a.Selector, err = helper.ParseSelector(c.Sig); err != nil {
+		return a, err
+	}
+	if c.Value != "" {
+		if a.Value, err = helper.ParseEther(c.Value); err != nil {
+			return a, fmt.Errorf("value: %w", err)
+		}
+	}
+	return a, nil
+}
@@ -0,0 +1,351 @@
+package strategy
+
+import (
+	"chainget/pkg/bundle"
+	"chainget/pkg/nonce"
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/lmittmann/flashbots"
+	"github.com/lmittmann/w3"
+	"github.com/lmittmann/w3/module/eth"
+	"github.com/lmittmann/w3/w3types"
+)
+
+// Engine 订阅 pending 交易、日志和新区块，触发命中后按策略签名动作并以 bundle 提交
+type Engine struct {
+	Strategies []*Strategy
+
+	client  *w3.Client        // HTTP 节点，查询参数、估算 gas、跟踪收据
+	stream  *ethclient.Client // websocket 节点，订阅 pending 交易、日志和新区块
+	relay   *bundle.Sender
+	subs    *bundle.SubmissionTracker
+	nonces  *nonce.Manager
+	fees    *bundle.FeeOracle
+	signers map[string]*bundle.Signer
+
+	mu   sync.Mutex
+	done map[string]bool // 已执行过的一次性策略
+
+	reserveMu sync.Mutex
+	reserved  map[string]map[string]*nonce.Reservation // 策略最近一次提交占用的 nonce，新版本提交前归还
+}
+
+// NewEngine 创建引擎，accounts 为动作 account 名到私钥的映射，所有动作引用的账户都必须存在
+func NewEngine(client *w3.Client, stream *ethclient.Client, relay *bundle.Sender, nonces *nonce.Manager, chainID *big.Int, accounts map[string]*ecdsa.PrivateKey, strategies []*Strategy) (*Engine, error) {
+	e := &Engine{
+		Strategies: strategies,
+		client:     client,
+		stream:     stream,
+		relay:      relay,
+		subs:       bundle.NewSubmissionTracker(relay),
+		nonces:     nonces,
+		fees:       bundle.NewFeeOracle(client),
+		signers:    make(map[string]*bundle.Signer),
+		done:       make(map[string]bool),
+		reserved:   make(map[string]map[string]*nonce.Reservation),
+	}
+	for name, key := range accounts {
+		e.signers[name] = bundle.NewSigner(key, chainID)
+	}
+	for _, s := range strategies {
+		for _, a := range s.Actions {
+			if _, ok := e.signers[a.Account]; !ok {
+				return nil, fmt.Errorf("strategy %s: unknown account %q", s.Name, a.Account)
+			}
+		}
+	}
+	return e, nil
+}
+
+// Run 订阅所需的数据源并分发触发，直到 ctx 取消或订阅出错
+func (e *Engine) Run(ctx context.Context) error {
+	var pending, events []*Strategy
+	for _, s := range e.Strategies {
+		switch s.Trigger.Type {
+		case TriggerPending:
+			pending = append(pending, s)
+		case TriggerEvent:
+			events = append(events, s)
+		}
+	}
+
+	heads := make(chan *types.Header)
+	headSub, err := e.stream.SubscribeNewHead(ctx, heads)
+	if err != nil {
+		return fmt.Errorf("订阅新区块失败: %w", err)
+	}
+	defer headSub.Unsubscribe()
+
+	var (
+		txHashes = make(chan common.Hash, 256)
+		txErr    <-chan error
+	)
+	if len(pending) > 0 {
+		sub, err := e.stream.Client().EthSubscribe(ctx, txHashes, "newPendingTransactions")
+		if err != nil {
+			return fmt.Errorf("订阅 pending 交易失败: %w", err)
+		}
+		defer sub.Unsubscribe()
+		txErr = sub.Err()
+	}
+
+	var (
+		logs   = make(chan types.Log, 256)
+		logErr <-chan error
+	)
+	if len(events) > 0 {
+		q := ethereum.FilterQuery{}
+		for _, s := range events {
+			q.Addresses = append(q.Addresses, s.Trigger.Address)
+		}
+		sub, err := e.stream.SubscribeFilterLogs(ctx, q, logs)
+		if err != nil {
+			return fmt.Errorf("订阅日志失败: %w", err)
+		}
+		defer sub.Unsubscribe()
+		logErr = sub.Err()
+	}
+
+	log.Printf("✔️ 策略引擎启动，策略数: %d", len(e.Strategies))
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-headSub.Err():
+			return fmt.Errorf("新区块订阅错误: %w", err)
+		case err := <-txErr:
+			return fmt.Errorf("pending 交易订阅错误: %w", err)
+		case err := <-logErr:
+			return fmt.Errorf("日志订阅错误: %w", err)
+		case head := <-heads:
+			if err := e.nonces.SyncAll(ctx, head.Number.Uint64()); err != nil {
+				log.Printf("❌ 区块 %d 同步 nonce 失败: %v", head.Number, err)
+			}
+			for _, s := range e.Strategies {
+				if s.Trigger.Due(head) {
+					e.fire(ctx, s, nil)
+				}
+			}
+		case hash := <-txHashes:
+			tx, isPending, err := e.stream.TransactionByHash(ctx, hash)
+			if err != nil || !isPending {
+				continue
+			}
+			for _, s := range pending {
+				if s.Trigger.MatchTx(tx) {
+					log.Printf("✔️ 策略 %s 命中 pending 交易 %s", s.Name, tx.Hash().Hex())
+					e.fire(ctx, s, tx)
+				}
+			}
+		case l := <-logs:
+			for _, s := range events {
+				if s.Trigger.MatchLog(&l) {
+					log.Printf("✔️ 策略 %s 命中事件 tx: %s, block: %d", s.Name, l.TxHash.Hex(), l.BlockNumber)
+					e.fire(ctx, s, nil)
+				}
+			}
+		}
+	}
+}
+
+// fire 异步执行策略，一次性策略只提交一次：执行期间占住，避免重复触发；
+// 提交前失败或 bundle 没有上链时撤销，下次触发时重试，已提交的不会因为等待出错而重复提交
+func (e *Engine) fire(ctx context.Context, s *Strategy, trigger *types.Transaction) {
+	once := s.Once || s.Trigger.Type == TriggerBlock || s.Trigger.Type == TriggerTime
+	if once {
+		e.mu.Lock()
+		done := e.done[s.Name]
+		e.done[s.Name] = true
+		e.mu.Unlock()
+		if done {
+			return
+		}
+	}
+	go func() {
+		submitted, err := e.execute(ctx, s, trigger)
+		if err == nil {
+			return
+		}
+		log.Printf("❌ 策略 %s 执行失败: %v", s.Name, err)
+		if once && (!submitted || errors.Is(err, bundle.ErrNotIncluded)) {
+			e.mu.Lock()
+			delete(e.done, s.Name)
+			e.mu.Unlock()
+		}
+	}()
+}
+
+// Execute 签名策略动作并提交 bundle；trigger 不为空时（pending 触发）放在 bundle 最前面
+func (e *Engine) Execute(ctx context.Context, s *Strategy, trigger *types.Transaction) error {
+	_, err := e.execute(ctx, s, trigger)
+	return err
+}
+
+// execute 同 Execute，submitted 表示 bundle 已提交给 relay
+func (e *Engine) execute(ctx context.Context, s *Strategy, trigger *types.Transaction) (submitted bool, err error) {
+	var head *big.Int
+	if err := e.client.CallCtx(ctx, eth.BlockNumber().Returns(&head)); err != nil {
+		return false, fmt.Errorf("获取区块高度失败: %w", err)
+	}
+	from, to := head.Uint64()+1, head.Uint64()+s.Blocks
+	if s.Trigger.Type == TriggerBlock && from < s.Trigger.Block {
+		from, to = s.Trigger.Block, s.Trigger.Block+s.Blocks-1
+	}
+
+	fees, err := e.fees.Suggest(ctx)
+	if err != nil {
+		return false, fmt.Errorf("获取 gas 报价失败: %w", err)
+	}
+
+	counts := make(map[string]int)
+	for _, a := range s.Actions {
+		counts[a.Account]++
+	}
+	reservations, err := e.reserve(ctx, s.Name, counts, to)
+	if err != nil {
+		return false, fmt.Errorf("分配 nonce 失败: %w", err)
+	}
+	release := func() {
+		for _, r := range reservations {
+			r.Release()
+		}
+	}
+
+	b := &bundle.Bundle{BlockNumber: new(big.Int).SetUint64(from)}
+	if trigger != nil {
+		b.Txs = append(b.Txs, trigger)
+	}
+	var (
+		ours types.Transactions
+		used = make(map[string]int)
+	)
+	for i, a := range s.Actions {
+		signer := e.signers[a.Account]
+		gas, err := e.gas(ctx, signer.Address, a, trigger != nil)
+		if err != nil {
+			release()
+			return false, fmt.Errorf("action %d: %w", i, err)
+		}
+		n := reservations[a.Account].Nonces[used[a.Account]]
+		used[a.Account]++
+		tx, err := signer.Sign(fees.Tx(signer.ChainID, n, a.To, a.Value, gas, a.Data))
+		if err != nil {
+			release()
+			return false, fmt.Errorf("action %d: 签名失败: %w", i, err)
+		}
+		ours = append(ours, tx)
+	}
+	b.Txs = append(b.Txs, ours...)
+
+	res, err := e.relay.Simulate(ctx, b)
+	if err != nil {
+		release()
+		return false, fmt.Errorf("模拟 bundle 失败: %w", err)
+	}
+	if err := b.Check(res); err != nil {
+		release()
+		return false, fmt.Errorf("模拟 bundle 未通过: %w", err)
+	}
+	for name := range counts {
+		if err := e.evaluate(ctx, s, b, res, e.signers[name].Address); err != nil {
+			release()
+			return false, fmt.Errorf("账户 %s: %w", name, err)
+		}
+	}
+	log.Printf("✔️ 策略 %s 模拟通过 bundleHash: %s, gasUsed: %d", s.Name, res.BundleHash.Hex(), res.TotalGasUsed)
+	if s.DryRun {
+		release()
+		log.Printf("✔️ 策略 %s dry-run，不提交", s.Name)
+		return false, nil
+	}
+
+	key := "strategy:" + s.Name
+	sub, err := e.subs.Submit(ctx, key, b, from, to)
+	if err != nil {
+		release()
+		return false, err
+	}
+	log.Printf("✔️ 策略 %s bundle v%d 已提交到区块 %d-%d", s.Name, sub.Version, from, to)
+
+	receipts, err := bundle.NewTracker(e.client).Wait(ctx, (&bundle.Bundle{Txs: ours}).Hashes(), to)
+	if current, ok := e.subs.Current(key); ok && current == sub {
+		e.subs.Done(key)
+	}
+	if errors.Is(err, bundle.ErrNotIncluded) {
+		release()
+	}
+	if err != nil {
+		return true, fmt.Errorf("等待上链失败: %w", err)
+	}
+	for _, receipt := range receipts {
+		log.Printf("✔️ 策略 %s 已上链 tx: %s, block: %d, status: %d", s.Name, receipt.TxHash.Hex(), receipt.BlockNumber, receipt.Status)
+	}
+	return true, nil
+}
+
+// reserve 每个账户按动作顺序分配连续 nonce；同一策略上一版 bundle 会被新版本撤回，先归还它的 nonce 供复用
+func (e *Engine) reserve(ctx context.Context, name string, counts map[string]int, maxBlock uint64) (map[string]*nonce.Reservation, error) {
+	e.reserveMu.Lock()
+	defer e.reserveMu.Unlock()
+	for _, r := range e.reserved[name] {
+		r.Release()
+	}
+	reservations := make(map[string]*nonce.Reservation)
+	for account, n := range counts {
+		r, err := e.nonces.Reserve(ctx, e.signers[account].Address, n, maxBlock)
+		if err != nil {
+			for _, r := range reservations {
+				r.Release()
+			}
+			return nil, err
+		}
+		reservations[account] = r
+	}
+	e.reserved[name] = reservations
+	return reservations, nil
+}
+
+// gas 动作未指定 gas 时估算并加 20% 余量；pending 触发的动作依赖触发交易的执行结果，必须指定 gas
+func (e *Engine) gas(ctx context.Context, from common.Address, a Action, afterTrigger bool) (uint64, error) {
+	if a.Gas != 0 {
+		return a.Gas, nil
+	}
+	if afterTrigger {
+		return 0, fmt.Errorf("%s: pending 触发的动作需要指定 gas", a.Sig)
+	}
+	var gas uint64
+	if err := e.client.CallCtx(ctx, eth.EstimateGas(&w3types.Message{From: from, To: &a.To, Value: a.Value, Input: a.Data}, nil).Returns(&gas)); err != nil {
+		return 0, fmt.Errorf("%s: 估算 gas 失败: %w", a.Sig, err)
+	}
+	return gas * 12 / 10, nil
+}
+
+// evaluate 用策略的花费上限和动作声明的目标、方法检查 account 的交易
+func (e *Engine) evaluate(ctx context.Context, s *Strategy, b *bundle.Bundle, res *flashbots.CallBundleResponse, account common.Address) error {
+	policy := &bundle.Policy{
+		Account:          account,
+		MaxValue:         s.Policy.MaxValue,
+		MaxGasSpend:      s.Policy.MaxGasSpend,
+		MinBalanceChange: s.Policy.MinBalanceChange,
+	}
+	for _, a := range s.Actions {
+		policy.AllowedTargets = append(policy.AllowedTargets, a.To)
+		policy.AllowedSelectors = append(policy.AllowedSelectors, a.Selector)
+	}
+	change, baseFee, err := bundle.BalanceChange(ctx, e.client, b, account)
+	if err != nil {
+		return fmt.Errorf("计算余额变化失败: %w", err)
+	}
+	_, err = policy.Evaluate(b, res, baseFee, change)
+	return err
+}
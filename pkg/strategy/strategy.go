@@ -0,0 +1,132 @@
+package strategy
+
+import (
+	"chainget/pkg/decode"
+	"chainget/pkg/expr"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// TriggerType 触发类型
+type TriggerType string
+
+const (
+	TriggerPending TriggerType = "pending" // 交易池中出现对 Address 的调用，bundle 为 [触发交易, 动作...]
+	TriggerEvent   TriggerType = "event"   // Address 产生事件且已上链，动作提交到后续区块
+	TriggerBlock   TriggerType = "block"   // 动作提交到 Block
+	TriggerTime    TriggerType = "time"    // 下一个块的出块时间达到 Time 时提交
+)
+
+// DefaultAccount 未指定 account 的动作使用的签名账户
+const DefaultAccount = "default"
+
+// Strategy 一个触发条件加一组动作
+type Strategy struct {
+	Name    string
+	Trigger Trigger
+	Actions []Action
+	Blocks  uint64
+	DryRun  bool
+	Once    bool
+	Policy  Limits
+}
+
+// Trigger 解析后的触发条件
+type Trigger struct {
+	Type     TriggerType
+	Address  common.Address
+	Selector *[4]byte    // pending，nil 表示任意调用
+	Topic    common.Hash // event，零值表示任意事件
+	Block    uint64
+	Time     time.Time
+	Where    *expr.Rule // pending、event 的过滤表达式，nil 表示不过滤
+	ABI      *abi.ABI   // 解码 Where 引用的方法、事件参数
+}
+
+// Action 解析后的动作，calldata 在加载时打包好
+type Action struct {
+	To       common.Address
+	Sig      string
+	Args     []any
+	Data     []byte
+	Selector [4]byte
+	Value    *big.Int
+	Gas      uint64
+	Account  string
+}
+
+// Limits 策略对每个签名账户的花费上限，nil 表示不限制
+type Limits struct {
+	MaxValue         *big.Int
+	MaxGasSpend      *big.Int
+	MinBalanceChange *big.Int
+}
+
+// MatchTx 判断 pending 交易是否命中
+func (t *Trigger) MatchTx(tx *types.Transaction) bool {
+	if t.Type != TriggerPending || tx.To() == nil || *tx.To() != t.Address {
+		return false
+	}
+	if t.Selector != nil && (len(tx.Data()) < 4 || [4]byte(tx.Data()[:4]) != *t.Selector) {
+		return false
+	}
+	if t.Where == nil {
+		return true
+	}
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return false
+	}
+	var name string
+	method, args, err := expr.DecodeCall(*t.ABI, tx.Data())
+	if err == nil {
+		name = method.Name
+	} else {
+		method, args = nil, nil
+	}
+	return t.match(name, expr.TxVars(tx, from, method, args), tx.Hash())
+}
+
+// MatchLog 判断日志是否命中
+func (t *Trigger) MatchLog(l *types.Log) bool {
+	if t.Type != TriggerEvent || l.Address != t.Address || l.Removed {
+		return false
+	}
+	if t.Topic != (common.Hash{}) && (len(l.Topics) == 0 || l.Topics[0] != t.Topic) {
+		return false
+	}
+	if t.Where == nil {
+		return true
+	}
+	r, err := decode.NewDecoder(*t.ABI).Decode(*l, time.Time{})
+	if err != nil {
+		r = &decode.Record{Address: l.Address, BlockNumber: l.BlockNumber, TxHash: l.TxHash, LogIndex: l.Index}
+	}
+	return t.match(r.Event, expr.RecordVars(r), l.TxHash)
+}
+
+// match 求值出错按未命中处理
+func (t *Trigger) match(name string, vars map[string]any, tx common.Hash) bool {
+	ok, err := t.Where.Match(name, vars)
+	if err != nil {
+		log.Printf("⚠️ 过滤表达式求值失败 tx: %s: %v", tx.Hex(), err)
+	}
+	return ok
+}
+
+// Due 判断 block、time 触发在新区块 head 到来后是否应该提交，目标区块为 head+1
+func (t *Trigger) Due(head *types.Header) bool {
+	switch t.Type {
+	case TriggerBlock:
+		return head.Number.Uint64()+1 >= t.Block
+	case TriggerTime:
+		//按 12 秒出块估算下一个块的时间
+		return int64(head.Time)+12 >= t.Time.Unix()
+	}
+	return false
+}
@@ -0,0 +1,225 @@
+package watch
+
+import (
+	"chainget/pkg/decode"
+	"chainget/pkg/expr"
+	"chainget/pkg/factory"
+	"chainget/pkg/helper"
+	"chainget/pkg/notify"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"gopkg.in/yaml.v3"
+)
+
+// File 监听定义文件，所有 watch 在一个进程内并发运行，新增合约只需要改配置
+//
+//	chains:
+//	  eth: {rpc: ["wss://ethereum-rpc.publicnode.com"], slotTime: 12s}
+//	watches:
+//	  - name: usdt-whale
+//	    chain: eth
+//	    addresses: ["0xdac17f958d2ee523a2206206994597c13d831ec7"]
+//	    abi: erc20_abi.json
+//	    events: [Transfer]
+//	    filters: {to: ["0x28C6c06298d514Db089934071355E5743bf21d60"]}
+//	    decimals: {value: 6}
+//	    sinks: [stdout, notify]
+type File struct {
+	Chains  map[string]ChainConfig `yaml:"chains"`
+	Watches []Config               `yaml:"watches"`
+	Notify  *notify.Config         `yaml:"notify"`
+}
+
+// ChainConfig 一条链的节点，多个 http(s) 地址组成节点池
+type ChainConfig struct {
+	RPC          []string      `yaml:"rpc"`
+	PollInterval time.Duration `yaml:"pollInterval"` // http(s) 节点的轮询间隔
+	SlotTime     time.Duration `yaml:"slotTime"`     // 出块周期，为 0 时不检测错过的 slot
+}
+
+// Config 单个监听定义
+type Config struct {
+	Name      string            `yaml:"name"`
+	Chain     string            `yaml:"chain"`
+	Addresses []string          `yaml:"addresses"`
+	ABI       string            `yaml:"abi"`      // abis 目录下的文件名
+	Events    []string          `yaml:"events"`   // 为空时接收 ABI 中的全部事件
+	Filters   map[string]any    `yaml:"filters"`  // 参数名 → 值或值列表，只对含该参数的事件生效
+	Decimals  map[string]uint8  `yaml:"decimals"` // 参数名 → 精度
+	Amounts   map[string]string `yaml:"amounts"`  // 参数名 → 代币地址所在的参数名，为空表示发出事件的合约；精度从链上查询
+	Times     []string          `yaml:"times"`    // 按时间显示的参数名
+	Where     string            `yaml:"where"`    // 过滤表达式，见 pkg/expr，如 value >= 200000 * 10**decimals && to in $exchanges
+	Vars      map[string]any    `yaml:"vars"`     // 表达式中的常量；decimals 只配置了一种精度时默认为该精度
+	Sinks     []string          `yaml:"sinks"`    // stdout | json | file:<path> | notify，默认 stdout
+	Factory   *FactoryConfig    `yaml:"factory"`  // 地址为工厂合约时，自动监听它创建的子合约
+}
+
+// FactoryConfig 工厂事件和子合约 ABI，见 pkg/factory
+type FactoryConfig struct {
+	Event    string `yaml:"event"`
+	ChildArg string `yaml:"childArg"`
+	ChildABI string `yaml:"childAbi"`
+	Store    string `yaml:"store"` // 已发现子合约的保存文件，默认 <name>_children.json
+}
+
+// Load 读取监听定义文件
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f File
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// Watch 编译后的监听定义
+type Watch struct {
+	Name      string
+	Chain     string
+	Addresses []common.Address
+	ABI       abi.ABI
+	Events    []*abi.Event // 为空时全部事件
+	Filters   []*Filter
+	Where     *expr.Rule // 为空时不过滤
+	Decimals  map[string]uint8
+	Amounts   map[string]string
+	Times     []string
+	Sinks     []string
+	Factory   *factory.Factory
+	Store     string
+}
+
+// Watch 解析 ABI，校验事件名和过滤参数
+func (c Config) Watch() (*Watch, error) {
+	if c.Name == "" {
+		return nil, fmt.Errorf("watch without name")
+	}
+	w := &Watch{Name: c.Name, Chain: c.Chain, Decimals: c.Decimals, Amounts: c.Amounts, Times: c.Times, Sinks: c.Sinks}
+	if len(w.Sinks) == 0 {
+		w.Sinks = []string{"stdout"}
+	}
+	if len(c.Addresses) == 0 {
+		return nil, fmt.Errorf("watch %s: no addresses", c.Name)
+	}
+	for _, a := range c.Addresses {
+		if !common.IsHexAddress(a) {
+			return nil, fmt.Errorf("watch %s: invalid address %q", c.Name, a)
+		}
+		w.Addresses = append(w.Addresses, common.HexToAddress(a))
+	}
+	var err error
+	if w.ABI, err = abi.JSON(strings.NewReader(helper.ReadAbiJson(c.ABI))); err != nil {
+		return nil, fmt.Errorf("watch %s: abi: %w", c.Name, err)
+	}
+	if c.Factory != nil {
+		if len(w.Addresses) != 1 {
+			return nil, fmt.Errorf("watch %s: factory watch needs exactly one address", c.Name)
+		}
+		w.Factory, err = factory.Config{
+			Name:     c.Name,
+			Address:  c.Addresses[0],
+			ABI:      c.ABI,
+			Event:    c.Factory.Event,
+			ChildArg: c.Factory.ChildArg,
+			ChildABI: c.Factory.ChildABI,
+		}.Factory()
+		if err != nil {
+			return nil, err
+		}
+		w.Store = c.Factory.Store
+		if w.Store == "" {
+			w.Store = c.Name + "_children.json"
+		}
+	}
+	for _, name := range c.Events {
+		event, ok := w.ABI.Events[name]
+		if !ok && w.Factory != nil {
+			event, ok = w.Factory.ChildABI.Events[name]
+		}
+		if !ok {
+			return nil, fmt.Errorf("watch %s: event %s not found in %s", c.Name, name, c.ABI)
+		}
+		w.Events = append(w.Events, &event)
+	}
+	for arg, value := range c.Filters {
+		f, err := newFilter(w.events(), arg, value)
+		if err != nil {
+			return nil, fmt.Errorf("watch %s: filter %s: %w", c.Name, arg, err)
+		}
+		w.Filters = append(w.Filters, f)
+	}
+	if c.Where != "" {
+		if w.Where, err = c.where(w.events()); err != nil {
+			return nil, fmt.Errorf("watch %s: where: %w", c.Name, err)
+		}
+	}
+	return w, nil
+}
+
+// where 按每个监听的事件编译过滤表达式，只对能编译通过（参数都存在）的事件生效
+func (c Config) where(events []*abi.Event) (*expr.Rule, error) {
+	env := expr.NewEnv()
+	if decimals := slices.Compact(slices.Sorted(maps.Values(c.Decimals))); len(decimals) == 1 {
+		env.Const("decimals", decimals[0])
+	}
+	if err := env.Consts(c.Vars); err != nil {
+		return nil, err
+	}
+	return expr.CompileRule(c.Where, expr.EventEnvs(env, events))
+}
+
+// events 监听的事件，未指定时为 ABI（工厂监听还包括子合约 ABI）中的全部事件
+func (w *Watch) events() []*abi.Event {
+	if len(w.Events) > 0 {
+		return w.Events
+	}
+	var events []*abi.Event
+	for _, e := range w.ABI.Events {
+		events = append(events, &e)
+	}
+	if w.Factory != nil {
+		for _, e := range w.Factory.ChildABI.Events {
+			events = append(events, &e)
+		}
+	}
+	return events
+}
+
+// ownEvents 监听地址自己发出的事件（不含工厂子合约的事件和匿名事件）
+func (w *Watch) ownEvents() []*abi.Event {
+	var events []*abi.Event
+	for _, e := range w.events() {
+		if own, ok := w.ABI.Events[e.Name]; ok && own.ID == e.ID && !e.Anonymous {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// match 事件在监听范围内且通过所有过滤条件
+func (w *Watch) match(r *decode.Record) bool {
+	if len(w.Events) > 0 && !slices.ContainsFunc(w.Events, func(e *abi.Event) bool { return e.Name == r.Event }) {
+		return false
+	}
+	for _, f := range w.Filters {
+		if !f.Match(r) {
+			return false
+		}
+	}
+	ok, err := w.Where.Match(r.Event, expr.RecordVars(r))
+	if err != nil {
+		log.Printf("⚠️ [%s] 过滤表达式求值失败 tx: %s: %v", w.Name, r.TxHash.Hex(), err)
+	}
+	return ok
+}
@@ -0,0 +1,387 @@
+package watch
+
+import (
+	"bytes"
+	"chainget/pkg/decode"
+	"chainget/pkg/endpoint"
+	"chainget/pkg/factory"
+	"chainget/pkg/head"
+	"chainget/pkg/multicall"
+	"chainget/pkg/poll"
+	"chainget/pkg/proxy"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Engine 在一个进程内并发运行所有监听定义，每条链共用一个连接和区块头服务
+type Engine struct {
+	chains  map[string]*chain
+	watches []*Watch
+	outputs map[*Watch][]Sink
+	sinks   *sinks
+}
+
+// chain 一条链的连接、区块头服务和区块时间缓存
+type chain struct {
+	name    string
+	client  poll.Backend
+	tracker *head.Tracker
+	tokens  *decode.TokenCache //查询金额参数的代币精度，客户端不支持 JSON-RPC 批量调用时为 nil
+
+	mu    sync.Mutex
+	times map[uint64]time.Time
+}
+
+// New 编译监听定义并连接用到的链；配置错误在启动时全部报出
+func New(f *File) (*Engine, error) {
+	e := &Engine{chains: make(map[string]*chain), outputs: make(map[*Watch][]Sink)}
+	e.sinks = &sinks{byName: make(map[string]Sink)}
+	if f.Notify != nil {
+		n, err := f.Notify.Notifier()
+		if err != nil {
+			return nil, fmt.Errorf("notify: %w", err)
+		}
+		e.sinks.notifier = n
+	}
+	names := make(map[string]bool)
+	for _, c := range f.Watches {
+		w, err := c.Watch()
+		if err != nil {
+			return nil, err
+		}
+		if names[w.Name] {
+			return nil, fmt.Errorf("watch %s: duplicate name", w.Name)
+		}
+		names[w.Name] = true
+		cc, ok := f.Chains[w.Chain]
+		if !ok {
+			return nil, fmt.Errorf("watch %s: unknown chain %q", w.Name, w.Chain)
+		}
+		for _, name := range w.Sinks {
+			sink, err := e.sinks.get(name)
+			if err != nil {
+				return nil, fmt.Errorf("watch %s: %w", w.Name, err)
+			}
+			e.outputs[w] = append(e.outputs[w], sink)
+		}
+		if _, ok := e.chains[w.Chain]; !ok {
+			client, err := dial(cc)
+			if err != nil {
+				return nil, fmt.Errorf("chain %s: %w", w.Chain, err)
+			}
+			c := &chain{
+				name:    w.Chain,
+				client:  client,
+				tracker: head.NewTracker(client, cc.SlotTime),
+				times:   make(map[uint64]time.Time),
+			}
+			if rc, ok := client.(interface{ Client() *rpc.Client }); ok {
+				c.tokens = decode.NewTokenCache(multicall.New(rc.Client()))
+			}
+			e.chains[w.Chain] = c
+		}
+		e.watches = append(e.watches, w)
+	}
+	if len(e.watches) == 0 {
+		return nil, errors.New("no watches")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := e.checkProxies(ctx); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// dial 单个地址直接连接（http(s) 轮询），多个 http(s) 地址组成节点池
+func dial(c ChainConfig) (poll.Backend, error) {
+	switch len(c.RPC) {
+	case 0:
+		return nil, errors.New("no rpc")
+	case 1:
+		return poll.Dial(c.RPC[0], c.PollInterval)
+	}
+	pool, err := endpoint.New(c.RPC)
+	if err != nil {
+		return nil, err
+	}
+	go pool.Run(context.Background())
+	return poll.New(pool.Client(), c.PollInterval), nil
+}
+
+// Run 阻塞运行到 ctx 取消，单个 watch 出错只会让它自己重新订阅
+func (e *Engine) Run(ctx context.Context) error {
+	defer e.sinks.close()
+	var wg sync.WaitGroup
+	for _, c := range e.chains {
+		wg.Add(1)
+		go func(c *chain) {
+			defer wg.Done()
+			c.tracker.Run(ctx)
+		}(c)
+	}
+	for _, w := range e.watches {
+		wg.Add(1)
+		go func(w *Watch) {
+			defer wg.Done()
+			e.run(ctx, w)
+		}(w)
+	}
+	log.Printf("✔️ 启动 %d 个监听，%d 条链", len(e.watches), len(e.chains))
+	wg.Wait()
+	for _, c := range e.chains {
+		c.client.Close()
+	}
+	return ctx.Err()
+}
+
+// checkProxies 监听地址是代理合约时，事件由代理地址发出，abi 应为实现合约的 ABI：
+// 在实现合约字节码中查找监听事件的 topic0，一个都没有时报错，部分没有时提示。查询失败只提示，不影响启动
+func (e *Engine) checkProxies(ctx context.Context) error {
+	for _, w := range e.watches {
+		client, ok := e.chains[w.Chain].client.(proxy.Client)
+		if !ok {
+			continue
+		}
+		events := w.ownEvents()
+		if len(events) == 0 {
+			continue
+		}
+		r := proxy.NewResolver(client)
+		for _, address := range w.Addresses {
+			p, err := r.Resolve(ctx, address)
+			if err != nil {
+				log.Printf("⚠️ [%s] 检查代理合约 %s 失败: %v", w.Name, address.Hex(), err)
+				continue
+			}
+			if p.Kind == proxy.None {
+				continue
+			}
+			code, err := client.CodeAt(ctx, p.Target(), nil)
+			if err != nil {
+				log.Printf("⚠️ [%s] 读取实现合约 %s 字节码失败: %v", w.Name, p.Target().Hex(), err)
+				continue
+			}
+			missing := missingEvents(code, events)
+			switch {
+			case len(missing) == len(events):
+				return fmt.Errorf("watch %s: %s 是 %s 代理，实现合约 %s 不会发出任何监听事件 %v，abi 应为实现合约的 ABI",
+					w.Name, address.Hex(), p.Kind, p.Target().Hex(), missing)
+			case len(missing) > 0:
+				log.Printf("⚠️ [%s] %s 是 %s 代理，实现合约 %s 中没有事件 %v", w.Name, address.Hex(), p.Kind, p.Target().Hex(), missing)
+			default:
+				log.Printf("✔️ [%s] %s 是 %s 代理，实现合约 %s 与 abi 一致", w.Name, address.Hex(), p.Kind, p.Target().Hex())
+			}
+		}
+	}
+	return nil
+}
+
+// missingEvents 字节码中找不到 topic0 的事件：emit 会把 topic0 作为 PUSH32 常量写进字节码
+func missingEvents(code []byte, events []*abi.Event) []string {
+	var missing []string
+	for _, event := range events {
+		if !bytes.Contains(code, event.ID.Bytes()) {
+			missing = append(missing, event.Name)
+		}
+	}
+	return missing
+}
+
+// run 出错后 1 秒重试，普通监听会从上次处理到的日志补齐中断期间的日志
+func (e *Engine) run(ctx context.Context, w *Watch) {
+	c := e.chains[w.Chain]
+	var cursor poll.Cursor
+	for ctx.Err() == nil {
+		var err error
+		if w.Factory != nil {
+			err = e.runFactory(ctx, w, c)
+		} else {
+			err = e.runLogs(ctx, w, c, &cursor)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("⚠️ [%s] 监听中断，1 秒后重试: %v", w.Name, err)
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (e *Engine) runLogs(ctx context.Context, w *Watch, c *chain, cursor *poll.Cursor) error {
+	d := e.decoder(w.ABI, w, c)
+	logs := make(chan types.Log, 256)
+	q := w.query()
+	sub, err := c.client.SubscribeFilterLogs(ctx, q, logs)
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+	//丢掉补齐和订阅重复推送的已处理日志，被重组移除的日志总是输出
+	next := func(l types.Log) {
+		if !l.Removed && cursor.Covers(l) {
+			return
+		}
+		e.handle(w, c, d, l)
+		*cursor = cursor.Next(l)
+	}
+	if !cursor.IsZero() {
+		head, err := c.client.BlockNumber(ctx)
+		if err != nil {
+			return err
+		}
+		//从游标所在区块补齐，同一区块中断后的日志也不会漏掉
+		if head >= cursor.Block {
+			q.FromBlock, q.ToBlock = new(big.Int).SetUint64(cursor.Block), new(big.Int).SetUint64(head)
+			missed, err := c.client.FilterLogs(ctx, q)
+			if err != nil {
+				return err
+			}
+			for _, l := range missed {
+				next(l)
+			}
+			*cursor = poll.BlockCursor(head)
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-sub.Err():
+			return err
+		case l := <-logs:
+			next(l)
+		}
+	}
+}
+
+func (e *Engine) runFactory(ctx context.Context, w *Watch, c *chain) error {
+	store, err := factory.OpenStore(w.Store)
+	if err != nil {
+		return err
+	}
+	fw := factory.NewWatcher(c.client, store, []*factory.Factory{w.Factory})
+	fw.BlockTime = c.blockTime
+	fw.NewDecoder = func(f *factory.Factory, child bool) *decode.Decoder {
+		if child {
+			return e.decoder(f.ChildABI, w, c)
+		}
+		return e.decoder(f.ABI, w, c)
+	}
+	fw.OnRecord = func(r *decode.Record) { e.emit(w, r) }
+	return fw.Run(ctx)
+}
+
+// decoder 按 watch 配置的精度和时间字段创建解码器
+func (e *Engine) decoder(contractAbi abi.ABI, w *Watch, c *chain) *decode.Decoder {
+	d := decode.NewDecoder(contractAbi)
+	for name, decimals := range w.Decimals {
+		d.Decimals[name] = decimals
+	}
+	for name, token := range w.Amounts {
+		d.Amounts[name] = token
+	}
+	d.TimeFields = w.Times
+	if c.tokens != nil {
+		d.TokenDecimals, d.TokenAddress = c.tokens.Decimals, c.tokens.Address
+	}
+	return d
+}
+
+// handle 解码、过滤并输出一条日志
+func (e *Engine) handle(w *Watch, c *chain, d *decode.Decoder, l types.Log) {
+	r, err := d.Decode(l, c.blockTime(l.BlockNumber))
+	if errors.Is(err, decode.ErrUnknownEvent) {
+		return
+	}
+	if err != nil {
+		log.Printf("❌ [%s] 解码事件失败 tx: %s: %v", w.Name, l.TxHash.Hex(), err)
+		return
+	}
+	e.emit(w, r)
+}
+
+func (e *Engine) emit(w *Watch, r *decode.Record) {
+	if !w.match(r) {
+		return
+	}
+	for _, sink := range e.outputs[w] {
+		if err := sink.Write(w, r); err != nil {
+			log.Printf("⚠️ [%s] 输出失败: %v", w.Name, err)
+		}
+	}
+}
+
+// query 订阅条件：topic0 为监听的事件；只监听一个事件时，indexed 参数的过滤条件也放进 topics 由节点过滤
+func (w *Watch) query() ethereum.FilterQuery {
+	q := ethereum.FilterQuery{Addresses: w.Addresses}
+	if len(w.Events) == 0 {
+		return q
+	}
+	ids := make([]common.Hash, len(w.Events))
+	for i, event := range w.Events {
+		ids[i] = event.ID
+	}
+	q.Topics = [][]common.Hash{ids}
+	if len(w.Events) != 1 {
+		return q
+	}
+	var indexed []string
+	for _, in := range w.Events[0].Inputs {
+		if in.Indexed {
+			indexed = append(indexed, in.Name)
+		}
+	}
+	for i, name := range indexed {
+		q.Topics = append(q.Topics, nil)
+		for _, f := range w.Filters {
+			if f.Arg == name {
+				q.Topics[i+1] = f.topics
+			}
+		}
+	}
+	// 去掉末尾不过滤的位置
+	for len(q.Topics) > 1 && q.Topics[len(q.Topics)-1] == nil {
+		q.Topics = q.Topics[:len(q.Topics)-1]
+	}
+	return q
+}
+
+// blockTime 优先用区块头服务缓存的最近区块，补日志时再按区块号查询
+func (c *chain) blockTime(number uint64) time.Time {
+	if h, ok := c.tracker.Header(number); ok {
+		return time.Unix(int64(h.Time), 0)
+	}
+	c.mu.Lock()
+	t, ok := c.times[number]
+	c.mu.Unlock()
+	if ok {
+		return t
+	}
+	h, err := c.client.HeaderByNumber(context.Background(), new(big.Int).SetUint64(number))
+	if err != nil {
+		log.Printf("⚠️ [%s] 获取区块 %d 时间失败: %v", c.name, number, err)
+		return time.Time{}
+	}
+	t = time.Unix(int64(h.Time), 0)
+	c.mu.Lock()
+	if len(c.times) > 1000 {
+		clear(c.times)
+	}
+	c.times[number] = t
+	c.mu.Unlock()
+	return t
+}
@@ -0,0 +1,87 @@
+package watch
+
+import (
+	"chainget/pkg/decode"
+	"chainget/pkg/helper"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Filter 参数等值过滤，值统一编码成 topic 比较，indexed 的动态类型解码后只有 hash 也能匹配
+type Filter struct {
+	Arg    string
+	Type   abi.Type
+	Values map[common.Hash]bool
+	topics []common.Hash
+}
+
+// newFilter 按事件中参数的 ABI 类型转换配置值，参数在所有事件中都不存在或类型不一致时报错
+func newFilter(events []*abi.Event, arg string, value any) (*Filter, error) {
+	var typ *abi.Type
+	for _, e := range events {
+		for _, in := range e.Inputs {
+			if in.Name != arg {
+				continue
+			}
+			if typ != nil && typ.String() != in.Type.String() {
+				return nil, fmt.Errorf("type differs between events: %s, %s", typ, in.Type)
+			}
+			t := in.Type
+			typ = &t
+		}
+	}
+	if typ == nil {
+		return nil, fmt.Errorf("no watched event has argument %s", arg)
+	}
+	values, ok := value.([]any)
+	if !ok {
+		values = []any{value}
+	}
+	f := &Filter{Arg: arg, Type: *typ, Values: make(map[common.Hash]bool)}
+	for _, v := range values {
+		converted, err := helper.ConvertArg(*typ, v)
+		if err != nil {
+			return nil, err
+		}
+		topic, err := topicOf(converted)
+		if err != nil {
+			return nil, fmt.Errorf("%s can not be filtered: %w", typ, err)
+		}
+		if !f.Values[topic] {
+			f.Values[topic] = true
+			f.topics = append(f.topics, topic)
+		}
+	}
+	return f, nil
+}
+
+// Match 记录中没有该参数时不过滤
+func (f *Filter) Match(r *decode.Record) bool {
+	for _, field := range r.Fields {
+		if field.Name != f.Arg {
+			continue
+		}
+		if h, ok := field.Value.(common.Hash); ok && field.Indexed && dynamic(field.Type) {
+			return f.Values[h]
+		}
+		topic, err := topicOf(field.Value)
+		return err == nil && f.Values[topic]
+	}
+	return true
+}
+
+// dynamic indexed 参数只保存 keccak256 的类型
+func dynamic(t string) bool {
+	return t == "string" || t == "bytes" || strings.HasSuffix(t, "]") || strings.HasPrefix(t, "(")
+}
+
+func topicOf(v any) (common.Hash, error) {
+	topics, err := abi.MakeTopics([]any{v})
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return topics[0][0], nil
+}
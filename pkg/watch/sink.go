@@ -0,0 +1,112 @@
+package watch
+
+import (
+	"chainget/pkg/decode"
+	"chainget/pkg/notify"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Sink 匹配的事件输出到哪里
+type Sink interface {
+	Write(w *Watch, r *decode.Record) error
+}
+
+// output 带 watch 名称的记录，json 和 file 输出的格式
+type output struct {
+	Watch string `json:"watch"`
+	*decode.Record
+}
+
+// textSink 逐行打印参数，多个 watch 并发时整条记录一起输出
+type textSink struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+func (s *textSink) Write(w *Watch, r *decode.Record) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s] %s %s\n", w.Name, r.Event, r.Address.Hex())
+	fmt.Fprintf(&b, "时间: %s\n块号: %d\n交易哈希: %s\n", r.BlockTime, r.BlockNumber, r.TxHash.Hex())
+	for _, f := range r.Fields {
+		fmt.Fprintf(&b, "%s (%s): %s\n", f.Name, f.Type, f.Display)
+	}
+	b.WriteString("------------------------\n")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := io.WriteString(s.out, b.String())
+	return err
+}
+
+// jsonSink 一条记录一行 JSON，file:<path> 追加写入文件
+type jsonSink struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+func (s *jsonSink) Write(w *Watch, r *decode.Record) error {
+	data, err := json.Marshal(output{Watch: w.Name, Record: r})
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.out.Write(append(data, '\n'))
+	return err
+}
+
+// notifySink 以 watch 名称作为通知事件名，模板数据为 decode.Record
+type notifySink struct {
+	n *notify.Notifier
+}
+
+func (s *notifySink) Write(w *Watch, r *decode.Record) error {
+	s.n.Go(w.Name, fmt.Sprintf("%s:%d", r.TxHash.Hex(), r.LogIndex), r)
+	return nil
+}
+
+// sinks 按名称创建并复用输出，同一个文件只打开一次
+type sinks struct {
+	notifier *notify.Notifier
+	byName   map[string]Sink
+	files    []*os.File
+}
+
+func (s *sinks) get(name string) (Sink, error) {
+	if sink, ok := s.byName[name]; ok {
+		return sink, nil
+	}
+	var sink Sink
+	switch {
+	case name == "stdout":
+		sink = &textSink{out: os.Stdout}
+	case name == "json":
+		sink = &jsonSink{out: os.Stdout}
+	case strings.HasPrefix(name, "file:"):
+		f, err := os.OpenFile(strings.TrimPrefix(name, "file:"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, err
+		}
+		s.files = append(s.files, f)
+		sink = &jsonSink{out: f}
+	case name == "notify":
+		if s.notifier == nil {
+			return nil, fmt.Errorf("sink notify requires notify config")
+		}
+		sink = &notifySink{n: s.notifier}
+	default:
+		return nil, fmt.Errorf("unknown sink %q", name)
+	}
+	s.byName[name] = sink
+	return sink, nil
+}
+
+func (s *sinks) close() {
+	for _, f := range s.files {
+		f.Close()
+	}
+}
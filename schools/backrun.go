@@ -0,0 +1,281 @@
+package main
+
+import (
+	"chainget/global"
+	"chainget/pkg/bundle"
+	"chainget/pkg/helper"
+	"chainget/pkg/nonce"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/lmittmann/w3"
+	"github.com/lmittmann/w3/module/eth"
+)
+
+// backrunConfig 跟单配置，对应 config.yaml 的 backrun 节点
+type backrunConfig struct {
+	Amount   *big.Int // 每笔 presale 的购买数量
+	Price    *big.Int // presale 单价
+	TxCount  int      // presale 交易笔数
+	GasLimit uint64   // 每笔 presale 的 gas 上限
+	Blocks   uint64   // 连续提交的区块数
+	MaxSpend *big.Int // 我方交易最大花费（value + gas 上限），超过则放弃
+	DryRun   bool     // 只模拟不提交
+	Policy   *bundle.Policy
+}
+
+func loadBackrunConfig() backrunConfig {
+	v := global.Viper
+	v.SetDefault("backrun.amount", 1)
+	v.SetDefault("backrun.price", "0.01")
+	v.SetDefault("backrun.txCount", 1)
+	v.SetDefault("backrun.gasLimit", 250_000)
+	v.SetDefault("backrun.blocks", 3)
+	v.SetDefault("backrun.maxSpend", "0.05")
+	v.SetDefault("backrun.dryRun", true)
+
+	price, err := helper.ParseEther(v.GetString("backrun.price"))
+	if err != nil {
+		log.Fatalf("❌ backrun.price 配置错误: %v", err)
+	}
+	maxSpend, err := helper.ParseEther(v.GetString("backrun.maxSpend"))
+	if err != nil {
+		log.Fatalf("❌ backrun.maxSpend 配置错误: %v", err)
+	}
+	policy, err := loadPolicy("backrun.policy", crypto.PubkeyToAddress(privateKey.PublicKey), address)
+	if err != nil {
+		log.Fatalf("❌ backrun.policy 配置错误: %v", err)
+	}
+	return backrunConfig{
+		Amount:   big.NewInt(v.GetInt64("backrun.amount")),
+		Price:    price,
+		TxCount:  v.GetInt("backrun.txCount"),
+		GasLimit: v.GetUint64("backrun.gasLimit"),
+		Blocks:   v.GetUint64("backrun.blocks"),
+		MaxSpend: maxSpend,
+		DryRun:   v.GetBool("backrun.dryRun"),
+		Policy:   policy,
+	}
+}
+
+// loadPolicy 读取 bundle 花费策略，金额均为 ETH，targets 默认只允许 target 合约，selectors 默认只允许 presale
+func loadPolicy(key string, account, target common.Address) (*bundle.Policy, error) {
+	v := global.Viper
+	v.SetDefault(key+".maxValue", "0.05")
+	v.SetDefault(key+".maxGasSpend", "0.02")
+	v.SetDefault(key+".targets", []string{target.Hex()})
+	v.SetDefault(key+".selectors", []string{"presale(uint256)"})
+
+	p := &bundle.Policy{Account: account}
+	var err error
+	for name, dst := range map[string]**big.Int{
+		"maxValue":         &p.MaxValue,
+		"maxGasSpend":      &p.MaxGasSpend,
+		"minBalanceChange": &p.MinBalanceChange,
+	} {
+		if s := v.GetString(key + "." + name); s != "" {
+			if *dst, err = helper.ParseEther(s); err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+	for _, s := range v.GetStringSlice(key + ".targets") {
+		if !common.IsHexAddress(s) {
+			return nil, fmt.Errorf("targets: invalid address %q", s)
+		}
+		p.AllowedTargets = append(p.AllowedTargets, common.HexToAddress(s))
+	}
+	for _, s := range v.GetStringSlice(key + ".selectors") {
+		selector, err := helper.ParseSelector(s)
+		if err != nil {
+			return nil, fmt.Errorf("selectors: %w", err)
+		}
+		p.AllowedSelectors = append(p.AllowedSelectors, selector)
+	}
+	return p, nil
+}
+
+// Backrun 把 pending 的 enablePresale 交易和我方 presale 交易打成 bundle [victim, presale...]，
+// 模拟通过后提交到接下来 Blocks 个区块并跟踪上链
+func (f FlashBotsClient) Backrun(victim common.Hash) error {
+	var (
+		ctx    = context.Background()
+		cfg    = loadBackrunConfig()
+		sender = crypto.PubkeyToAddress(privateKey.PublicKey)
+	)
+	client, err := dialW3()
+	if err != nil {
+		return fmt.Errorf("连接 RPC 失败: %w", err)
+	}
+	defer client.Close()
+
+	var (
+		rawVictim hexutil.Bytes
+		head      *big.Int
+		chainID   uint64
+	)
+	if err := client.CallCtx(ctx,
+		bundle.RawTx(victim).Returns(&rawVictim),
+		eth.BlockNumber().Returns(&head),
+		eth.ChainID().Returns(&chainID),
+	); err != nil {
+		return fmt.Errorf("获取 victim 交易失败: %w", err)
+	}
+	victimTx := new(types.Transaction)
+	if err := victimTx.UnmarshalBinary(rawVictim); err != nil {
+		return fmt.Errorf("解析 victim 交易失败: %w", err)
+	}
+
+	fees, err := bundle.NewFeeOracle(client).Suggest(ctx)
+	if err != nil {
+		return fmt.Errorf("获取 gas 报价失败: %w", err)
+	}
+	//同一合约上一版 bundle 即将被撤回，先归还它的 nonce 供本次复用
+	key := "presale:" + address.Hex()
+	from, to := head.Uint64()+1, head.Uint64()+cfg.Blocks
+	reservation, err := reserveBackrunNonces(ctx, key, sender, cfg.TxCount, to)
+	if err != nil {
+		return fmt.Errorf("分配 nonce 失败: %w", err)
+	}
+	signer := bundle.NewSigner(privateKey, new(big.Int).SetUint64(chainID))
+	presaleTxs, err := f.presaleTxs(signer, fees, reservation.First(), cfg)
+	if err != nil {
+		reservation.Release()
+		return err
+	}
+	spend := new(big.Int)
+	for _, tx := range presaleTxs {
+		spend.Add(spend, tx.Cost())
+	}
+	if spend.Cmp(cfg.MaxSpend) > 0 {
+		reservation.Release()
+		return fmt.Errorf("最大花费 %s ETH 超过上限 %s ETH", w3.FromWei(spend, 18), w3.FromWei(cfg.MaxSpend, 18))
+	}
+
+	b := &bundle.Bundle{
+		Txs:         append(types.Transactions{victimTx}, presaleTxs...),
+		BlockNumber: new(big.Int).Add(head, w3.Big1),
+	}
+	relay, submissions, err := backrunRelay()
+	if err != nil {
+		reservation.Release()
+		return err
+	}
+
+	res, err := relay.Simulate(ctx, b)
+	if err != nil {
+		reservation.Release()
+		return fmt.Errorf("模拟 bundle 失败: %w", err)
+	}
+	if err := b.Check(res); err != nil {
+		reservation.Release()
+		return fmt.Errorf("模拟 bundle 未通过: %w", err)
+	}
+	change, baseFee, err := bundle.BalanceChange(ctx, client, b, sender)
+	if err != nil {
+		reservation.Release()
+		return fmt.Errorf("计算余额变化失败: %w", err)
+	}
+	out, err := cfg.Policy.Evaluate(b, res, baseFee, change)
+	if err != nil {
+		reservation.Release()
+		return err
+	}
+	log.Printf("✔️ 模拟通过 bundleHash: %s, gasUsed: %d, coinbaseDiff: %s, value: %s ETH, gas: %s ETH, 余额变化: %s ETH",
+		res.BundleHash.Hex(), res.TotalGasUsed, res.CoinbaseDiff, w3.FromWei(out.Value, 18), w3.FromWei(out.GasSpend, 18), w3.FromWei(out.BalanceChange, 18))
+	if cfg.DryRun {
+		reservation.Release()
+		log.Println("✔️ dry-run 模式，不提交 bundle")
+		return nil
+	}
+
+	//同一合约的新 victim 到来时撤回上一版 bundle，旧 bundle 的 nonce 与价格都已过时
+	sub, err := submissions.Submit(ctx, key, b, from, to)
+	if err != nil {
+		reservation.Release()
+		return err
+	}
+	log.Printf("✔️ bundle v%d 已提交到区块 %d-%d: %v", sub.Version, from, to, sub.Hashes)
+
+	ours := (&bundle.Bundle{Txs: presaleTxs}).Hashes()
+	receipts, err := bundle.NewTracker(client).Wait(ctx, ours, to)
+	if current, ok := submissions.Current(key); ok && current == sub {
+		submissions.Done(key)
+	}
+	if errors.Is(err, bundle.ErrNotIncluded) {
+		reservation.Release()
+	}
+	if err != nil {
+		return fmt.Errorf("等待上链失败: %w", err)
+	}
+	for _, receipt := range receipts {
+		log.Printf("✔️ presale 已上链 tx: %s, block: %d, status: %d", receipt.TxHash.Hex(), receipt.BlockNumber, receipt.Status)
+	}
+	return nil
+}
+
+var (
+	backrunOnce   sync.Once
+	backrunSender *bundle.Sender
+	backrunSubs   *bundle.SubmissionTracker
+	backrunErr    error
+)
+
+// backrunRelay 所有 Backrun 共用一个 relay 连接和 SubmissionTracker，才能撤回之前提交的 bundle
+func backrunRelay() (*bundle.Sender, *bundle.SubmissionTracker, error) {
+	backrunOnce.Do(func() {
+		backrunSender, backrunErr = bundle.Dial(relayURL, privateKey)
+		if backrunErr != nil {
+			backrunErr = fmt.Errorf("连接 relay 失败: %w", backrunErr)
+			return
+		}
+		backrunSubs = bundle.NewSubmissionTracker(backrunSender)
+	})
+	return backrunSender, backrunSubs, backrunErr
+}
+
+var (
+	backrunNoncesMu sync.Mutex
+	backrunNonces   = make(map[string]*nonce.Reservation)
+)
+
+// reserveBackrunNonces 为 key 分配 nonce，并归还同一 key 上一次分配的 nonce
+func reserveBackrunNonces(ctx context.Context, key string, sender common.Address, n int, maxBlock uint64) (*nonce.Reservation, error) {
+	backrunNoncesMu.Lock()
+	defer backrunNoncesMu.Unlock()
+	if old, ok := backrunNonces[key]; ok {
+		old.Release()
+	}
+	reservation, err := nonceManager().Reserve(ctx, sender, n, maxBlock)
+	if err != nil {
+		return nil, err
+	}
+	backrunNonces[key] = reservation
+	return reservation, nil
+}
+
+// presaleTxs 签名 TxCount 笔连续 nonce 的 presale 交易
+func (f FlashBotsClient) presaleTxs(signer *bundle.Signer, fees *bundle.Fees, nonce uint64, cfg backrunConfig) (types.Transactions, error) {
+	data, err := contractABI.Pack("presale", cfg.Amount)
+	if err != nil {
+		return nil, fmt.Errorf("presale 编码失败: %w", err)
+	}
+	value := new(big.Int).Mul(cfg.Price, cfg.Amount)
+	txs := make(types.Transactions, 0, cfg.TxCount)
+	for i := 0; i < cfg.TxCount; i++ {
+		tx, err := signer.Sign(fees.Tx(signer.ChainID, nonce+uint64(i), address, value, cfg.GasLimit, data))
+		if err != nil {
+			return nil, fmt.Errorf("签名 presale 交易失败: %w", err)
+		}
+		txs = append(txs, tx)
+	}
+	return txs, nil
+}
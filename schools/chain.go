@@ -1,33 +1,69 @@
 package main
 
 import (
+	"chainget/global"
+	"chainget/pkg/expr"
+	"chainget/pkg/head"
+	"chainget/pkg/poll"
 	"context"
 	"fmt"
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"log"
 	"math/big"
 	"time"
 )
 
+// whaleTransfer 大额转账通知的模板数据
+type whaleTransfer struct {
+	Block  uint64
+	TxHash string
+	From   string
+	To     string
+	Value  int64 // USDT，已去掉 6 位精度
+}
+
+// whaleFilter 编译大额转账的过滤表达式，可引用 from、to、value（原始精度）和 whale.vars 中的常量，decimals 默认为 6
+func whaleFilter(src string, vars map[string]any) (*expr.Program, error) {
+	env := expr.NewEnv()
+	env.Field("from", expr.Address)
+	env.Field("to", expr.Address)
+	env.Field("value", expr.Int)
+	env.Const("decimals", 6)
+	if err := env.Consts(vars); err != nil {
+		return nil, err
+	}
+	return expr.Compile(src, env)
+}
+
 func chain() {
 	ctx := context.Background()
-	wsURL := "wss://ethereum-rpc.publicnode.com"
-	client, err := ethclient.Dial(wsURL)
+	notifier := loadNotifier()
+	v := global.Viper
+	v.SetDefault("whale.rpc", "wss://ethereum-rpc.publicnode.com")
+	v.SetDefault("whale.pollInterval", poll.DefaultInterval)
+	v.SetDefault("whale.where", "value >= 200000 * 10**decimals")
+	where, err := whaleFilter(v.GetString("whale.where"), v.GetStringMap("whale.vars"))
+	if err != nil {
+		log.Fatalf("whale.where 无效: %v", err)
+	}
+	//http(s) 节点没有订阅，按间隔轮询
+	client, err := poll.Dial(v.GetString("whale.rpc"), v.GetDuration("whale.pollInterval"))
 	if err != nil {
-		log.Fatalf("无法连接到 WebSocket 节点: %v", err)
+		log.Fatalf("无法连接到节点: %v", err)
 	}
 	defer client.Close()
 
 	number, _ := client.BlockNumber(context.Background())
 	fmt.Printf("当前区块高度：%d\n", number)
 
-	var lastTime time.Time
-	newBlockLog := make(chan *types.Header, 2048)
-	newBlockSub, _ := client.SubscribeNewHead(ctx, newBlockLog)
+	//以太坊 12 秒一个 slot
+	tracker := head.NewTracker(client, 12*time.Second)
+	go tracker.Run(ctx)
+	newBlockLog := make(chan *head.Head, 64)
+	newBlockSub := tracker.Subscribe(newBlockLog)
 	defer newBlockSub.Unsubscribe()
 
 	contractAddress := common.HexToAddress("0xdac17f958d2ee523a2206206994597c13d831ec7")
@@ -50,23 +86,36 @@ func chain() {
 			log.Printf("订阅错误: %v", err)
 			return
 		case newBlock := <-newBlockLog:
-			currentTime := time.Unix(int64(newBlock.Time), 0)
-			if !lastTime.IsZero() {
-				timeDiff := currentTime.Sub(lastTime)
-				fmt.Printf("Block #%d, Hash: %s, Time: %s\n", newBlock.Number, newBlock.Hash().Hex(), currentTime)
-				fmt.Printf("Time since last block: %s\n", timeDiff)
+			header := newBlock.Header
+			fmt.Printf("Block #%d, Hash: %s, Time: %s\n", header.Number, header.Hash().Hex(), time.Unix(int64(header.Time), 0))
+			if newBlock.Interval > 0 {
+				fmt.Printf("Time since last block: %s\n", newBlock.Interval)
+			}
+			if newBlock.Missed > 0 {
+				log.Printf("⚠️ 区块 %d 前错过 %d 个 slot", header.Number, newBlock.Missed)
+			}
+			if newBlock.Reorg {
+				log.Printf("⚠️ 区块 %d 发生重组", header.Number)
+			}
+			if header.Number.Uint64()%100 == 0 {
+				stats := tracker.Stats()
+				fmt.Printf("最近 %d 个区块间隔 平均 %s p50 %s p95 %s 最大 %s，累计错过 %d 个 slot，重组 %d 次\n",
+					stats.Count, stats.Mean, stats.P50, stats.P95, stats.Max, stats.Missed, stats.Reorgs)
 			}
-			fmt.Printf("区块高度:%d\n", newBlock.Number)
-			lastTime = currentTime
 		case vLog := <-logs:
 			if len(vLog.Topics) == 3 {
-				value := new(big.Int).SetBytes(vLog.Data).Int64() / 1000000
-				if value < int64(200000) {
-					continue
-				}
 				//获取 usdt 转账
 				from := common.HexToAddress(vLog.Topics[1].Hex())
 				to := common.HexToAddress(vLog.Topics[2].Hex())
+				amount := new(big.Int).SetBytes(vLog.Data)
+				ok, err := where.Eval(map[string]any{"from": from, "to": to, "value": amount})
+				if err != nil {
+					log.Printf("⚠️ whale.where 求值失败: %v", err)
+				}
+				if !ok {
+					continue
+				}
+				value := amount.Int64() / 1000000
 
 				fmt.Printf("区块号: %d\n", vLog.BlockNumber)
 				fmt.Printf("交易哈希: %s\n", vLog.TxHash.Hex())
@@ -74,6 +123,13 @@ func chain() {
 				fmt.Printf("To: %s\n", to.Hex())
 				fmt.Printf("Value: %d usdt\n", value)
 				fmt.Println("-------------------")
+				notifier.Go("whale", fmt.Sprintf("%s:%d", vLog.TxHash.Hex(), vLog.Index), whaleTransfer{
+					Block:  vLog.BlockNumber,
+					TxHash: vLog.TxHash.Hex(),
+					From:   from.Hex(),
+					To:     to.Hex(),
+					Value:  value,
+				})
 			}
 		}
 	}
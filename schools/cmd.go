@@ -1,12 +1,53 @@
 package main
 
-import "chainget/global"
+import (
+	"chainget/global"
+	"log"
+	"os"
+)
 
 func main() {
+	//离线计算存储槽位，不需要配置文件
+	if len(os.Args) > 1 && os.Args[1] == "slot" {
+		runSlot(os.Args[2:])
+		return
+	}
 	global.InitConfig()
-	//subSlot()
+	//subSlot("")
 
-	NewItmClient().Run() //
+	if len(os.Args) < 2 {
+		NewItmClient().Run() //
+		return
+	}
+	switch os.Args[1] {
+	case "watch": //监听 pending enablePresale 并跟单
+		f := FlashBotsClient{}
+		f.initData()
+		go f.WatchPending()
+		<-lock
+	case "private": //私密发送单笔调用
+		runPrivate(os.Args[2:])
+	case "mevshare": //订阅 mev-share hint 并 backrun
+		runMevShare()
+	case "strategy": //按策略文件执行触发→动作
+		runStrategy(os.Args[2:])
+	case "whale": //监听 USDT 大额转账并通知
+		chain()
+	case "endpoints": //查看节点池状态
+		runEndpoints()
+	case "storage": //按 storageLayout 读取状态变量
+		runStorage(os.Args[2:])
+	case "proxy": //识别代理合约的实现合约
+		runProxy(os.Args[2:])
+	case "diff": //比较存储在交易前后或两个区块间的变化
+		runDiff(os.Args[2:])
+	case "proof": //用 eth_getProof 校验账户和存储
+		runProof(os.Args[2:])
+	case "tokens": //批量读取代币信息和余额
+		runTokens(os.Args[2:])
+	default:
+		log.Fatalf("❌ 未知命令: %s，可用命令: watch, private, mevshare, strategy, whale, endpoints, storage, slot, proxy, diff, proof, tokens", os.Args[1])
+	}
 
 	//FlashBotsClient{}.Run()
 }
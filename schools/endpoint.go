@@ -0,0 +1,55 @@
+package main
+
+import (
+	"chainget/global"
+	"chainget/pkg/endpoint"
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/lmittmann/w3"
+)
+
+var (
+	poolOnce sync.Once
+	pool     *endpoint.Pool
+)
+
+// rpcPool 进程内共用的节点池，节点来自 rpc.endpoints（默认只有 ethRpcUrl），后台定期健康检查
+func rpcPool() *endpoint.Pool {
+	poolOnce.Do(func() {
+		v := global.Viper
+		v.SetDefault("rpc.endpoints", []string{ethRpcUrl})
+		v.SetDefault("rpc.maxLag", 3)
+		v.SetDefault("rpc.checkInterval", "15s")
+
+		var err error
+		if pool, err = endpoint.New(v.GetStringSlice("rpc.endpoints")); err != nil {
+			log.Fatalf("❌ rpc.endpoints 配置错误: %v", err)
+		}
+		pool.ChainID = v.GetUint64("rpc.chainId")
+		pool.MaxLag = v.GetUint64("rpc.maxLag")
+		pool.CheckInterval = v.GetDuration("rpc.checkInterval")
+		go pool.Run(context.Background())
+	})
+	return pool
+}
+
+// dialW3 基于节点池的 w3 客户端，节点故障时自动切换
+func dialW3() (*w3.Client, error) {
+	return w3.NewClient(rpcPool().RPC()), nil
+}
+
+// dialEth 基于节点池的 ethclient
+func dialEth() (*ethclient.Client, error) {
+	return rpcPool().Client(), nil
+}
+
+// runEndpoints 检查一次并打印节点池状态
+func runEndpoints() {
+	p := rpcPool()
+	p.Check(context.Background())
+	fmt.Print(p)
+}
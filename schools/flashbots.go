@@ -2,6 +2,9 @@ package main
 
 import (
 	"chainget/global"
+	"chainget/pkg/bundle"
+	"chainget/pkg/nonce"
+	"chainget/pkg/poll"
 	"context"
 	"crypto/ecdsa"
 	"encoding/hex"
@@ -10,8 +13,11 @@ import (
 	"log"
 	"math/big"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/rpc"
@@ -36,9 +42,52 @@ var (
 	address      = common.HexToAddress(watchAddress)
 )
 
+var (
+	nonceOnce sync.Once
+	nonces    *nonce.Manager
+)
+
+// nonceManager 进程内共用的 nonce 分配器，Push、Backrun、SendPrivate、ItmFlashBot、mev-share backrun 并发时不会拿到相同 nonce。
+// 首次调用时开始按新区块同步，过了 MaxBlock 仍未上链的分配才会被释放
+func nonceManager() *nonce.Manager {
+	nonceOnce.Do(func() {
+		client, err := dialW3()
+		if err != nil {
+			log.Fatalf("连接 RPC 失败: %v", err)
+		}
+		nonces = nonce.NewManager(client)
+		heads := make(chan *types.Header)
+		go nonces.Run(context.Background(), heads)
+		go subscribeHeads(context.Background(), heads)
+	})
+	return nonces
+}
+
+// subscribeHeads 把节点池的新区块推送到 heads，http 节点按间隔轮询，订阅中断后 1 秒重试
+func subscribeHeads(ctx context.Context, heads chan<- *types.Header) {
+	client, err := dialEth()
+	if err != nil {
+		log.Fatalf("连接 RPC 失败: %v", err)
+	}
+	backend := poll.New(client, 0)
+	for ctx.Err() == nil {
+		sub, err := backend.SubscribeNewHead(ctx, heads)
+		if err == nil {
+			err = <-sub.Err()
+			sub.Unsubscribe()
+		}
+		log.Printf("⚠️ nonce 同步的新区块订阅中断，1 秒后重试: %v", err)
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+		}
+	}
+}
+
 func (f FlashBotsClient) initData() {
 	var err error
-	if privateKey, err = crypto.HexToECDSA(global.GetPrivateKey()); err != nil {
+	//InitConfig 已把 key.privateKey 替换为解密后的私钥
+	if privateKey, err = crypto.HexToECDSA(global.Viper.GetString("key.privateKey")); err != nil {
 		log.Fatalf("解析私钥失败: %v", err)
 	}
 	//解析合约 ABI
@@ -80,6 +129,9 @@ func (f FlashBotsClient) WatchPending() {
 	}
 	defer sub.Unsubscribe()
 
+	//新区块到来时同步 nonce，释放过期 bundle 占用的 nonce
+	nonceManager()
+
 	//监控合约地址,合约方法选择器获取
 	contractAddress := common.HexToAddress(watchAddress)
 	enablePresaleSelector := f.GetEnablePresaleSelector()
@@ -99,6 +151,11 @@ func (f FlashBotsClient) WatchPending() {
 				if string(tx.Data()[:4]) == string(enablePresaleSelector) {
 					//调用 flashbots 打包
 					fmt.Printf("检测到pending enablePresale交易: %s\n", tx.Hash().Hex())
+					go func(hash common.Hash) {
+						if err := f.Backrun(hash); err != nil {
+							log.Printf("❌ backrun 失败: %v", err)
+						}
+					}(tx.Hash())
 				}
 			}
 		}
@@ -106,37 +163,38 @@ func (f FlashBotsClient) WatchPending() {
 }
 
 // 交易签名
-func (f FlashBotsClient) signedTx(client *ethclient.Client, gasFeeCap *big.Int) []string {
+func (f FlashBotsClient) signedTx(gasFeeCap *big.Int, targetBlock *big.Int) []string {
 	var (
 		chainID = big.NewInt(11155111)
 		sender  = crypto.PubkeyToAddress(privateKey.PublicKey)
 	)
-	nonce, err := client.PendingNonceAt(context.Background(), sender)
+	reservation, err := nonceManager().Reserve(context.Background(), sender, 2, targetBlock.Uint64())
 	if err != nil {
 		log.Fatalf("获取 nonce 失败: %v", err)
 	}
+	nonce := reservation.First()
 	// 估算 gas
 	gasLimit := uint64(200000)
 	gasLimit = gasLimit * 12 / 10 // 增加 20% 余量
-	amount := big.NewInt(1)
-	priceETH := "0.01"
-	priceFloat, ok := new(big.Float).SetString(priceETH)
-	if !ok {
-		log.Fatalf("无效的 NFT 价格: %s", priceETH)
-	}
-	price, _ := new(big.Float).Mul(priceFloat, big.NewFloat(1)).Int(nil)
-	weiPerEth := big.NewInt(1e18)
-	price = new(big.Int).Mul(price, weiPerEth) // 转换为 wei
-	data, _ := contractABI.Pack("presale", amount)
+	//数量和单价与 backrun 共用配置，value = price * amount
+	cfg := loadBackrunConfig()
+	value := new(big.Int).Mul(cfg.Price, cfg.Amount)
+	data, _ := contractABI.Pack("presale", cfg.Amount)
 
-	signedTx1, _ := types.SignTx(types.NewTx(&types.LegacyTx{Nonce: nonce, To: &address, Value: amount, Gas: gasLimit, GasPrice: gasFeeCap, Data: data}), types.NewEIP155Signer(chainID), privateKey)
+	signedTx1, _ := types.SignTx(types.NewTx(&types.LegacyTx{Nonce: nonce, To: &address, Value: value, Gas: gasLimit, GasPrice: gasFeeCap, Data: data}), types.NewEIP155Signer(chainID), privateKey)
 	rawTxABytes1, _ := signedTx1.MarshalBinary()
 	rawTxHex1 := "0x" + hex.EncodeToString(rawTxABytes1)
 
-	signedTx2, _ := types.SignTx(types.NewTx(&types.LegacyTx{Nonce: nonce + 1, To: &address, Value: amount, Gas: gasLimit, GasPrice: gasFeeCap, Data: data}), types.NewEIP155Signer(chainID), privateKey)
+	signedTx2, _ := types.SignTx(types.NewTx(&types.LegacyTx{Nonce: nonce + 1, To: &address, Value: value, Gas: gasLimit, GasPrice: gasFeeCap, Data: data}), types.NewEIP155Signer(chainID), privateKey)
 	rawTxABytes2, _ := signedTx2.MarshalBinary()
 	rawTxHex2 := "0x" + hex.EncodeToString(rawTxABytes2)
 
+	//不经模拟直接提交，按 gas 用满的最坏情况检查花费策略
+	if _, err := cfg.Policy.Precheck(&bundle.Bundle{Txs: types.Transactions{signedTx1, signedTx2}}); err != nil {
+		reservation.Release()
+		log.Fatalf("❌ %v", err)
+	}
+
 	return []string{
 		rawTxHex1,
 		rawTxHex2,
@@ -144,7 +202,7 @@ func (f FlashBotsClient) signedTx(client *ethclient.Client, gasFeeCap *big.Int)
 }
 
 func (f FlashBotsClient) Push() {
-	client, err := ethclient.Dial(ethRpcUrl)
+	client, err := dialEth()
 	if err != nil {
 		log.Fatalf("连接 RPC 失败: %v", err)
 	}
@@ -169,9 +227,9 @@ func (f FlashBotsClient) Push() {
 
 	fmt.Println("区块号码：", targetBlock.String())
 	//获取
-	signedTxArr := f.signedTx(client, gasFeeCap)
+	signedTxArr := f.signedTx(gasFeeCap, targetBlock)
 
-	f.sendBundle(signedTxArr, targetBlock.String())
+	f.sendBundle(signedTxArr, targetBlock)
 
 	// 发送交易
 	//err = client.SendTransaction(context.Background(), signedTx)
@@ -189,7 +247,7 @@ func (f FlashBotsClient) Push() {
 	//}
 }
 func (f FlashBotsClient) GetChainIdAndNonce() (*big.Int, uint64) {
-	client, err := ethclient.Dial(ethRpcUrl)
+	client, err := dialEth()
 	if err != nil {
 		log.Fatalf("连接 RPC 失败: %v", err)
 	}
@@ -216,19 +274,19 @@ func (f FlashBotsClient) GetChainIdAndNonce() (*big.Int, uint64) {
 }
 
 func (f FlashBotsClient) getEthClient() *ethclient.Client {
-	client, err := ethclient.Dial(ethRpcUrl)
+	client, err := dialEth()
 	if err != nil {
 		log.Fatalf("连接 RPC 失败: %v", err)
 	}
 	return client
 }
 
-func (f FlashBotsClient) sendBundle(txs []string, blockNumber string) {
+func (f FlashBotsClient) sendBundle(txs []string, blockNumber *big.Int) {
 	rpc := flashbotsrpc.New(relayURL)
 	rpc.Debug = true
 	sendBundleArgs := flashbotsrpc.FlashbotsSendBundleRequest{
 		Txs:         txs,
-		BlockNumber: fmt.Sprintf("0x%x", blockNumber),
+		BlockNumber: hexutil.EncodeBig(blockNumber),
 	}
 	result, err := rpc.FlashbotsSendBundle(privateKey, sendBundleArgs)
 	if err != nil {
@@ -242,14 +300,14 @@ func (f FlashBotsClient) sendBundle(txs []string, blockNumber string) {
 	fmt.Printf("%+v\n", result)
 }
 
-func (f FlashBotsClient) sendCall(txs []string, blockNumber string) {
+func (f FlashBotsClient) sendCall(txs []string, blockNumber *big.Int) {
 	rpc := flashbotsrpc.New(relayURL)
 	rpc.Debug = true // 启用调试模式获取更多信息
 
 	fmt.Printf("发送交易到区块: %s, 交易数据长度: %d\n", blockNumber, len(txs[0]))
 	opts := flashbotsrpc.FlashbotsCallBundleParam{
 		Txs:              txs,
-		BlockNumber:      fmt.Sprintf("0x%x", blockNumber),
+		BlockNumber:      hexutil.EncodeBig(blockNumber),
 		StateBlockNumber: "latest",
 	}
 
@@ -2,6 +2,9 @@ package main
 
 import (
 	"chainget/global"
+	"chainget/pkg/bundle"
+	"chainget/pkg/helper"
+	"chainget/pkg/nonce"
 	"context"
 	"crypto/ecdsa"
 	"encoding/hex"
@@ -28,6 +31,9 @@ type ItmFlashBot struct {
 	ChainID         *big.Int
 	Lock            chan int
 	Debug           bool
+	Nonces          *nonce.Manager
+	Price           *big.Int // presale 单价
+	Policy          *bundle.Policy
 }
 
 var ctx = context.Background()
@@ -42,16 +48,33 @@ func NewItmClient() *ItmFlashBot {
 	if err != nil {
 		log.Fatalf("❌ ABI解析失败: %v", err)
 	}
+	global.Viper.SetDefault("key.price", "0.01")
+	price, err := helper.ParseEther(global.Viper.GetString("key.price"))
+	if err != nil {
+		log.Fatalf("❌ key.price 配置错误: %v", err)
+	}
+	var (
+		sender          = crypto.PubkeyToAddress(pk.PublicKey)
+		contractAddress = common.HexToAddress(global.Viper.GetString("key.contractAddress"))
+	)
+	policy, err := loadPolicy("policy", sender, contractAddress)
+	if err != nil {
+		log.Fatalf("❌ policy 配置错误: %v", err)
+	}
+	ethRpcUrl := global.Viper.GetString("rpc.ethRpcUrl")
 	return &ItmFlashBot{
-		Sender:          crypto.PubkeyToAddress(pk.PublicKey),
+		Sender:          sender,
 		PrivateKey:      pk,
-		ETHRpcUrl:       global.Viper.GetString("rpc.ethRpcUrl"),
+		ETHRpcUrl:       ethRpcUrl,
 		FlashBotsRpcUrl: global.Viper.GetString("rpc.flashBotsRpcUrl"),
-		ContractAddress: common.HexToAddress(global.Viper.GetString("key.contractAddress")),
+		ContractAddress: contractAddress,
 		ContractABI:     abiAnalysis,
 		ChainID:         big.NewInt(global.Viper.GetInt64("chainId")),
 		Lock:            make(chan int),
 		Debug:           global.Viper.GetBool("debug"),
+		Nonces:          nonceManager(),
+		Price:           price,
+		Policy:          policy,
 	}
 }
 
@@ -67,8 +90,9 @@ func (f ItmFlashBot) Run() {
 // StartFlashBots 开始执行发送到 flashbots 流程
 func (f ItmFlashBot) StartFlashBots() {
 	var (
-		nonce, gasPrice, lastBlock = f.GetParams()
-		txArr                      = f.GetTxsArr(nonce, gasPrice)
+		reservation, gasPrice, lastBlock = f.GetParams()
+		nonce                            = reservation.First()
+		txArr                            = f.GetTxsArr(nonce, gasPrice)
 	)
 	//发送交易到本地执行
 	log.Printf("✔️ nonce: %v", nonce)
@@ -77,6 +101,10 @@ func (f ItmFlashBot) StartFlashBots() {
 	log.Printf("✔️ txArr: %v", txArr)
 
 	hash, err := f.SendBundle(txArr, lastBlock)
+	//只模拟或提交失败时 nonce 没有被占用
+	if err != nil || f.Debug {
+		reservation.Release()
+	}
 	if err != nil {
 		log.Fatalf("❌ SendBundle 失败: %v", err)
 	}
@@ -90,20 +118,23 @@ func (f ItmFlashBot) Client() *w3.Client {
 }
 
 // 获取必要参数
-func (f ItmFlashBot) GetParams() (uint64, *big.Int, *big.Int) {
+func (f ItmFlashBot) GetParams() (*nonce.Reservation, *big.Int, *big.Int) {
 	var (
-		nonce       uint64
 		gasPrice    *big.Int
 		latestBlock *big.Int
 	)
 	if err := f.Client().Call(
-		eth.Nonce(f.Sender, nil).Returns(&nonce),
 		eth.GasPrice().Returns(&gasPrice),
 		eth.BlockNumber().Returns(&latestBlock),
 	); err != nil {
 		log.Fatalf("❌ params 获取失败: %v", err)
 	}
-	return nonce, gasPrice, latestBlock
+	//bundle 只提交到下一个区块，过了该区块未上链时 nonce 自动归还
+	reservation, err := f.Nonces.Reserve(ctx, f.Sender, 1, latestBlock.Uint64()+1)
+	if err != nil {
+		log.Fatalf("❌ nonce 分配失败: %v", err)
+	}
+	return reservation, gasPrice, latestBlock
 }
 
 // 预先签名交易
@@ -126,7 +157,7 @@ func (f ItmFlashBot) CreateSignedTx(amount *big.Int, nonce uint64, gasPrice *big
 	tx := &types.LegacyTx{
 		Nonce:    nonce,
 		To:       &f.ContractAddress,
-		Value:    big.NewInt(1e16),
+		Value:    new(big.Int).Mul(f.Price, amount),
 		Gas:      250_000,
 		GasPrice: gasPrice,
 		Data:     data,
@@ -140,7 +171,7 @@ func (f ItmFlashBot) CreateSignedTx(amount *big.Int, nonce uint64, gasPrice *big
 }
 
 // 发送交易
-func (f ItmFlashBot) SendBundle(bundle types.Transactions, latestBlock *big.Int) (common.Hash, error) {
+func (f ItmFlashBot) SendBundle(txs types.Transactions, latestBlock *big.Int) (common.Hash, error) {
 	var (
 		client     = flashbots.MustDial(f.FlashBotsRpcUrl, f.PrivateKey)
 		bundleHash common.Hash
@@ -148,18 +179,26 @@ func (f ItmFlashBot) SendBundle(bundle types.Transactions, latestBlock *big.Int)
 	)
 	defer client.Close()
 
+	//先模拟再按花费策略检查，Debug 模式只模拟不提交
+	var callBundle *flashbots.CallBundleResponse
+	if err = client.Call(flashbots.CallBundle(&flashbots.CallBundleRequest{
+		Transactions: txs,
+		BlockNumber:  new(big.Int).Add(latestBlock, w3.Big1),
+	}).Returns(&callBundle)); err != nil {
+		return bundleHash, err
+	}
+	log.Printf("✔️ CallBundleResponse: %v", callBundle)
+	out, err := f.Policy.Evaluate(&bundle.Bundle{Txs: txs}, callBundle, nil, nil)
+	if err != nil {
+		return bundleHash, err
+	}
+	log.Printf("✔️ 花费 value: %s ETH, gas: %s ETH", w3.FromWei(out.Value, 18), w3.FromWei(out.GasSpend, 18))
 	if f.Debug {
-		var callBundle *flashbots.CallBundleResponse
-		err = client.Call(flashbots.CallBundle(&flashbots.CallBundleRequest{
-			Transactions: bundle,
-			BlockNumber:  new(big.Int).Add(latestBlock, w3.Big1),
-		}).Returns(&callBundle))
-		log.Printf("✔️ CallBundleResponse: %v", callBundle)
-	} else {
-		err = client.Call(flashbots.SendBundle(&flashbots.SendBundleRequest{
-			Transactions: bundle,
-			BlockNumber:  new(big.Int).Add(latestBlock, w3.Big1),
-		}).Returns(&bundleHash))
+		return bundleHash, nil
 	}
+	err = client.Call(flashbots.SendBundle(&flashbots.SendBundleRequest{
+		Transactions: txs,
+		BlockNumber:  new(big.Int).Add(latestBlock, w3.Big1),
+	}).Returns(&bundleHash))
 	return bundleHash, err
 }
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"chainget/global"
+	"chainget/pkg/mevshare"
+	"context"
+	"log"
+	"math/big"
+
+	"github.com/lmittmann/w3/module/eth"
+)
+
+// runMevShare 订阅 MEV-Share 事件流，按 config.yaml 的 mevshare.rules 提交 backrun bundle
+func runMevShare() {
+	f := FlashBotsClient{}
+	f.initData()
+
+	v := global.Viper
+	v.SetDefault("mevshare.stream", mevshare.SepoliaStreamURL)
+	v.SetDefault("mevshare.blocks", 5)
+	v.SetDefault("mevshare.dryRun", true)
+
+	var configs []mevshare.RuleConfig
+	if err := v.UnmarshalKey("mevshare.rules", &configs); err != nil {
+		log.Fatalf("❌ 解析 mevshare.rules 失败: %v", err)
+	}
+	rules := make([]*mevshare.Rule, 0, len(configs))
+	for _, c := range configs {
+		rule, err := c.Rule()
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		rules = append(rules, rule)
+	}
+	if len(rules) == 0 {
+		log.Fatal("❌ 未配置 mevshare.rules")
+	}
+
+	client, err := dialW3()
+	if err != nil {
+		log.Fatalf("❌ 连接 RPC 失败: %v", err)
+	}
+	defer client.Close()
+	var chainID uint64
+	if err := client.Call(eth.ChainID().Returns(&chainID)); err != nil {
+		log.Fatalf("❌ 获取链ID失败: %v", err)
+	}
+
+	backrunner, err := mevshare.NewBackrunner(client, relayURL, privateKey, new(big.Int).SetUint64(chainID), nonceManager(), rules)
+	if err != nil {
+		log.Fatalf("❌ 连接 relay 失败: %v", err)
+	}
+	defer backrunner.Close()
+	backrunner.Blocks = v.GetUint64("mevshare.blocks")
+	backrunner.DryRun = v.GetBool("mevshare.dryRun")
+
+	log.Printf("✔️ 开始订阅 mev-share 事件流 %s，规则数: %d", v.GetString("mevshare.stream"), len(rules))
+	if err := backrunner.Run(context.Background(), mevshare.NewClient(v.GetString("mevshare.stream"))); err != nil {
+		log.Fatalf("❌ mev-share 订阅结束: %v", err)
+	}
+}
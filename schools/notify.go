@@ -0,0 +1,25 @@
+package main
+
+import (
+	"chainget/global"
+	"chainget/pkg/notify"
+	"log"
+)
+
+// loadNotifier 读取 config.yaml 的 notify 节点，未配置时返回 nil（通知方法对 nil 是空操作）
+func loadNotifier() *notify.Notifier {
+	v := global.Viper
+	if !v.IsSet("notify") {
+		return nil
+	}
+	var c notify.Config
+	if err := v.UnmarshalKey("notify", &c); err != nil {
+		log.Fatalf("❌ 解析 notify 失败: %v", err)
+	}
+	n, err := c.Notifier()
+	if err != nil {
+		log.Fatalf("❌ notify 配置错误: %v", err)
+	}
+	log.Printf("✔️ 已配置 %d 个通知渠道", n.Channels())
+	return n
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"chainget/pkg/bundle"
+	"chainget/pkg/helper"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/lmittmann/w3/module/eth"
+	"github.com/lmittmann/w3/w3types"
+)
+
+// privateCall 私密发送的单笔合约调用
+type privateCall struct {
+	To     common.Address
+	Data   []byte
+	Value  *big.Int
+	Gas    uint64 // 为 0 时估算
+	Blocks uint64 // 最多等待的区块数，超过后撤回
+}
+
+// runPrivate 命令行：private -to 合约 -sig "presale(uint256)" -args 1 -value 0.01 -blocks 25
+func runPrivate(args []string) {
+	var (
+		fs       = flag.NewFlagSet("private", flag.ExitOnError)
+		to       = fs.String("to", watchAddress, "合约地址")
+		sig      = fs.String("sig", "presale(uint256)", "函数签名")
+		callArgs = fs.String("args", "", "函数参数，逗号分隔")
+		value    = fs.String("value", "0", "发送的 ETH 数量")
+		gas      = fs.Uint64("gas", 0, "gas 上限，0 表示估算")
+		blocks   = fs.Uint64("blocks", 25, "最多等待的区块数")
+	)
+	_ = fs.Parse(args)
+
+	var params []any
+	if *callArgs != "" {
+		for _, arg := range strings.Split(*callArgs, ",") {
+			params = append(params, strings.TrimSpace(arg))
+		}
+	}
+	data, err := helper.PackCall(*sig, params...)
+	if err != nil {
+		log.Fatalf("❌ 编码调用失败: %v", err)
+	}
+	amount, err := helper.ParseEther(*value)
+	if err != nil {
+		log.Fatalf("❌ value 错误: %v", err)
+	}
+	if !common.IsHexAddress(*to) {
+		log.Fatalf("❌ 无效的合约地址: %s", *to)
+	}
+
+	f := FlashBotsClient{}
+	f.initData()
+	if err := f.SendPrivate(privateCall{
+		To:     common.HexToAddress(*to),
+		Data:   data,
+		Value:  amount,
+		Gas:    *gas,
+		Blocks: *blocks,
+	}); err != nil {
+		log.Fatalf("❌ 私密交易失败: %v", err)
+	}
+}
+
+// SendPrivate 用与 bundle 相同的签名、报价和上链跟踪私密发送单笔交易，超过等待区块仍未上链则撤回
+func (f FlashBotsClient) SendPrivate(c privateCall) error {
+	var (
+		ctx    = context.Background()
+		sender = crypto.PubkeyToAddress(privateKey.PublicKey)
+	)
+	client, err := dialW3()
+	if err != nil {
+		return fmt.Errorf("连接 RPC 失败: %w", err)
+	}
+	defer client.Close()
+
+	var (
+		head    *big.Int
+		chainID uint64
+		gas     = c.Gas
+		calls   = []w3types.RPCCaller{
+			eth.BlockNumber().Returns(&head),
+			eth.ChainID().Returns(&chainID),
+		}
+	)
+	if gas == 0 {
+		calls = append(calls, eth.EstimateGas(&w3types.Message{From: sender, To: &c.To, Value: c.Value, Input: c.Data}, nil).Returns(&gas))
+	}
+	if err := client.CallCtx(ctx, calls...); err != nil {
+		return fmt.Errorf("获取交易参数失败: %w", err)
+	}
+	if c.Gas == 0 {
+		gas = gas * 12 / 10 // 增加 20% 余量
+	}
+
+	fees, err := bundle.NewFeeOracle(client).Suggest(ctx)
+	if err != nil {
+		return fmt.Errorf("获取 gas 报价失败: %w", err)
+	}
+	maxBlock := new(big.Int).Add(head, new(big.Int).SetUint64(c.Blocks))
+	reservation, err := nonceManager().Reserve(ctx, sender, 1, maxBlock.Uint64())
+	if err != nil {
+		return fmt.Errorf("分配 nonce 失败: %w", err)
+	}
+	signer := bundle.NewSigner(privateKey, new(big.Int).SetUint64(chainID))
+	tx, err := signer.Sign(fees.Tx(signer.ChainID, reservation.First(), c.To, c.Value, gas, c.Data))
+	if err != nil {
+		reservation.Release()
+		return fmt.Errorf("签名交易失败: %w", err)
+	}
+
+	relay, err := bundle.Dial(relayURL, privateKey)
+	if err != nil {
+		reservation.Release()
+		return fmt.Errorf("连接 relay 失败: %w", err)
+	}
+	defer relay.Close()
+
+	hash, err := relay.SendPrivate(ctx, tx, maxBlock)
+	if err != nil {
+		reservation.Release()
+		return fmt.Errorf("发送私密交易失败: %w", err)
+	}
+	log.Printf("✔️ 私密交易已发送 tx: %s, maxBlock: %s", hash.Hex(), maxBlock)
+
+	receipts, err := bundle.NewTracker(client).Wait(ctx, []common.Hash{hash}, maxBlock.Uint64())
+	if errors.Is(err, bundle.ErrNotIncluded) {
+		cancelled, cancelErr := relay.CancelPrivate(ctx, hash)
+		log.Printf("⚠️ 私密交易未在 %s 块前上链，撤回结果: %v %v", maxBlock, cancelled, cancelErr)
+		if cancelErr == nil {
+			reservation.Release()
+		}
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("等待上链失败: %w", err)
+	}
+	log.Printf("✔️ 私密交易已上链 block: %d, status: %d", receipts[0].BlockNumber, receipts[0].Status)
+	return nil
+}
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"chainget/pkg/proof"
+	"chainget/pkg/proxy"
+	"chainget/pkg/slot"
+	"chainget/pkg/storage"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+)
+
+// runStorage 按 solc storageLayout 读取合约状态变量
+//
+//	go run ./schools storage -layout EsRNT.layout.json -address 0x20f4… 'locks[3].amount' locks
+//	go run ./schools storage -layout layouts/ -address 0xA0b8… '_balances[0xabc…]'   # 代理合约按实现合约地址选择 layouts/<地址>.json
+func runStorage(args []string) {
+	var (
+		fs      = flag.NewFlagSet("storage", flag.ExitOnError)
+		file    = fs.String("layout", "", "storageLayout JSON（solc --storage-layout 或 forge inspect <合约> storageLayout），或按地址存放 <地址>.json 的目录")
+		address = fs.String("address", "", "合约地址")
+		block   = fs.Int64("block", -1, "区块号，默认最新")
+		max     = fs.Int("max", 100, "整个数组最多展开的元素数")
+		asJSON  = fs.Bool("json", false, "输出 JSON")
+		verify  = addProofFlags(fs)
+	)
+	_ = fs.Parse(args)
+	if *file == "" || !common.IsHexAddress(*address) || fs.NArg() == 0 {
+		log.Fatalf("❌ 用法: storage -layout <file|目录> -address <合约> <路径>...，如 locks[3].amount、balances[0xabc…]")
+	}
+	client, _ := dialEth()
+	contract := common.HexToAddress(*address)
+	layout, err := storage.LoadLayout(layoutFile(client, contract, *file))
+	if err != nil {
+		log.Fatalf("❌ 读取 storageLayout 失败: %v", err)
+	}
+	r := storage.NewReader(client, contract, layout)
+	r.MaxItems = *max
+	if *block >= 0 {
+		r.Block = big.NewInt(*block)
+	}
+	if pc := verify.client(); pc != nil {
+		//所有读取固定在校验用的区块
+		header, err := pc.Header(context.Background(), r.Block)
+		if err != nil {
+			log.Fatalf("❌ 获取区块头失败: %v", err)
+		}
+		log.Printf("✔️ 按区块 %s（%s）的状态根 %s 校验", header.Number, header.Hash().Hex(), header.Root.Hex())
+		r = storage.NewReader(pc, contract, layout)
+		r.MaxItems, r.Block = *max, header.Number
+	}
+	for _, path := range fs.Args() {
+		v, err := r.Read(context.Background(), path)
+		if err != nil {
+			log.Printf("❌ %s: %v", path, err)
+			continue
+		}
+		if *asJSON {
+			data, _ := json.Marshal(v)
+			fmt.Println(string(data))
+		} else {
+			fmt.Print(v)
+		}
+	}
+}
+
+// proofFlags 用 eth_getProof 校验读取结果的参数
+type proofFlags struct {
+	verify    *bool
+	blockHash *string
+	trusted   *string
+}
+
+func addProofFlags(fs *flag.FlagSet) *proofFlags {
+	return &proofFlags{
+		verify:    fs.Bool("verify", false, "用 eth_getProof 读取并按区块头的状态根校验，需要 -blockhash 或 -trusted 提供可信的区块头"),
+		blockHash: fs.String("blockhash", "", "校验使用的区块哈希（来自可信来源），区块头在本地重新计算哈希，隐含 -verify"),
+		trusted:   fs.String("trusted", "", "提供区块头的可信节点（自己的节点或轻客户端），隐含 -verify"),
+	}
+}
+
+// client 未开启校验时返回 nil。区块头必须来自可信来源：-blockhash 在本地重新计算哈希，-trusted 为单独的节点，
+// 与证明来自同一节点池的区块头不能证明任何东西，只给 -verify 时直接退出
+func (f *proofFlags) client() *proof.Client {
+	if !*f.verify && *f.blockHash == "" && *f.trusted == "" {
+		return nil
+	}
+	if *f.blockHash == "" && *f.trusted == "" {
+		log.Fatalf("❌ 校验需要可信的区块头：用 -blockhash 指定区块哈希或 -trusted 指定提供区块头的节点")
+	}
+	var headers proof.Headers = rpcPool().Client()
+	if *f.trusted != "" {
+		c, err := ethclient.Dial(*f.trusted)
+		if err != nil {
+			log.Fatalf("❌ 连接可信节点失败: %v", err)
+		}
+		headers = c
+	}
+	pc := proof.NewClient(gethclient.New(rpcPool().RPC()), headers)
+	if *f.blockHash != "" {
+		if len(common.FromHex(*f.blockHash)) != common.HashLength {
+			log.Fatalf("❌ 无效的区块哈希: %s", *f.blockHash)
+		}
+		pc.BlockHash = common.HexToHash(*f.blockHash)
+	}
+	return pc
+}
+
+// runProof 读取并校验账户余额、nonce 和存储槽
+//
+//	go run ./schools proof -blockhash 0x… 0x20f48b9d9d019ad7cb8113c85c99940e3b224ffc 0 1
+func runProof(args []string) {
+	var (
+		fs    = flag.NewFlagSet("proof", flag.ExitOnError)
+		block = fs.Int64("block", -1, "区块号，默认最新")
+		f     = addProofFlags(fs)
+	)
+	_ = fs.Parse(args)
+	if fs.NArg() == 0 || !common.IsHexAddress(fs.Arg(0)) {
+		log.Fatalf("❌ 用法: proof [-block <区块>] (-blockhash <哈希> | -trusted <url>) <地址> [槽]...")
+	}
+	*f.verify = true
+	var slots []common.Hash
+	for _, arg := range fs.Args()[1:] {
+		n, ok := new(big.Int).SetString(arg, 0)
+		if !ok {
+			log.Fatalf("❌ 无效的槽号: %s", arg)
+		}
+		slots = append(slots, common.BigToHash(n))
+	}
+	var number *big.Int
+	if *block >= 0 {
+		number = big.NewInt(*block)
+	}
+	acc, err := f.client().Account(context.Background(), common.HexToAddress(fs.Arg(0)), number, slots...)
+	if err != nil {
+		log.Fatalf("❌ 校验失败: %v", err)
+	}
+	fmt.Printf("✔️ %s 区块 %s（%s）状态根 %s\n", acc.Address.Hex(), acc.Header.Number, acc.Header.Hash().Hex(), acc.Header.Root.Hex())
+	fmt.Printf("nonce       %d\nbalance     %s\ncodeHash    %s\nstorageRoot %s\n", acc.Nonce, acc.Balance, acc.CodeHash.Hex(), acc.StorageRoot.Hex())
+	for _, s := range slots {
+		fmt.Printf("%s = %s\n", s.Hex(), acc.Storage[s].Hex())
+	}
+}
+
+// layoutFile 代理合约的存储在代理地址，布局要用实现合约的：file 为目录时取其中以实现合约地址（不是代理时为合约本身）命名的
+// <地址>.json，找不到时退出；file 为文件且合约是代理时提示
+func layoutFile(client proxy.Client, contract common.Address, file string) string {
+	p, err := proxy.NewResolver(client).Resolve(context.Background(), contract)
+	info, statErr := os.Stat(file)
+	if statErr != nil || !info.IsDir() {
+		if err != nil {
+			log.Printf("⚠️ 检查代理合约失败: %v", err)
+		} else if p.Kind != proxy.None {
+			log.Printf("⚠️ %s 是 %s 代理，实现合约 %s，-layout 应为实现合约的 storageLayout（可以改用目录，按实现合约地址选择）", contract.Hex(), p.Kind, p.Target().Hex())
+		}
+		return file
+	}
+	if err != nil {
+		log.Fatalf("❌ 检查代理合约失败，无法按实现合约选择 storageLayout: %v", err)
+	}
+	target := p.Target()
+	path := filepath.Join(file, target.Hex()+".json")
+	if _, err := os.Stat(path); err != nil {
+		log.Fatalf("❌ %s 中没有 %s 的 storageLayout（%s）: %v", file, target.Hex(), p, err)
+	}
+	if p.Kind != proxy.None {
+		log.Printf("✔️ %s 是 %s 代理，使用实现合约的 storageLayout %s", contract.Hex(), p.Kind, path)
+	}
+	return path
+}
+
+// runDiff 比较合约存储在交易前后或两个区块间的变化，按 storageLayout 解码
+//
+//	go run ./schools diff -address 0x20f4… -layout EsRNT.layout.json -tx 0x…
+//	go run ./schools diff -address 0x20f4… -slots 0,1,2 -from 7000000 -to 7000100
+func runDiff(args []string) {
+	var (
+		fs      = flag.NewFlagSet("diff", flag.ExitOnError)
+		address = fs.String("address", "", "合约地址")
+		file    = fs.String("layout", "", "storageLayout JSON 或按地址存放 <地址>.json 的目录，不指定时只输出原始槽")
+		slots   = fs.String("slots", "", "额外比较的槽，逗号分隔")
+		keys    = fs.String("keys", "", "mapping 的候选键，逗号分隔；-tx 时自动加入交易涉及的地址")
+		txHash  = fs.String("tx", "", "交易哈希，比较交易执行前后")
+		from    = fs.Int64("from", -1, "起始区块")
+		to      = fs.Int64("to", -1, "结束区块，默认最新")
+		max     = fs.Int("max", 100, "定长数组最多展开的元素数")
+		asJSON  = fs.Bool("json", false, "输出 JSON")
+	)
+	_ = fs.Parse(args)
+	if !common.IsHexAddress(*address) || (*txHash == "") == (*from < 0) {
+		log.Fatalf("❌ 用法: diff -address <合约> [-layout <file|目录>] [-slots 0,1] [-keys 0xabc…] (-tx <哈希> | -from <区块> [-to <区块>]) [路径]...")
+	}
+	ctx := context.Background()
+	client, _ := dialEth()
+	contract := common.HexToAddress(*address)
+
+	var candidates []string
+	for _, k := range strings.Split(*keys, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			candidates = append(candidates, k)
+		}
+	}
+	var extra []common.Hash
+	for _, s := range strings.Split(*slots, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		n, ok := new(big.Int).SetString(s, 0)
+		if !ok {
+			log.Fatalf("❌ 无效的槽号: %s", s)
+		}
+		extra = append(extra, common.BigToHash(n))
+	}
+
+	var (
+		before, after *big.Int
+		changes       []storage.Change
+		traced        bool
+	)
+	if *txHash != "" {
+		hash := common.HexToHash(*txHash)
+		receipt, err := client.TransactionReceipt(ctx, hash)
+		if err != nil {
+			log.Fatalf("❌ 查询交易失败: %v", err)
+		}
+		before, after = new(big.Int).Sub(receipt.BlockNumber, big.NewInt(1)), receipt.BlockNumber
+		if diff, err := storage.TraceDiff(ctx, rpcPool().RPC(), hash); err != nil {
+			log.Printf("⚠️ debug_traceTransaction 不可用（%v），改为逐槽比较区块 %s 与 %s，包含同区块其他交易的改动", err, before, after)
+		} else {
+			traced, changes = true, diff.Changes[contract]
+			for _, a := range diff.Accounts {
+				candidates = append(candidates, a.Hex())
+			}
+		}
+	} else {
+		before = big.NewInt(*from)
+		if *to >= 0 {
+			after = big.NewInt(*to)
+		}
+	}
+
+	var fields []storage.Field
+	if *file != "" {
+		layout, err := storage.LoadLayout(layoutFile(client, contract, *file))
+		if err != nil {
+			log.Fatalf("❌ 读取 storageLayout 失败: %v", err)
+		}
+		fields = layout.Fields(candidates, *max)
+		r := storage.NewReader(client, contract, layout)
+		r.Block, r.MaxItems = after, *max
+		for _, path := range fs.Args() {
+			f, err := r.Fields(ctx, path, candidates)
+			if err != nil {
+				log.Fatalf("❌ %s: %v", path, err)
+			}
+			fields = append(fields, f...)
+		}
+	} else if fs.NArg() > 0 {
+		log.Fatalf("❌ 按路径比较需要 -layout")
+	}
+
+	if !traced {
+		targets := append(storage.Slots(fields), extra...)
+		if len(targets) == 0 {
+			log.Fatalf("❌ 没有要比较的槽，请指定 -layout 或 -slots")
+		}
+		var err error
+		if changes, err = storage.DiffSlots(ctx, client, contract, targets, before, after); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+	}
+
+	decoded, unknown := storage.Decode(changes, fields)
+	if *asJSON {
+		data, _ := json.Marshal(map[string]any{"changes": decoded, "slots": unknown})
+		fmt.Println(string(data))
+		return
+	}
+	if len(changes) == 0 {
+		fmt.Println("存储没有变化")
+		return
+	}
+	for _, c := range decoded {
+		fmt.Println(c)
+	}
+	for _, c := range unknown {
+		fmt.Printf("slot %s: %s → %s\n", c.Slot.Hex(), c.Before.Hex(), c.After.Hex())
+	}
+}
+
+// runProxy 识别代理合约并打印实现合约
+//
+//	go run ./schools proxy 0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48
+func runProxy(args []string) {
+	if len(args) == 0 {
+		log.Fatalf("❌ 用法: proxy <合约地址>...")
+	}
+	client, _ := dialEth()
+	r := proxy.NewResolver(client)
+	for _, arg := range args {
+		if !common.IsHexAddress(arg) {
+			log.Printf("❌ 无效的地址: %s", arg)
+			continue
+		}
+		p, err := r.Resolve(context.Background(), common.HexToAddress(arg))
+		if err != nil {
+			log.Printf("❌ %s: %v", arg, err)
+			continue
+		}
+		fmt.Print(p)
+		if p.Kind != proxy.None {
+			fmt.Printf("ABI 和 storageLayout 使用实现合约 %s 的\n", p.Target().Hex())
+		}
+	}
+}
+
+// runSlot 离线计算槽位，不需要节点
+//
+//	go run ./schools slot 2 map:address:0xabc… map:address:0xdef…   # allowance[a][b]
+//	go run ./schools slot 0 array:3:64 +1 field:8:24                 # locks[3] 第二个槽的高 24 字节
+func runSlot(args []string) {
+	if len(args) == 0 {
+		log.Fatalf("❌ 用法: slot <槽号> [map:<键类型>:<键> | array:<下标>[:<元素字节数>] | index:<下标>[:<元素字节数>] | +<n> | field:<偏移>:<字节数>]...")
+	}
+	n, ok := new(big.Int).SetString(args[0], 0)
+	if !ok {
+		log.Fatalf("❌ 无效的槽号: %s", args[0])
+	}
+	at := common.BigToHash(n)
+	fmt.Printf("%-40s %s\n", args[0], at.Hex())
+	for _, arg := range args[1:] {
+		kind, rest, _ := strings.Cut(arg, ":")
+		offset := 0
+		switch {
+		case kind == "map":
+			typ, key, ok := strings.Cut(rest, ":")
+			if !ok {
+				log.Fatalf("❌ %s: 需要 map:<键类型>:<键>", arg)
+			}
+			var err error
+			if at, err = slot.MappingKey(at, typ, key); err != nil {
+				log.Fatalf("❌ %s: %v", arg, err)
+			}
+		case kind == "array" || kind == "index":
+			index, size := parseElement(arg, rest)
+			if kind == "array" {
+				at, offset = slot.DynamicElement(at, index, size)
+			} else {
+				at, offset = slot.Element(at, index, size)
+			}
+		case strings.HasPrefix(arg, "+"):
+			k, err := strconv.ParseUint(arg[1:], 0, 64)
+			if err != nil {
+				log.Fatalf("❌ %s: %v", arg, err)
+			}
+			at = slot.Add(at, k)
+		case kind == "field":
+			o, s, _ := strings.Cut(rest, ":")
+			f := slot.Field{Slot: at}
+			var err1, err2 error
+			f.Offset, err1 = strconv.Atoi(o)
+			f.Size, err2 = strconv.Atoi(s)
+			if err1 != nil || err2 != nil || f.Size <= 0 || f.Offset+f.Size > 32 {
+				log.Fatalf("❌ %s: 需要 field:<偏移>:<字节数>", arg)
+			}
+			fmt.Printf("%-40s %s mask 0x%064x\n", arg, f, f.Mask())
+			continue
+		default:
+			log.Fatalf("❌ 无法识别: %s", arg)
+		}
+		if offset > 0 {
+			fmt.Printf("%-40s %s offset %d\n", arg, at.Hex(), offset)
+		} else {
+			fmt.Printf("%-40s %s\n", arg, at.Hex())
+		}
+	}
+}
+
+func parseElement(arg, rest string) (uint64, int) {
+	i, s, hasSize := strings.Cut(rest, ":")
+	index, err := strconv.ParseUint(i, 0, 64)
+	if err != nil {
+		log.Fatalf("❌ %s: 无效的下标", arg)
+	}
+	size := 32
+	if hasSize {
+		if size, err = strconv.Atoi(s); err != nil || size <= 0 {
+			log.Fatalf("❌ %s: 无效的元素字节数", arg)
+		}
+	}
+	return index, size
+}
@@ -1,58 +1,83 @@
 package main
 
 import (
+	"chainget/pkg/multicall"
+	"chainget/pkg/proof"
+	"chainget/pkg/slot"
+	"chainget/pkg/storage"
 	"context"
 	"fmt"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
 	"github.com/ethereum/go-ethereum/rpc"
 	"log"
 	"math/big"
 )
 
 // 0x20f48b9d9d019ad7cb8113c85c99940e3b224ffc 合约地址
-func subSlot() {
+// trusted 不为空时用 eth_getProof 读取，并按 trusted 节点给出的最新区块头的状态根校验，所有读取固定在同一个区块；
+// 区块头必须来自提供证明之外的节点，否则校验没有意义
+func subSlot(trusted string) {
 	rpcClient, err := rpc.Dial("https://sepolia.drpc.org")
 	if err != nil {
 		log.Fatalf("Failed to connect to RPC: %v", err)
 	}
-	client := ethclient.NewClient(rpcClient)
+	var (
+		client storage.Client = ethclient.NewClient(rpcClient)
+		block  *big.Int
+	)
 	defer rpcClient.Close()
+	verify := trusted != ""
+	if verify {
+		headers, err := ethclient.Dial(trusted)
+		if err != nil {
+			log.Fatalf("Failed to connect to trusted node: %v", err)
+		}
+		defer headers.Close()
+		pc := proof.NewClient(gethclient.New(rpcClient), headers)
+		header, err := pc.Header(context.Background(), nil)
+		if err != nil {
+			log.Fatalf("Failed to get header: %v", err)
+		}
+		client, block = pc, header.Number
+		fmt.Printf("Verifying against block %s state root %s from %s\n", block, header.Root.Hex(), trusted)
+	}
 
 	// 替换为实际的 esRNT 合约地址
 	contractAddress := common.HexToAddress("0x20f48b9d9d019ad7cb8113c85c99940e3b224ffc")
 	// 读取数组长度（插槽 0）
-	lengthSlot := common.Big0
-	lengthHex, err := client.StorageAt(context.Background(), contractAddress, common.BigToHash(lengthSlot), nil)
+	lengthSlot := slot.Of(0)
+	lengthHex, err := client.StorageAt(context.Background(), contractAddress, lengthSlot, block)
 	if err != nil {
 		log.Fatalf("Failed to read array length: %v", err)
 	}
-	length := new(big.Int).SetBytes(lengthHex[:]).Int64()
+	length := new(big.Int).SetBytes(lengthHex[:]).Uint64()
 	fmt.Printf("Array length: %d\n", length)
-	// 计算数组数据的起始插槽
-	dataStartSlot := common.BytesToHash(common.BigToHash(lengthSlot).Bytes())
-	dataStart := new(big.Int).SetBytes(dataStartSlot[:])
-	// 遍历数组的每个元素
-	for i := int64(0); i < length; i++ {
-		// 每个元素占用 2 个插槽，计算当前元素的起始插槽
-		elementStartSlot := new(big.Int).Add(dataStart, big.NewInt(i*2))
-
-		// 读取 user（插槽 elementStartSlot）
-		userSlot := common.BigToHash(elementStartSlot)
-		userHex, err := client.StorageAt(context.Background(), contractAddress, userSlot, nil)
-		if err != nil {
-			log.Fatalf("Failed to read user at index %d: %v", i, err)
-		}
-		user := common.BytesToAddress(userHex[12:]) // 地址占用低 20 字节
-
-		// 读取 startTime 和 amount（插槽 elementStartSlot+1）
-		dataSlot := common.BigToHash(new(big.Int).Add(elementStartSlot, common.Big1))
-		dataHex, err := client.StorageAt(context.Background(), contractAddress, dataSlot, nil)
-		if err != nil {
-			log.Fatalf("Failed to read data at index %d: %v", i, err)
+	// 每个元素占用 2 个插槽，元素从 keccak256(slot) 开始：user 在第一个槽，startTime 和 amount 在第二个槽
+	slots := make([]common.Hash, 0, 2*length)
+	for i := uint64(0); i < length; i++ {
+		elementStartSlot, _ := slot.DynamicElement(lengthSlot, i, 64)
+		slots = append(slots, elementStartSlot, slot.Add(elementStartSlot, 1))
+	}
+	values := make([]common.Hash, len(slots))
+	if verify {
+		for i, s := range slots {
+			v, err := client.StorageAt(context.Background(), contractAddress, s, block)
+			if err != nil {
+				log.Fatalf("Failed to read slot %s: %v", s.Hex(), err)
+			}
+			values[i] = common.BytesToHash(v)
 		}
-		startTime := new(big.Int).SetBytes(dataHex[24:32]).Uint64() // startTime 占用低 8 字节
-		amount := new(big.Int).SetBytes(dataHex[:24])               // amount 占用高 24 字节
+	} else if values, err = multicall.New(rpcClient).Storage(context.Background(), contractAddress, slots); err != nil {
+		// 不校验时用批量请求一次读完
+		log.Fatalf("Failed to read locks: %v", err)
+	}
+	for i := uint64(0); i < length; i++ {
+		user := common.BytesToAddress(slot.Field{Size: 20}.Extract(values[2*i])) // 地址占用低 20 字节
+		data := values[2*i+1]
+		startTime := new(big.Int).SetBytes(slot.Field{Size: 8}.Extract(data)).Uint64() // startTime 占用低 8 字节
+		amount := new(big.Int).SetBytes(slot.Field{Offset: 8, Size: 24}.Extract(data)) // amount 占用高 24 字节
 		// 按指定格式打印
 		fmt.Printf("locks[%d]: user: %s, startTime: %d, amount: %s\n", i, user.Hex(), startTime, amount.String())
 	}
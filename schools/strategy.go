@@ -0,0 +1,73 @@
+package main
+
+import (
+	"chainget/global"
+	"chainget/pkg/strategy"
+	"context"
+	"crypto/ecdsa"
+	"flag"
+	"log"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/lmittmann/w3/module/eth"
+)
+
+// runStrategy 命令行：strategy -file strategies.yaml，按策略文件执行触发→动作
+//
+// 动作的 account 默认为 default（key.privateKey），其他账户在 config.yaml 的 strategy.accounts 中配置加密私钥
+func runStrategy(args []string) {
+	var (
+		fs   = flag.NewFlagSet("strategy", flag.ExitOnError)
+		file = fs.String("file", "strategies.yaml", "策略文件")
+	)
+	_ = fs.Parse(args)
+
+	f := FlashBotsClient{}
+	f.initData()
+
+	strategies, err := strategy.Load(*file)
+	if err != nil {
+		log.Fatalf("❌ 加载策略失败: %v", err)
+	}
+	if len(strategies) == 0 {
+		log.Fatalf("❌ %s 未配置策略", *file)
+	}
+
+	accounts := map[string]*ecdsa.PrivateKey{strategy.DefaultAccount: privateKey}
+	for name, encrypted := range global.Viper.GetStringMapString("strategy.accounts") {
+		key, err := crypto.HexToECDSA(global.DecryptPrivateKey(encrypted))
+		if err != nil {
+			log.Fatalf("❌ 解析账户 %s 私钥失败: %v", name, err)
+		}
+		accounts[name] = key
+	}
+
+	client, err := dialW3()
+	if err != nil {
+		log.Fatalf("❌ 连接 RPC 失败: %v", err)
+	}
+	defer client.Close()
+	var chainID uint64
+	if err := client.Call(eth.ChainID().Returns(&chainID)); err != nil {
+		log.Fatalf("❌ 获取链ID失败: %v", err)
+	}
+	stream, err := ethclient.Dial(webSocketUrl)
+	if err != nil {
+		log.Fatalf("❌ 连接 websocket 失败: %v", err)
+	}
+	defer stream.Close()
+	relay, _, err := backrunRelay()
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	engine, err := strategy.NewEngine(client, stream, relay, nonceManager(), new(big.Int).SetUint64(chainID), accounts, strategies)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	if err := engine.Run(context.Background()); err != nil {
+		log.Fatalf("❌ 策略引擎退出: %v", err)
+	}
+}
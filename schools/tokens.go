@@ -0,0 +1,89 @@
+package main
+
+import (
+	"chainget/pkg/multicall"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/lmittmann/w3"
+)
+
+// runTokens 用 Multicall3 一次读取代币元数据和一批地址的余额
+//
+//	go run ./schools tokens -holders 0xabc…,0xdef… 0xdAC17F958D2ee523a2206206994597C13D831ec7 0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48
+func runTokens(args []string) {
+	var (
+		fs      = flag.NewFlagSet("tokens", flag.ExitOnError)
+		holders = fs.String("holders", "", "查询余额的地址，逗号分隔")
+		block   = fs.Int64("block", -1, "区块号，默认最新")
+		batch   = fs.Int("batch", 500, "一次 aggregate3 的调用数")
+	)
+	_ = fs.Parse(args)
+	if fs.NArg() == 0 {
+		log.Fatalf("❌ 用法: tokens [-holders 0xabc…,0xdef…] [-block <区块>] <代币地址>...，0x0 表示 ETH")
+	}
+	tokens, err := parseAddresses(fs.Args())
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	var owners []common.Address
+	if *holders != "" {
+		if owners, err = parseAddresses(strings.Split(*holders, ",")); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+	}
+	ctx := context.Background()
+	c := multicall.New(rpcPool().RPC())
+	c.BatchSize = *batch
+	if *block >= 0 {
+		c.Block = big.NewInt(*block)
+	}
+	if ok, err := c.Deployed(ctx); err == nil && !ok {
+		log.Printf("⚠️ %s 上没有 Multicall3，改用 JSON-RPC 批量请求", c.Address.Hex())
+	}
+
+	infos, err := c.Tokens(ctx, tokens...)
+	if err != nil {
+		log.Fatalf("❌ 读取代币信息失败: %v", err)
+	}
+	for _, t := range infos {
+		if t.Address == (common.Address{}) {
+			t.Name, t.Symbol, t.Decimals, t.Err = "Ether", "ETH", 18, nil
+		}
+		if t.Err != nil {
+			log.Printf("⚠️ %s: %v", t.Address.Hex(), t.Err)
+		}
+		fmt.Printf("%s %-8s %-24s decimals %-2d totalSupply %s\n", t.Address.Hex(), t.Symbol, t.Name, t.Decimals, w3.FromWei(t.TotalSupply, t.Decimals))
+		if len(owners) == 0 {
+			continue
+		}
+		balances, err := c.Balances(ctx, t.Address, owners...)
+		if err != nil {
+			log.Printf("❌ 读取 %s 余额失败: %v", t.Symbol, err)
+			continue
+		}
+		for i, b := range balances {
+			fmt.Printf("  %s %s %s\n", owners[i].Hex(), w3.FromWei(b, t.Decimals), t.Symbol)
+		}
+	}
+}
+
+func parseAddresses(args []string) ([]common.Address, error) {
+	addresses := make([]common.Address, 0, len(args))
+	for _, a := range args {
+		if a = strings.TrimSpace(a); a == "0x0" {
+			addresses = append(addresses, common.Address{})
+			continue
+		}
+		if !common.IsHexAddress(a) {
+			return nil, fmt.Errorf("无效的地址: %s", a)
+		}
+		addresses = append(addresses, common.HexToAddress(a))
+	}
+	return addresses, nil
+}